@@ -29,6 +29,18 @@
       if (!window.__receiveGoMessage) {
         Object.defineProperty(window, "__receiveGoMessage", {
           value: function (payload) {
+            if (payload && payload.type === "__velo_before_quit") {
+              handle_before_quit(payload.id);
+              return;
+            }
+            if (payload && payload.type === "__velo_event") {
+              handle_velo_event(payload);
+              return;
+            }
+            if (payload && payload.type === "__velo_call") {
+              handle_velo_call(payload);
+              return;
+            }
             ensure_go_msg_handlers();
             var list = window.__goMessageHandlers || [];
             console.log("before invoke handlers", list);
@@ -63,6 +75,184 @@
         });
       }
     }
+    // Pages that register onBeforeQuit handlers are asked before the app
+    // quits; a handler returning false (or a promise of false) vetoes it.
+    var velo_page_id =
+      String(Date.now()) + Math.random().toString(16).slice(2);
+    var before_quit_handlers = [];
+    function set_quit_guard(active) {
+      invoke("/__velo/lifecycle/guard", {
+        method: "POST",
+        args: { page: velo_page_id, active: active },
+      }).catch(function (_e) {});
+    }
+    function handle_before_quit(id) {
+      var answers = before_quit_handlers.slice().map(function (handler) {
+        try {
+          return Promise.resolve(handler()).catch(function (_e) {
+            return true;
+          });
+        } catch (_e) {
+          return Promise.resolve(true);
+        }
+      });
+      Promise.all(answers).then(function (values) {
+        var allow = values.every(function (v) {
+          return v !== false;
+        });
+        invoke("/__velo/lifecycle/reply", {
+          method: "POST",
+          args: { id: id, page: velo_page_id, allow: allow },
+        }).catch(function (_e) {});
+      });
+    }
+    function on_before_quit(handler) {
+      if (typeof handler !== "function") {
+        return function () {};
+      }
+      before_quit_handlers.push(handler);
+      if (before_quit_handlers.length === 1) {
+        set_quit_guard(true);
+      }
+      return function () {
+        var i = before_quit_handlers.indexOf(handler);
+        if (i === -1) {
+          return;
+        }
+        before_quit_handlers.splice(i, 1);
+        if (before_quit_handlers.length === 0) {
+          set_quit_guard(false);
+        }
+      };
+    }
+    // Named events between Go and every window: Box.Emit/EmitTo reach
+    // velo.on listeners, velo.emit reaches Box.On listeners and the other
+    // windows.
+    var event_listeners = {};
+    function velo_window_name() {
+      try {
+        var info = window.__VELO__ && window.__VELO__.window;
+        if (info && info.name) {
+          return info.name;
+        }
+      } catch (_e) {}
+      return "default";
+    }
+    function handle_velo_event(packet) {
+      if (packet.source && packet.source === velo_page_id) {
+        return;
+      }
+      if (packet.window && packet.window !== velo_window_name()) {
+        return;
+      }
+      dispatch_velo_event(packet.name, packet.payload);
+    }
+    function dispatch_velo_event(name, payload) {
+      var list = (event_listeners[name] || []).slice();
+      for (var i = 0; i < list.length; i++) {
+        try {
+          list[i](payload);
+        } catch (_e) {}
+      }
+    }
+    function velo_on(name, handler) {
+      if (typeof handler !== "function") {
+        return function () {};
+      }
+      (event_listeners[name] = event_listeners[name] || []).push(handler);
+      if (!has_native_bridge()) {
+        ensure_velo_ws().catch(function (_e) {});
+      }
+      return function () {
+        velo_off(name, handler);
+      };
+    }
+    function velo_off(name, handler) {
+      var list = event_listeners[name];
+      if (!list) {
+        return;
+      }
+      if (!handler) {
+        delete event_listeners[name];
+        return;
+      }
+      var i = list.indexOf(handler);
+      if (i !== -1) {
+        list.splice(i, 1);
+      }
+      if (!list.length) {
+        delete event_listeners[name];
+      }
+    }
+    function velo_once(name, handler) {
+      var off = velo_on(name, function (payload) {
+        off();
+        handler(payload);
+      });
+      return off;
+    }
+    function velo_emit(name, payload) {
+      dispatch_velo_event(name, payload);
+      return invoke("/__velo/events/emit", {
+        method: "POST",
+        args: {
+          name: name,
+          payload: payload,
+          window: velo_window_name(),
+          source: velo_page_id,
+        },
+      });
+    }
+    // Functions registered with velo.expose can be called from Go with
+    // Webview.Call; the result or error is sent back under the call id.
+    var exposed_functions = {};
+    function velo_expose(name, fn) {
+      if (typeof fn !== "function") {
+        return function () {};
+      }
+      exposed_functions[name] = fn;
+      if (!has_native_bridge()) {
+        ensure_velo_ws().catch(function (_e) {});
+      }
+      return function () {
+        if (exposed_functions[name] === fn) {
+          delete exposed_functions[name];
+        }
+      };
+    }
+    function reply_velo_call(id, body) {
+      body.id = id;
+      body.window = velo_window_name();
+      invoke("/__velo/call/reply", { method: "POST", args: body }).catch(
+        function (_e) {},
+      );
+    }
+    function handle_velo_call(packet) {
+      if (packet.window && packet.window !== velo_window_name()) {
+        return;
+      }
+      var fn = exposed_functions[packet.fn];
+      if (!fn) {
+        reply_velo_call(packet.id, {
+          error: "no function exposed as " + JSON.stringify(packet.fn),
+        });
+        return;
+      }
+      new Promise(function (resolve) {
+        resolve(fn.apply(null, packet.args || []));
+      }).then(
+        function (result) {
+          reply_velo_call(packet.id, {
+            result: result === undefined ? null : result,
+          });
+        },
+        function (err) {
+          reply_velo_call(packet.id, {
+            error: String((err && err.message) || err || "error"),
+          });
+        },
+      );
+    }
     var velo_ws = null;
     var velo_ws_connecting = null;
     function has_native_bridge() {
@@ -121,13 +311,34 @@
           protocol = "wss:";
         }
       } catch (_e) {}
-      return protocol + "//" + host + "/__velo/ws";
+      // The bound Go server address wins over the page origin, so pages served
+      // by a frontend dev server still reach velo when it uses another port.
+      try {
+        var base = window.__VELO__ && window.__VELO__.http_base;
+        if (base) {
+          var parsed = new URL(base);
+          host = parsed.host;
+          protocol = parsed.protocol === "https:" ? "wss:" : "ws:";
+        }
+      } catch (_e) {}
+      var endpoint = protocol + "//" + host + "/__velo/ws";
+      var token = window.__VELO__ && window.__VELO__.token;
+      if (token) {
+        endpoint += "?velo_token=" + encodeURIComponent(token);
+      }
+      return endpoint;
     }
     function handle_velo_ws_message(event) {
       var packet = null;
       try {
-        packet =
-          typeof event.data === "string" ? JSON.parse(event.data) : event.data;
+        if (event.data instanceof ArrayBuffer) {
+          packet = decode_binary_message(event.data);
+        } else {
+          packet =
+            typeof event.data === "string"
+              ? JSON.parse(event.data)
+              : event.data;
+        }
       } catch (_e) {
         return;
       }
@@ -177,6 +388,7 @@
         }
         try {
           socket = new WebSocket(velo_ws_endpoint());
+          socket.binaryType = "arraybuffer";
         } catch (e) {
           finish(reject, e);
           return;
@@ -213,35 +425,303 @@
         return true;
       });
     }
+    // Binary payloads. Over the WebSocket an invoke with an ArrayBuffer or
+    // Blob argument is sent as a binary frame: a big-endian uint32 header
+    // length, the JSON invoke message, then the raw bytes; []byte results
+    // come back the same way. The native bridges only carry strings, so the
+    // bytes travel through /__velo/transfer/ (base64 as a last resort).
+    function is_binary(value) {
+      return (
+        (typeof ArrayBuffer !== "undefined" &&
+          (value instanceof ArrayBuffer || ArrayBuffer.isView(value))) ||
+        (typeof Blob !== "undefined" && value instanceof Blob)
+      );
+    }
+    function binary_to_buffer(value) {
+      if (value instanceof ArrayBuffer) {
+        return Promise.resolve(value);
+      }
+      if (ArrayBuffer.isView(value)) {
+        return Promise.resolve(
+          value.buffer.slice(
+            value.byteOffset,
+            value.byteOffset + value.byteLength,
+          ),
+        );
+      }
+      return value.arrayBuffer();
+    }
+    function encode_binary_message(payload, buffer) {
+      var header = new TextEncoder().encode(JSON.stringify(payload));
+      var out = new Uint8Array(4 + header.length + buffer.byteLength);
+      new DataView(out.buffer).setUint32(0, header.length);
+      out.set(header, 4);
+      out.set(new Uint8Array(buffer), 4 + header.length);
+      return out.buffer;
+    }
+    function decode_binary_message(buffer) {
+      var size = new DataView(buffer).getUint32(0);
+      var header = new TextDecoder().decode(
+        new Uint8Array(buffer, 4, size),
+      );
+      var packet = JSON.parse(header);
+      if (packet && packet.result && typeof packet.result === "object") {
+        packet.result.data = buffer.slice(4 + size);
+      }
+      return packet;
+    }
+    function transfer_url(id) {
+      var base = (window.__VELO__ && window.__VELO__.http_base) || "";
+      return base + "/__velo/transfer/" + (id || "");
+    }
+    function buffer_to_base64(buffer) {
+      var bytes = new Uint8Array(buffer);
+      var chunks = [];
+      for (var i = 0; i < bytes.length; i += 0x8000) {
+        chunks.push(
+          String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000)),
+        );
+      }
+      return btoa(chunks.join(""));
+    }
+    function upload_binary(payload, buffer) {
+      return fetch(transfer_url(), {
+        method: "POST",
+        headers: { "Content-Type": "application/octet-stream" },
+        credentials: "include",
+        body: buffer,
+      })
+        .then(function (resp) {
+          if (!resp.ok) {
+            throw new Error("transfer upload failed: " + resp.status);
+          }
+          return resp.json();
+        })
+        .then(function (body) {
+          payload.binary = body.id;
+        })
+        .catch(function (_e) {
+          payload.binary_base64 = buffer_to_base64(buffer);
+        });
+    }
+    function download_binary(id) {
+      return fetch(transfer_url(id), { credentials: "include" }).then(
+        function (resp) {
+          if (!resp.ok) {
+            throw new Error("transfer download failed: " + resp.status);
+          }
+          return resp.arrayBuffer();
+        },
+      );
+    }
+    function send_invoke_to_go(payload, binary) {
+      if (!binary) {
+        return send_message_to_go(payload);
+      }
+      return binary_to_buffer(binary).then(function (buffer) {
+        if (has_native_bridge()) {
+          return upload_binary(payload, buffer).then(function () {
+            if (!post_message_to_go(payload)) {
+              throw new Error("go bridge not available");
+            }
+          });
+        }
+        return ensure_velo_ws().then(function (socket) {
+          socket.send(encode_binary_message(payload, buffer));
+        });
+      });
+    }
+    function abort_error(reason) {
+      if (reason instanceof Error) {
+        return reason;
+      }
+      var err = new Error(reason ? String(reason) : "The operation was aborted");
+      err.name = "AbortError";
+      return err;
+    }
+    function cancel_invoke(id) {
+      send_message_to_go({
+        id: "",
+        method: "__velo/cancel",
+        args: { id: id },
+      }).catch(function (_e) {});
+    }
+    // invoke(url, { args, body, headers, method, signal, timeout, onChunk })
+    // `body` (or `args` itself) may be an ArrayBuffer, typed array or Blob,
+    // read in Go with BoxContext.Binary; a []byte result resolves with an
+    // ArrayBuffer in `data`.
+    // `signal` is an AbortSignal and `timeout` a number of milliseconds;
+    // either one cancels the Go handler's context and rejects the promise.
+    // Values pushed by a streaming handler (BoxContext.Stream) are passed to
+    // `onChunk` and can be consumed with `for await (const chunk of invoke(...))`;
+    // the promise itself resolves with the final result.
     function invoke(url, args) {
-      return new Promise(function (resolve, reject) {
+      var chunks = [];
+      var waiters = [];
+      var finished = false;
+      var failure = null;
+      function push_chunk(data) {
+        if (waiters.length) {
+          waiters.shift().resolve({ value: data, done: false });
+        } else {
+          chunks.push(data);
+        }
+      }
+      function finish_chunks(err) {
+        finished = true;
+        failure = err || null;
+        while (waiters.length) {
+          var w = waiters.shift();
+          if (failure) {
+            w.reject(failure);
+          } else {
+            w.resolve({ value: undefined, done: true });
+          }
+        }
+      }
+      var promise = new Promise(function (resolve, reject) {
         args = args || {};
         const id = String(Date.now()) + Math.random().toString(16).slice(2);
+        var binary = is_binary(args.body)
+          ? args.body
+          : is_binary(args.args)
+            ? args.args
+            : null;
         const payload = {
           id: id,
           method: url,
           headers: args.headers,
-          args: args.args,
+          args: binary === args.args ? undefined : args.args,
         };
         if (args.method) {
           payload.httpMethod = String(args.method).toUpperCase();
         }
+        var signal = args.signal;
+        var timeout = Number(args.timeout) || 0;
+        if (timeout > 0) {
+          payload.timeout = timeout;
+        }
+        var timer = null;
+        function on_abort() {
+          settle(reject, abort_error(signal && signal.reason));
+          cancel_invoke(id);
+        }
+        function settle(fn, value) {
+          if (!window.invoke_cbs || !window.invoke_cbs[id]) {
+            return;
+          }
+          delete window.invoke_cbs[id];
+          if (timer) {
+            clearTimeout(timer);
+          }
+          if (signal && typeof signal.removeEventListener === "function") {
+            signal.removeEventListener("abort", on_abort);
+          }
+          finish_chunks(fn === reject ? value : null);
+          fn(value);
+        }
+        if (signal && signal.aborted) {
+          var aborted = abort_error(signal.reason);
+          finish_chunks(aborted);
+          reject(aborted);
+          return;
+        }
         ensure_cbs();
         window.invoke_cbs[id] = function (result) {
-          delete window.invoke_cbs[id];
           if (typeof result === "string") {
             try {
-              resolve(JSON.parse(result));
-              return;
+              result = JSON.parse(result);
             } catch (e) {}
           }
-          resolve(result);
+          if (result && result.__velo_stream === true) {
+            if (typeof args.onChunk === "function") {
+              try {
+                args.onChunk(result.data, result.seq);
+              } catch (_e) {}
+            }
+            push_chunk(result.data);
+            return;
+          }
+          if (result && typeof result.binary === "string" && result.binary) {
+            download_binary(result.binary).then(
+              function (buffer) {
+                delete result.binary;
+                result.data = buffer;
+                settle(resolve, result);
+              },
+              function (err) {
+                settle(reject, err);
+              },
+            );
+            return;
+          }
+          settle(resolve, result);
         };
-        send_message_to_go(payload).catch(function (err) {
-          delete window.invoke_cbs[id];
-          reject(err || new Error("go bridge not available"));
+        if (signal && typeof signal.addEventListener === "function") {
+          signal.addEventListener("abort", on_abort);
+        }
+        if (timeout > 0) {
+          timer = setTimeout(function () {
+            var err = new Error("invoke " + url + " timed out after " + timeout + "ms");
+            err.name = "TimeoutError";
+            settle(reject, err);
+          }, timeout);
+        }
+        send_invoke_to_go(payload, binary).catch(function (err) {
+          settle(reject, err || new Error("go bridge not available"));
+        });
+      });
+      if (typeof Symbol === "function" && Symbol.asyncIterator) {
+        promise[Symbol.asyncIterator] = function () {
+          return {
+            next: function () {
+              if (chunks.length) {
+                return Promise.resolve({ value: chunks.shift(), done: false });
+              }
+              if (finished) {
+                return failure
+                  ? Promise.reject(failure)
+                  : Promise.resolve({ value: undefined, done: true });
+              }
+              return new Promise(function (resolve, reject) {
+                waiters.push({ resolve: resolve, reject: reject });
+              });
+            },
+          };
+        };
+      }
+      return promise;
+    }
+    // velo.services.<Type>.<Method>(args, options) calls a method bound with
+    // Box.Bind. It resolves with the result data and rejects with an Error
+    // carrying the result code, reason and details.
+    function call_service(url, args, options) {
+      var opts = Object.assign({ method: "POST" }, options, { args: args });
+      return invoke(url, opts).then(function (result) {
+        if (!result || result.code !== 0) {
+          var err = new Error((result && result.msg) || "service call failed");
+          err.code = result && result.code;
+          err.reason = result && result.reason;
+          err.details = result && result.details;
+          throw err;
+        }
+        return result.data;
+      });
+    }
+    function build_services() {
+      var info = (window.__VELO__ && window.__VELO__.services) || {};
+      var services = {};
+      Object.keys(info).forEach(function (name) {
+        var methods = {};
+        info[name].forEach(function (method) {
+          var url = "/svc/" + name + "/" + method;
+          methods[method] = function (args, options) {
+            return call_service(url, args, options);
+          };
         });
+        services[name] = Object.freeze(methods);
       });
+      return Object.freeze(services);
     }
     Object.defineProperty(window, "invoke", {
       value: invoke,
@@ -257,6 +737,188 @@
         enumerable: false,
       });
     }
+    if (!window.velo) {
+      Object.defineProperty(window, "velo", {
+        value: {
+          invoke: invoke,
+          on: velo_on,
+          off: velo_off,
+          once: velo_once,
+          emit: velo_emit,
+          expose: velo_expose,
+          onBeforeQuit: on_before_quit,
+          services: build_services(),
+        },
+        writable: false,
+        configurable: false,
+        enumerable: false,
+      });
+    }
+    if (!window.onBeforeQuit) {
+      Object.defineProperty(window, "onBeforeQuit", {
+        value: on_before_quit,
+        writable: false,
+        configurable: false,
+        enumerable: false,
+      });
+      window.addEventListener("pagehide", function () {
+        if (before_quit_handlers.length) {
+          set_quit_guard(false);
+        }
+      });
+    }
+    // With desktop.frontend_logs enabled in velo.json, console output and
+    // uncaught errors are sent to the Go log in batches.
+    var log_levels = { debug: 0, log: 1, info: 2, warn: 3, error: 4 };
+    var log_queue = [];
+    var log_timer = null;
+    var log_dropped = 0;
+    var log_busy = false;
+    var log_batch_size = 50;
+    var log_max_queue = 500;
+    var log_max_length = 8192;
+    function truncate_log(text) {
+      text = String(text);
+      if (text.length > log_max_length) {
+        return text.slice(0, log_max_length) + "…";
+      }
+      return text;
+    }
+    function format_log_value(value) {
+      if (typeof value === "string") {
+        return value;
+      }
+      if (value instanceof Error) {
+        return value.stack || String(value);
+      }
+      if (value === undefined || typeof value === "function" || typeof value === "symbol") {
+        return String(value);
+      }
+      try {
+        var text = JSON.stringify(value);
+        return text === undefined ? String(value) : text;
+      } catch (_e) {
+        return String(value);
+      }
+    }
+    function queue_log(entry) {
+      if (log_queue.length >= log_max_queue) {
+        log_dropped += 1;
+        return;
+      }
+      entry.time = Date.now();
+      log_queue.push(entry);
+      if (log_queue.length >= log_batch_size) {
+        flush_logs();
+        return;
+      }
+      if (!log_timer) {
+        log_timer = setTimeout(flush_logs, 1000);
+      }
+    }
+    function flush_logs() {
+      if (log_timer) {
+        clearTimeout(log_timer);
+        log_timer = null;
+      }
+      if (!log_queue.length && !log_dropped) {
+        return;
+      }
+      var entries = log_queue;
+      var dropped = log_dropped;
+      log_queue = [];
+      log_dropped = 0;
+      var busy = log_busy;
+      log_busy = true;
+      try {
+        invoke("/__velo/log", {
+          method: "POST",
+          args: {
+            window: velo_window_name(),
+            url: String(window.location.href),
+            entries: entries,
+            dropped: dropped,
+          },
+        }).catch(function (_e) {});
+      } catch (_e) {}
+      log_busy = busy;
+    }
+    function capture_logs() {
+      var options = window.__VELO__ && window.__VELO__.frontend_logs;
+      if (
+        !options ||
+        Object.prototype.hasOwnProperty.call(window, "__veloLogsCaptured__")
+      ) {
+        return;
+      }
+      Object.defineProperty(window, "__veloLogsCaptured__", {
+        value: true,
+        writable: false,
+        configurable: false,
+      });
+      var min = log_levels[options.level];
+      if (min === undefined) {
+        min = log_levels.warn;
+      }
+      Object.keys(log_levels).forEach(function (level) {
+        var original = console[level];
+        if (log_levels[level] < min || typeof original !== "function") {
+          return;
+        }
+        console[level] = function () {
+          original.apply(console, arguments);
+          if (log_busy) {
+            return;
+          }
+          log_busy = true;
+          try {
+            var parts = [];
+            var stack = "";
+            for (var i = 0; i < arguments.length; i++) {
+              parts.push(format_log_value(arguments[i]));
+              if (!stack && arguments[i] instanceof Error && arguments[i].stack) {
+                stack = truncate_log(arguments[i].stack);
+              }
+            }
+            queue_log({
+              level: level,
+              kind: "console",
+              message: truncate_log(parts.join(" ")),
+              stack: stack,
+            });
+          } catch (_e) {}
+          log_busy = false;
+        };
+      });
+      window.addEventListener("error", function (ev) {
+        var err = ev && ev.error;
+        queue_log({
+          level: "error",
+          kind: "error",
+          message: truncate_log(ev.message || format_log_value(err)),
+          stack: err && err.stack ? truncate_log(err.stack) : "",
+          source: ev.filename || "",
+          line: ev.lineno || 0,
+          column: ev.colno || 0,
+        });
+      });
+      window.addEventListener("unhandledrejection", function (ev) {
+        var reason = ev && ev.reason;
+        queue_log({
+          level: "error",
+          kind: "rejection",
+          message: truncate_log(
+            "Unhandled rejection: " +
+              (reason instanceof Error ? String(reason) : format_log_value(reason)),
+          ),
+          stack: reason && reason.stack ? truncate_log(reason.stack) : "",
+        });
+      });
+      window.addEventListener("pagehide", flush_logs);
+    }
+    try {
+      capture_logs();
+    } catch (_e) {}
     ensure_go_msg_handlers();
     notify_go_ready();
     Object.defineProperty(invoke, "toString", {
@@ -0,0 +1,224 @@
+package velo
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ltaoo/velo/buildcfg"
+)
+
+// OpenURLHandler receives a link of one of the URL schemes registered in
+// velo.json's protocols section, e.g. "myapp://open?note=123".
+type OpenURLHandler func(url string)
+
+// OpenFilesHandler receives the absolute paths of files the system asked
+// the app to open, e.g. a double-clicked document of one of the types in
+// velo.json's document_types section.
+type OpenFilesHandler func(paths []string)
+
+// launchQueue holds what the app was asked to open until Run has started
+// the app and a handler is registered.
+type launchQueue[T any] struct {
+	mu       sync.Mutex
+	started  bool
+	handlers []func(T)
+	pending  []T
+}
+
+func newLaunchQueue[T any]() *launchQueue[T] {
+	return &launchQueue[T]{}
+}
+
+func (q *launchQueue[T]) add(fn func(T)) {
+	q.mu.Lock()
+	q.handlers = append(q.handlers, fn)
+	q.mu.Unlock()
+	q.flush()
+}
+
+func (q *launchQueue[T]) open(items ...T) {
+	if len(items) == 0 {
+		return
+	}
+	q.mu.Lock()
+	q.pending = append(q.pending, items...)
+	q.mu.Unlock()
+	q.flush()
+}
+
+func (q *launchQueue[T]) start() {
+	q.mu.Lock()
+	q.started = true
+	q.mu.Unlock()
+	q.flush()
+}
+
+func (q *launchQueue[T]) flush() {
+	q.mu.Lock()
+	if !q.started || len(q.handlers) == 0 || len(q.pending) == 0 {
+		q.mu.Unlock()
+		return
+	}
+	items := q.pending
+	q.pending = nil
+	handlers := make([]func(T), len(q.handlers))
+	copy(handlers, q.handlers)
+	q.mu.Unlock()
+	for _, item := range items {
+		for _, fn := range handlers {
+			fn(item)
+		}
+	}
+}
+
+// OnOpenURL registers fn to receive the links of the schemes in velo.json's
+// protocols section. A link that launched the app is delivered once the app
+// has started; links opened while it runs are delivered as they arrive. On
+// Windows and Linux a running app only receives them with
+// VeloAppOpt.SingleInstance set, otherwise each link starts a new instance.
+func (b *Box) OnOpenURL(fn OpenURLHandler) {
+	b.urls.add(fn)
+}
+
+// OnOpenFiles registers fn to receive the files the system hands to the
+// app, such as documents of the types in velo.json's document_types section
+// that the user double-clicked. Like OnOpenURL, files that launched the app
+// are delivered once it has started, and a running app on Windows and Linux
+// needs VeloAppOpt.SingleInstance to receive them.
+func (b *Box) OnOpenFiles(fn OpenFilesHandler) {
+	b.files.add(fn)
+}
+
+// schemeURLs returns the arguments that are links of the registered
+// schemes.
+func (b *Box) schemeURLs(args []string) []string {
+	var urls []string
+	for _, arg := range args {
+		scheme, _, ok := strings.Cut(arg, ":")
+		if !ok {
+			continue
+		}
+		for _, p := range b.appConfig.Protocols {
+			if strings.EqualFold(scheme, p.Scheme) {
+				urls = append(urls, arg)
+				break
+			}
+		}
+	}
+	return urls
+}
+
+// documentPaths returns the arguments that are files of the registered
+// document types, resolved against cwd. Desktop entries may pass them as
+// file:// URLs.
+func (b *Box) documentPaths(args []string, cwd string) []string {
+	var paths []string
+	for _, arg := range args {
+		if p, ok := fileURLPath(arg); ok {
+			arg = p
+		}
+		if !b.isDocument(arg) {
+			continue
+		}
+		if !filepath.IsAbs(arg) {
+			arg = filepath.Join(cwd, arg)
+		}
+		paths = append(paths, arg)
+	}
+	return paths
+}
+
+func (b *Box) isDocument(path string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return false
+	}
+	for _, d := range b.appConfig.DocumentTypes {
+		for _, e := range d.Extensions {
+			if strings.EqualFold(ext, e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fileURLPath(s string) (string, bool) {
+	if len(s) < 7 || !strings.EqualFold(s[:7], "file://") {
+		return "", false
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), true
+}
+
+// openLaunchArgs delivers the links and files in the arguments of a launch.
+func (b *Box) openLaunchArgs(args []string, cwd string) {
+	b.urls.open(b.schemeURLs(args)...)
+	if paths := b.documentPaths(args, cwd); len(paths) > 0 {
+		b.files.open(paths)
+	}
+}
+
+// openURLs is the webview's HandleOpenURL: links and files delivered by the
+// system rather than on the command line.
+func (b *Box) openURLs(urls []string) {
+	var paths []string
+	for _, u := range urls {
+		if p, ok := fileURLPath(u); ok {
+			paths = append(paths, p)
+			continue
+		}
+		b.urls.open(u)
+	}
+	if len(paths) > 0 {
+		b.files.open(paths)
+	}
+}
+
+// useAssociations queues the links and files this launch was started with.
+// Run registers the schemes and document types where the system reads them
+// from the registry, so that velo generate and tests leave it alone.
+func (b *Box) useAssociations() {
+	b.dropInvalidAssociations()
+	if len(b.appConfig.Protocols) == 0 && len(b.appConfig.DocumentTypes) == 0 {
+		return
+	}
+	cwd, _ := os.Getwd()
+	b.openLaunchArgs(os.Args[1:], cwd)
+}
+
+// dropInvalidAssociations removes the schemes and document types that velo
+// build would reject. The runtime config is not validated when it is
+// loaded, and registering such an entry would fail or panic. The entries
+// are filtered into a copy of the config, which may be VeloAppOpt's.
+func (b *Box) dropInvalidAssociations() {
+	var protocols []buildcfg.ProtocolSection
+	for i, p := range b.appConfig.Protocols {
+		if err := p.Validate(); err != nil {
+			b.Log("associations").Warn("ignoring protocol", "index", i, "error", err)
+			continue
+		}
+		protocols = append(protocols, p)
+	}
+	var docs []buildcfg.DocumentTypeSection
+	for i, d := range b.appConfig.DocumentTypes {
+		if err := d.Validate(); err != nil {
+			b.Log("associations").Warn("ignoring document type", "index", i, "error", err)
+			continue
+		}
+		docs = append(docs, d)
+	}
+	if len(protocols) == len(b.appConfig.Protocols) && len(docs) == len(b.appConfig.DocumentTypes) {
+		return
+	}
+	cfg := *b.appConfig
+	cfg.Protocols = protocols
+	cfg.DocumentTypes = docs
+	b.appConfig = &cfg
+}
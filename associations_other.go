@@ -0,0 +1,9 @@
+//go:build !windows
+
+package velo
+
+// registerAssociations is a no-op where the schemes and document types are
+// declared by the app bundle (macOS) or its desktop entry (Linux).
+func registerAssociations(cfg *AppConfig) error {
+	return nil
+}
@@ -0,0 +1,100 @@
+package velo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ltaoo/velo/buildcfg"
+)
+
+func TestOpenURLWaitsForStart(t *testing.T) {
+	cfg := &AppConfig{Protocols: []buildcfg.ProtocolSection{{Scheme: "myapp"}}}
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, AppConfig: cfg})
+
+	urls := app.schemeURLs([]string{"--flag", "MyApp://open?note=123", "https://example.com", "other:x"})
+	if want := []string{"MyApp://open?note=123"}; !reflect.DeepEqual(urls, want) {
+		t.Fatalf("schemeURLs = %v, want %v", urls, want)
+	}
+
+	var got []string
+	app.urls.open(urls...)
+	app.OnOpenURL(func(url string) { got = append(got, url) })
+	if len(got) != 0 {
+		t.Fatalf("delivered %v before start", got)
+	}
+	app.urls.start()
+	app.openURLs([]string{"myapp://open?note=456"})
+	if want := []string{"MyApp://open?note=123", "myapp://open?note=456"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+}
+
+func TestOpenFiles(t *testing.T) {
+	cfg := &AppConfig{DocumentTypes: []buildcfg.DocumentTypeSection{{Extensions: []string{"vnote"}}}}
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, AppConfig: cfg})
+	cwd := t.TempDir()
+
+	paths := app.documentPaths([]string{"a.vnote", "/notes/B.VNOTE", "file:///notes/c%20d.vnote", "e.txt", "--flag"}, cwd)
+	want := []string{filepath.Join(cwd, "a.vnote"), filepath.FromSlash("/notes/B.VNOTE"), filepath.FromSlash("/notes/c d.vnote")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("documentPaths = %v, want %v", paths, want)
+	}
+
+	var got [][]string
+	app.OnOpenFiles(func(paths []string) { got = append(got, paths) })
+	app.files.start()
+	app.openURLs([]string{"file:///notes/e.txt", "file:///notes/f.vnote"})
+	if want := [][]string{{filepath.FromSlash("/notes/e.txt"), filepath.FromSlash("/notes/f.vnote")}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("OnOpenFiles got %v, want %v", got, want)
+	}
+}
+
+func TestSecondInstanceOpensURL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	exitSecondInstance = func() {}
+	defer func() { exitSecondInstance = func() { os.Exit(0) } }()
+
+	cfg := &AppConfig{Protocols: []buildcfg.ProtocolSection{{Scheme: "myapp"}}}
+	opt := &VeloAppOpt{Mode: ModeHttp, AppName: "velo-protocol-test", AppConfig: cfg, SingleInstance: true}
+	app := NewApp(opt)
+	defer app.shutdown(context.Background())
+	got := make(chan string, 1)
+	app.OnOpenURL(func(url string) { got <- url })
+	app.urls.start()
+
+	if err := forwardInstance(instanceSocketPath(opt.AppName), instanceMessage{Args: []string{"myapp://open?note=123"}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case url := <-got:
+		if url != "myapp://open?note=123" {
+			t.Fatalf("OnOpenURL got %q", url)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnOpenURL not called")
+	}
+}
+
+func TestInvalidAssociationsAreDropped(t *testing.T) {
+	cfg := &AppConfig{
+		Protocols: []buildcfg.ProtocolSection{{Scheme: "my app"}, {Scheme: "myapp"}},
+		DocumentTypes: []buildcfg.DocumentTypeSection{
+			{Name: "Broken"},
+			{Name: "Velo Note", Extensions: []string{"vnote"}},
+		},
+	}
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, AppConfig: cfg})
+	if len(app.appConfig.Protocols) != 1 || app.appConfig.Protocols[0].Scheme != "myapp" {
+		t.Fatalf("protocols = %+v", app.appConfig.Protocols)
+	}
+	if len(app.appConfig.DocumentTypes) != 1 || app.appConfig.DocumentTypes[0].MIMEType() != "application/x-vnote" {
+		t.Fatalf("document types = %+v", app.appConfig.DocumentTypes)
+	}
+	if len(cfg.Protocols) != 2 || len(cfg.DocumentTypes) != 2 {
+		t.Fatalf("caller's config was changed: %+v", cfg)
+	}
+}
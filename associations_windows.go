@@ -0,0 +1,128 @@
+//go:build windows
+
+package velo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ltaoo/velo/buildcfg"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const shcneAssocChanged = 0x08000000
+
+var procSHChangeNotify = windows.NewLazySystemDLL("shell32.dll").NewProc("SHChangeNotify")
+
+type registryValue struct {
+	path, name, value string
+}
+
+// registerAssociations points the per-user registry entries of the schemes
+// and document types in cfg at the running executable, so that a portable
+// build handles its links and files without an installer. The entries match
+// the registry.reg.template written by buildcfg. Entries that another
+// program, or another copy of the app, has claimed are left alone, as is an
+// extension whose default the user has set to another app; values that
+// already match are not rewritten.
+func registerAssociations(cfg *AppConfig) error {
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	command := `"` + execPath + `" "%1"`
+	var values []registryValue
+	for _, p := range cfg.Protocols {
+		name := p.Name
+		if name == "" {
+			name = cfg.displayName()
+		}
+		base := `Software\Classes\` + strings.ToLower(p.Scheme)
+		if !unclaimedOr(base+`\shell\open\command`, "", command) {
+			continue
+		}
+		values = append(values,
+			registryValue{base, "", "URL:" + name},
+			registryValue{base, "URL Protocol", ""},
+			registryValue{base + `\DefaultIcon`, "", execPath + ",0"},
+			registryValue{base + `\shell\open\command`, "", command},
+		)
+	}
+	var docs bool
+	for i, d := range cfg.DocumentTypes {
+		progID := buildcfg.ProgID(cfg.App.Name, d)
+		base := `Software\Classes\` + progID
+		if !unclaimedOr(base+`\shell\open\command`, "", command) {
+			continue
+		}
+		docs = true
+		name := d.Name
+		if name == "" {
+			name = cfg.displayName() + " Document"
+		}
+		icon := execPath + ",0"
+		if d.Icon != "" {
+			icon = execPath + ",-" + strconv.Itoa(buildcfg.FirstDocumentIconID+i)
+		}
+		values = append(values,
+			registryValue{base, "", name},
+			registryValue{base + `\DefaultIcon`, "", icon},
+			registryValue{base + `\shell\open\command`, "", command},
+		)
+		for _, ext := range d.Extensions {
+			extKey := `Software\Classes\.` + strings.ToLower(ext)
+			// Offer the app under Open with even when the extension opens
+			// with another one by default.
+			values = append(values, registryValue{extKey + `\OpenWithProgids`, progID, ""})
+			if unclaimedOr(extKey, "", progID) {
+				values = append(values,
+					registryValue{extKey, "", progID},
+					registryValue{extKey, "Content Type", d.MIMEType()},
+				)
+			}
+		}
+	}
+	var changed bool
+	for _, v := range values {
+		if current, ok := readRegistryValue(v.path, v.name); ok && current == v.value {
+			continue
+		}
+		key, _, err := registry.CreateKey(registry.CURRENT_USER, v.path, registry.SET_VALUE)
+		if err != nil {
+			return fmt.Errorf("failed to create registry key %s: %w", v.path, err)
+		}
+		err = key.SetStringValue(v.name, v.value)
+		key.Close()
+		if err != nil {
+			return fmt.Errorf("failed to set registry value %s: %w", v.path, err)
+		}
+		changed = true
+	}
+	if changed && docs {
+		procSHChangeNotify.Call(shcneAssocChanged, 0, 0, 0)
+	}
+	return nil
+}
+
+// unclaimedOr reports whether the per-user registry value is missing or
+// already holds want.
+func unclaimedOr(path, name, want string) bool {
+	current, ok := readRegistryValue(path, name)
+	return !ok || current == "" || current == want
+}
+
+func readRegistryValue(path, name string) (string, bool) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, path, registry.QUERY_VALUE)
+	if err != nil {
+		return "", false
+	}
+	defer key.Close()
+	value, _, err := key.GetStringValue(name)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
@@ -0,0 +1,157 @@
+package velo
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// VeloTokenHeader carries the per-launch session token on HTTP requests.
+	VeloTokenHeader = "X-Velo-Token"
+	// VeloTokenParam carries the token in a query string, for WebSocket
+	// upgrades and the first page load of a window.
+	VeloTokenParam = "velo_token"
+	// veloTokenCookie prefixes the cookie that keeps the token for
+	// same-origin requests once a page has been opened with VeloTokenParam.
+	veloTokenCookie = "velo_token"
+)
+
+// Token returns the random secret generated for this launch. In ModeHttp and
+// ModeBridgeHttp every API, WebSocket and runtime request must present it
+// unless VeloAppOpt.DisableTokenAuth is set.
+func (b *Box) Token() string {
+	return b.token
+}
+
+func (b *Box) authRequired() bool {
+	return usesHTTP(b.mode) && b.token != ""
+}
+
+func requestToken(r *http.Request) string {
+	if v := r.Header.Get(VeloTokenHeader); v != "" {
+		return v
+	}
+	if v := r.URL.Query().Get(VeloTokenParam); v != "" {
+		return v
+	}
+	if c, err := r.Cookie(tokenCookieName(r)); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// tokenCookieName names the session cookie after the port r was sent to.
+// Cookies are scoped by host but not by port, so every velo app on
+// 127.0.0.1 would otherwise see, and overwrite, the others' token.
+func tokenCookieName(r *http.Request) string {
+	if _, port, err := net.SplitHostPort(r.Host); err == nil && port != "" {
+		return veloTokenCookie + "_" + port
+	}
+	return veloTokenCookie
+}
+
+func (b *Box) validToken(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(b.token)) == 1
+}
+
+// allowedOrigin reports whether a browser Origin may talk to the server:
+// the server's own origin, the velo:// scheme, or one listed in
+// VeloAppOpt.AllowedOrigins. Requests without an Origin come from non-browser
+// clients and are authorised by the token alone.
+func (b *Box) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "velo" {
+		return true
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range b.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks the token and Origin of r. A valid token passed in the
+// query string is remembered in an HttpOnly, SameSite=Strict cookie so that
+// the page's own fetches keep working without handling the token.
+func (b *Box) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if !b.authRequired() {
+		return true
+	}
+	if !b.allowedOrigin(r) {
+		return false
+	}
+	if !b.validToken(requestToken(r)) {
+		return false
+	}
+	b.rememberToken(w, r)
+	return true
+}
+
+func (b *Box) rememberToken(w http.ResponseWriter, r *http.Request) {
+	if !b.validToken(r.URL.Query().Get(VeloTokenParam)) {
+		return
+	}
+	name := tokenCookieName(r)
+	if c, err := r.Cookie(name); err == nil && b.validToken(c.Value) {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    b.token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// requireToken guards h with authorize. It fails with 401, like API
+// routes do.
+func (b *Box) requireToken(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !b.authorize(w, r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// withToken lets static frontend files load without a token but still turns
+// a token in the query string into the session cookie.
+func (b *Box) withToken(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.authRequired() {
+			b.rememberToken(w, r)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// tokenURL appends the session token to a window URL served by this box.
+func (b *Box) tokenURL(raw string) string {
+	if !b.authRequired() {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	q.Set(VeloTokenParam, b.token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
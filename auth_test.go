@@ -0,0 +1,161 @@
+package velo
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newAuthTestApp(opt *VeloAppOpt) (*Box, *httptest.Server) {
+	app := NewApp(opt)
+	app.Get("/api/ping", func(c *BoxContext) interface{} { return c.Ok("pong") })
+	app.Post("/api/save", func(c *BoxContext) interface{} { return c.Ok(nil) })
+	return app, httptest.NewServer(app.setupMux(nil, ""))
+}
+
+func doAuthRequest(t *testing.T, method, url string, header http.Header) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestAPIRequiresLaunchToken(t *testing.T) {
+	app, server := newAuthTestApp(&VeloAppOpt{Mode: ModeHttp})
+	defer server.Close()
+
+	if app.Token() == "" {
+		t.Fatal("no token generated for ModeHttp")
+	}
+	for _, tc := range []struct {
+		name   string
+		url    string
+		header http.Header
+		status int
+	}{
+		{"missing", server.URL + "/api/ping", nil, http.StatusUnauthorized},
+		{"wrong", server.URL + "/api/ping", http.Header{VeloTokenHeader: {"nope"}}, http.StatusUnauthorized},
+		{"header", server.URL + "/api/ping", http.Header{VeloTokenHeader: {app.Token()}}, http.StatusOK},
+		{"query", server.URL + "/api/ping?" + VeloTokenParam + "=" + app.Token(), nil, http.StatusOK},
+		{"foreign origin", server.URL + "/api/ping", http.Header{VeloTokenHeader: {app.Token()}, "Origin": {"http://evil.example"}}, http.StatusUnauthorized},
+		{"ws missing", server.URL + VeloWebSocketPath, nil, http.StatusUnauthorized},
+		{"runtime missing", server.URL + "/__velo/runtime.js", nil, http.StatusUnauthorized},
+		{"post route missing", server.URL + "/api/save", nil, http.StatusUnauthorized},
+	} {
+		resp := doAuthRequest(t, http.MethodGet, tc.url, tc.header)
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, resp.StatusCode, tc.status)
+		}
+	}
+}
+
+func TestQueryTokenSetsSessionCookie(t *testing.T) {
+	app, server := newAuthTestApp(&VeloAppOpt{Mode: ModeHttp})
+	defer server.Close()
+
+	resp := doAuthRequest(t, http.MethodGet, server.URL+"/api/ping?"+VeloTokenParam+"="+app.Token(), nil)
+	var cookie *http.Cookie
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	for _, c := range resp.Cookies() {
+		if c.Name == veloTokenCookie+"_"+port {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("session cookie = %+v", cookie)
+	}
+
+	resp = doAuthRequest(t, http.MethodGet, server.URL+"/api/ping", http.Header{"Cookie": {cookie.String()}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("cookie request status = %d", resp.StatusCode)
+	}
+
+	// Another velo app on the same host does not accept this app's cookie.
+	other, otherServer := newAuthTestApp(&VeloAppOpt{Mode: ModeHttp})
+	defer otherServer.Close()
+	if other.Token() == app.Token() {
+		t.Fatal("apps share a token")
+	}
+	resp = doAuthRequest(t, http.MethodGet, otherServer.URL+"/api/ping", http.Header{"Cookie": {cookie.String()}})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("other port cookie status = %d", resp.StatusCode)
+	}
+}
+
+func TestAllowedOriginsAndOptOut(t *testing.T) {
+	app, server := newAuthTestApp(&VeloAppOpt{Mode: ModeHttp, AllowedOrigins: []string{"http://localhost:5173"}})
+	defer server.Close()
+
+	resp := doAuthRequest(t, http.MethodGet, server.URL+"/api/ping", http.Header{VeloTokenHeader: {app.Token()}, "Origin": {"http://localhost:5173"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("allowed origin status = %d", resp.StatusCode)
+	}
+
+	app, open := newAuthTestApp(&VeloAppOpt{Mode: ModeHttp, DisableTokenAuth: true})
+	defer open.Close()
+	if app.Token() != "" {
+		t.Fatal("token generated with DisableTokenAuth")
+	}
+	if resp := doAuthRequest(t, http.MethodGet, open.URL+"/api/ping", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("DisableTokenAuth status = %d", resp.StatusCode)
+	}
+}
+
+// lockedBuffer collects the log of an app that serves in the background.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServingDoesNotLogTheToken(t *testing.T) {
+	var out lockedBuffer
+	cfg := &AppConfig{}
+	cfg.Desktop.Addr = "127.0.0.1:0"
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, AppConfig: cfg, Logger: slog.New(slog.NewTextHandler(&out, nil))})
+	app.mux = app.setupMux(nil, "")
+	go app.listenAndServe()
+	t.Cleanup(func() {
+		app.listenMu.Lock()
+		server := app.server
+		app.listenMu.Unlock()
+		if server != nil {
+			server.Close()
+		}
+	})
+
+	for i := 0; i < 50 && !strings.Contains(out.String(), "launch token"); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	got := out.String()
+	if !strings.Contains(got, "launch token") {
+		t.Fatalf("log does not say how to open the app:\n%s", got)
+	}
+	if strings.Contains(got, app.Token()) {
+		t.Fatalf("log holds the token:\n%s", got)
+	}
+}
@@ -0,0 +1,145 @@
+package velo
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VeloTransferPath serves binary payloads for the native bridges, which can
+// only pass strings: the runtime uploads an invoke argument with a POST and
+// downloads a []byte result with a GET of the id found in BoxResult.Binary.
+const VeloTransferPath = "/__velo/transfer/"
+
+const (
+	transferTTL     = time.Minute
+	maxTransferSize = 256 << 20
+)
+
+// Binary returns the binary argument of the call: an ArrayBuffer or Blob
+// passed to invoke, or the body of an HTTP request that is not JSON. It is
+// nil when the call carried JSON arguments only.
+//
+// A handler returns binary data by returning a []byte; it reaches the
+// frontend as an ArrayBuffer in result.data, and HTTP clients as the raw
+// response body.
+func (c *BoxContext) Binary() []byte {
+	return c.body
+}
+
+// encodeBinaryMessage builds the payload of a binary WebSocket frame: the
+// length of the JSON header as a big-endian uint32, the header, then the
+// raw body.
+func encodeBinaryMessage(header, body []byte) []byte {
+	out := make([]byte, 4+len(header)+len(body))
+	binary.BigEndian.PutUint32(out, uint32(len(header)))
+	copy(out[4:], header)
+	copy(out[4+len(header):], body)
+	return out
+}
+
+func decodeBinaryMessage(payload []byte) (header, body []byte, err error) {
+	if len(payload) < 4 {
+		return nil, nil, errors.New("binary message too short")
+	}
+	n := binary.BigEndian.Uint32(payload)
+	if uint64(n) > uint64(len(payload)-4) {
+		return nil, nil, errors.New("binary message header overflows frame")
+	}
+	return payload[4 : 4+n], payload[4+n:], nil
+}
+
+// decodeBase64Body is the fallback used when a native bridge cannot upload
+// to VeloTransferPath.
+func decodeBase64Body(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+type transfer struct {
+	data    []byte
+	expires time.Time
+}
+
+// transferStore keeps binary payloads for a single GET, or until they
+// expire.
+type transferStore struct {
+	mu    sync.Mutex
+	items map[string]transfer
+}
+
+func newTransferStore() *transferStore {
+	return &transferStore{items: make(map[string]transfer)}
+}
+
+func (s *transferStore) put(data []byte) string {
+	id := generateID()
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, t := range s.items {
+		if now.After(t.expires) {
+			delete(s.items, k)
+		}
+	}
+	s.items[id] = transfer{data: data, expires: now.Add(transferTTL)}
+	return id
+}
+
+func (s *transferStore) take(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.items[id]
+	delete(s.items, id)
+	if !ok || time.Now().After(t.expires) {
+		return nil, false
+	}
+	return t.data, true
+}
+
+func (b *Box) serveTransfer(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxTransferSize+1))
+		if err != nil || len(data) > maxTransferSize {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": b.transfers.put(data)})
+	case http.MethodGet:
+		data, ok := b.transfers.take(strings.TrimPrefix(r.URL.Path, VeloTransferPath))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(data)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeBinary writes a []byte handler result as the HTTP response body.
+func (c *BoxContext) writeBinary(w http.ResponseWriter) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(c.bytes))
+	}
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+	}
+	w.Write(c.bytes)
+}
@@ -0,0 +1,122 @@
+package velo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// newBinaryTestApp registers a handler that reverses its binary argument.
+func newBinaryTestApp() *Box {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Post("/api/reverse", func(c *BoxContext) interface{} {
+		in := c.Binary()
+		out := make([]byte, len(in))
+		for i, b := range in {
+			out[len(in)-1-i] = b
+		}
+		return out
+	})
+	return app
+}
+
+func TestBinaryFrameRoundTrip(t *testing.T) {
+	app := newBinaryTestApp()
+	server := newTestServer(t, app)
+	client := dialTestWS(t, server.URL)
+	defer client.close()
+
+	header := []byte(`{"id":"b1","method":"/api/reverse","httpMethod":"POST"}`)
+	if err := writeMaskedWSFrame(client.conn, wsOpcodeBinary, encodeBinaryMessage(header, []byte{1, 2, 3, 0})); err != nil {
+		t.Fatalf("write binary frame: %v", err)
+	}
+	_, opcode, payload, err := readWSFrame(client.reader)
+	if err != nil {
+		t.Fatalf("read callback: %v", err)
+	}
+	if opcode != wsOpcodeBinary {
+		t.Fatalf("opcode = %d, want a binary frame", opcode)
+	}
+	head, body, err := decodeBinaryMessage(payload)
+	if err != nil {
+		t.Fatalf("decode binary frame: %v", err)
+	}
+	var callback struct {
+		Type   string    `json:"type"`
+		ID     string    `json:"id"`
+		Result BoxResult `json:"result"`
+	}
+	if err := json.Unmarshal(head, &callback); err != nil || callback.ID != "b1" || callback.Result.Code != CodeOK {
+		t.Fatalf("callback header = %s (%v)", head, err)
+	}
+	if !bytes.Equal(body, []byte{0, 3, 2, 1}) {
+		t.Fatalf("body = %v", body)
+	}
+}
+
+func TestBinaryOverNativeBridgeUsesTransfers(t *testing.T) {
+	app := newBinaryTestApp()
+	server := newTestServer(t, app)
+
+	resp, err := http.Post(server.URL+VeloTransferPath, "application/octet-stream", bytes.NewReader([]byte("abc")))
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	var upload struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&upload)
+	resp.Body.Close()
+	if upload.ID == "" {
+		t.Fatal("upload returned no id")
+	}
+
+	msg, _ := json.Marshal(H{"id": "1", "method": "/api/reverse", "httpMethod": "POST", "binary": upload.ID})
+	_, raw := app.handleMessage(string(msg))
+	var res BoxResult
+	if err := json.Unmarshal([]byte(raw), &res); err != nil || res.Code != CodeOK || res.Binary == "" {
+		t.Fatalf("result = %s", raw)
+	}
+
+	resp, err = http.Get(server.URL + VeloTransferPath + res.Binary)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "cba" {
+		t.Fatalf("download = %q", data)
+	}
+	resp, _ = http.Get(server.URL + VeloTransferPath + res.Binary)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("second download status = %d, want 404", resp.StatusCode)
+	}
+
+	msg, _ = json.Marshal(H{"id": "2", "method": "/api/reverse", "httpMethod": "POST", "binary_base64": "eHl6"})
+	_, raw = app.handleMessage(string(msg))
+	json.Unmarshal([]byte(raw), &res)
+	if data, ok := app.transfers.take(res.Binary); !ok || string(data) != "zyx" {
+		t.Fatalf("base64 result = %q, %v", data, ok)
+	}
+}
+
+func TestHTTPBinaryBody(t *testing.T) {
+	app := newBinaryTestApp()
+	server := newTestServer(t, app)
+
+	resp, err := http.Post(server.URL+"/api/reverse", "application/octet-stream", bytes.NewReader([]byte(">lmth<")))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if string(data) != "<html>" {
+		t.Fatalf("body = %q", data)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("content type = %q, want the detected type", ct)
+	}
+}
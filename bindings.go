@@ -0,0 +1,122 @@
+package velo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ltaoo/velo/tsgen"
+)
+
+const (
+	// BindingsDirEnv makes Run write the TypeScript bindings of the
+	// registered routes to the named directory and return instead of
+	// starting the app. `velo generate` runs the app with it set.
+	BindingsDirEnv = "VELO_BINDINGS_DIR"
+	// OpenAPIFileEnv makes Run write the OpenAPI document to the named file
+	// and return. `velo openapi` runs the app with it set.
+	OpenAPIFileEnv = "VELO_OPENAPI_FILE"
+)
+
+// RouteInfo describes a registered route for tooling.
+type RouteInfo struct {
+	Method string
+	Path   string
+	// Request and Response are the types of a Typed handler or bound
+	// service method, nil for other handlers.
+	Request  reflect.Type
+	Response reflect.Type
+	// Service and Name identify a method registered with Bind.
+	Service string
+	Name    string
+}
+
+// Routes lists the routes registered with Get, Post and Bind, sorted by
+// path. The built-in /__velo/ routes used by the runtime are left out.
+func (b *Box) Routes() []RouteInfo {
+	var routes []RouteInfo
+	add := func(method string, handlers map[string]Handler) {
+		for path := range handlers {
+			if strings.HasPrefix(path, "/__velo/") {
+				continue
+			}
+			r := RouteInfo{Method: method, Path: path}
+			if types := b.route_types[method+" "+path]; types != nil {
+				r.Request, r.Response = types.Request, types.Response
+			}
+			if rest, ok := strings.CutPrefix(path, ServicePrefix); ok {
+				if svc, name, ok := strings.Cut(rest, "/"); ok && b.services[svc] != nil {
+					r.Service, r.Name = svc, name
+				}
+			}
+			routes = append(routes, r)
+		}
+	}
+	add(http.MethodGet, b.get_handlers)
+	add(http.MethodPost, b.post_handlers)
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// WriteBindings writes velo.d.ts, which types window.invoke, window.velo and
+// window.__VELO__, and client.ts, a typed client for the registered routes,
+// to dir.
+func (b *Box) WriteBindings(dir string) error {
+	var routes []tsgen.Route
+	for _, r := range b.Routes() {
+		routes = append(routes, tsgen.Route{
+			Method:   r.Method,
+			Path:     r.Path,
+			Request:  r.Request,
+			Response: r.Response,
+			Service:  r.Service,
+			Name:     r.Name,
+		})
+	}
+	dts, client := tsgen.Generate(routes, reflect.TypeOf(veloRuntimeInfo{}))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "velo.d.ts"), dts, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "client.ts"), client, 0o644); err != nil {
+		return err
+	}
+	return nil
+}
+
+// generateFromEnv handles BindingsDirEnv and OpenAPIFileEnv for Run,
+// reporting whether anything was generated.
+func (b *Box) generateFromEnv() bool {
+	generated := false
+	if dir := os.Getenv(BindingsDirEnv); dir != "" {
+		if err := b.WriteBindings(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "[velo] bindings: %v\n", err)
+			os.Exit(1)
+		}
+		generated = true
+	}
+	if file := os.Getenv(OpenAPIFileEnv); file != "" {
+		data, err := json.MarshalIndent(b.OpenAPI(), "", "  ")
+		if err == nil {
+			err = os.WriteFile(file, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[velo] openapi: %v\n", err)
+			os.Exit(1)
+		}
+		generated = true
+	}
+	return generated
+}
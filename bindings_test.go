@@ -0,0 +1,50 @@
+package velo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoutesDescribeTypedHandlersAndServices(t *testing.T) {
+	app := newTypedTestApp()
+	if err := app.Bind(&NoteService{}); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	app.Get("/api/plain", func(c *BoxContext) interface{} { return c.Ok(nil) })
+
+	byKey := make(map[string]RouteInfo)
+	for _, r := range app.Routes() {
+		if strings.HasPrefix(r.Path, "/__velo/") {
+			t.Fatalf("internal route listed: %+v", r)
+		}
+		byKey[r.Method+" "+r.Path] = r
+	}
+	if r := byKey["POST /api/notes/:id"]; r.Request != reflect.TypeOf(createNoteReq{}) || r.Response != reflect.TypeOf(createNoteResp{}) {
+		t.Fatalf("typed route = %+v", r)
+	}
+	if r := byKey["POST /svc/NoteService/Create"]; r.Service != "NoteService" || r.Name != "Create" {
+		t.Fatalf("service route = %+v", r)
+	}
+	if r, ok := byKey["GET /api/plain"]; !ok || r.Request != nil {
+		t.Fatalf("plain route = %+v", r)
+	}
+}
+
+func TestWriteBindings(t *testing.T) {
+	app := newTypedTestApp()
+	dir := filepath.Join(t.TempDir(), "velo")
+	if err := app.WriteBindings(dir); err != nil {
+		t.Fatalf("WriteBindings: %v", err)
+	}
+	dts, err := os.ReadFile(filepath.Join(dir, "velo.d.ts"))
+	if err != nil || !strings.Contains(string(dts), "export interface CreateNoteReq") || !strings.Contains(string(dts), "export interface VeloRuntimeInfo") {
+		t.Fatalf("velo.d.ts = %s (%v)", dts, err)
+	}
+	client, err := os.ReadFile(filepath.Join(dir, "client.ts"))
+	if err != nil || !strings.Contains(string(client), "export function postApiNotesById(") {
+		t.Fatalf("client.ts = %s (%v)", client, err)
+	}
+}
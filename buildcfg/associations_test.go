@@ -0,0 +1,144 @@
+package buildcfg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func associationsConfig() *Config {
+	cfg := &Config{}
+	cfg.App.Name = "notes"
+	cfg.App.Version = "1.0.0"
+	cfg.Platforms.MacOS.BundleID = "com.example.notes"
+	cfg.Protocols = []ProtocolSection{{Scheme: "myapp", Name: "Notes Link"}}
+	cfg.DocumentTypes = []DocumentTypeSection{{Name: "Velo Note", Extensions: []string{"vnote"}, Icon: "assets/vnote.png"}}
+	return cfg
+}
+
+func readGenerated(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGenerateAssociations(t *testing.T) {
+	cfg := associationsConfig()
+	dir := t.TempDir()
+	if err := GenerateDarwinPlist(cfg, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateLinuxDesktop(cfg, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateWinres(cfg, dir, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	plist := readGenerated(t, dir, "Info.plist.template")
+	for _, want := range []string{
+		"<key>CFBundleURLTypes</key>",
+		"<string>com.example.notes.myapp</string>",
+		"<key>CFBundleTypeRole</key>\n      <string>Viewer</string>",
+		"<key>CFBundleURLSchemes</key>\n      <array>\n        <string>myapp</string>",
+		"<key>CFBundleDocumentTypes</key>",
+		"<key>CFBundleTypeName</key>\n      <string>Velo Note</string>\n      <key>CFBundleTypeRole</key>\n      <string>Editor</string>",
+		"<key>CFBundleTypeExtensions</key>\n      <array>\n        <string>vnote</string>",
+		"<string>application/x-vnote</string>",
+		"<key>CFBundleTypeIconFile</key>\n      <string>application-x-vnote.icns</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Fatalf("Info.plist.template missing %q:\n%s", want, plist)
+		}
+	}
+
+	desktop := readGenerated(t, dir, "app.desktop.template")
+	for _, want := range []string{"Exec=notes %U\n", "MimeType=x-scheme-handler/myapp;application/x-vnote;\n"} {
+		if !strings.Contains(desktop, want) {
+			t.Fatalf("app.desktop.template missing %q:\n%s", want, desktop)
+		}
+	}
+
+	mime := readGenerated(t, dir, "app.mime.xml")
+	for _, want := range []string{
+		`<mime-type type="application/x-vnote">`,
+		`<comment>Velo Note</comment>`,
+		`<icon name="application-x-vnote"/>`,
+		`<glob pattern="*.vnote"/>`,
+	} {
+		if !strings.Contains(mime, want) {
+			t.Fatalf("app.mime.xml missing %q:\n%s", want, mime)
+		}
+	}
+
+	reg := readGenerated(t, dir, "registry.reg.template")
+	for _, want := range []string{
+		`[HKEY_CURRENT_USER\Software\Classes\myapp]` + "\n" + `@="URL:Notes Link"` + "\n" + `"URL Protocol"=""`,
+		`[HKEY_CURRENT_USER\Software\Classes\myapp\shell\open\command]` + "\n" + `@="\"${EXE_PATH}\" \"%1\""`,
+		`[HKEY_CURRENT_USER\Software\Classes\.vnote]` + "\n" + `@="notes.vnote"` + "\n" + `"Content Type"="application/x-vnote"`,
+		`[HKEY_CURRENT_USER\Software\Classes\.vnote\OpenWithProgids]` + "\n" + `"notes.vnote"=""`,
+		`[HKEY_CURRENT_USER\Software\Classes\notes.vnote]` + "\n" + `@="Velo Note"`,
+		`[HKEY_CURRENT_USER\Software\Classes\notes.vnote\DefaultIcon]` + "\n" + `@="${EXE_PATH},-2"`,
+	} {
+		if !strings.Contains(reg, want) {
+			t.Fatalf("registry.reg.template missing %q:\n%s", want, reg)
+		}
+	}
+
+	winres := readGenerated(t, filepath.Join(dir, "winres"), "winres.json")
+	if !strings.Contains(winres, `"#2": {`) || !strings.Contains(winres, `"vnote.png"`) {
+		t.Fatalf("winres.json missing the document icon:\n%s", winres)
+	}
+}
+
+func TestGenerateWithoutAssociations(t *testing.T) {
+	cfg := associationsConfig()
+	cfg.Protocols = nil
+	cfg.DocumentTypes = nil
+	dir := t.TempDir()
+	if err := GenerateDarwinPlist(cfg, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateLinuxDesktop(cfg, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateWinres(cfg, dir, dir); err != nil {
+		t.Fatal(err)
+	}
+	if plist := readGenerated(t, dir, "Info.plist.template"); strings.Contains(plist, "CFBundleURLTypes") {
+		t.Fatalf("Info.plist.template declares URL types:\n%s", plist)
+	}
+	desktop := readGenerated(t, dir, "app.desktop.template")
+	if !strings.Contains(desktop, "Exec=notes\n") || strings.Contains(desktop, "MimeType") {
+		t.Fatalf("app.desktop.template:\n%s", desktop)
+	}
+	for _, name := range []string{"registry.reg.template", "app.mime.xml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s written without associations: %v", name, err)
+		}
+	}
+}
+
+func TestValidateAssociations(t *testing.T) {
+	cfg := associationsConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Protocols[0].Scheme = "my app"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("invalid scheme accepted")
+	}
+	cfg = associationsConfig()
+	cfg.DocumentTypes[0].Extensions = []string{".vnote"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("extension with a dot accepted")
+	}
+	cfg.DocumentTypes[0].Extensions = nil
+	if err := cfg.Validate(); err == nil || err.Error() != "document_types[0].extensions is required" {
+		t.Fatalf("document type without extensions: %v", err)
+	}
+}
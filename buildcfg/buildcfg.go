@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/ltaoo/velo/updater/types"
 )
@@ -113,8 +115,75 @@ type BuildSection struct {
 type DesktopSection struct {
 	Engine   string          `json:"engine"`
 	Electron ElectronSection `json:"electron"`
+	// Addr is the HTTP listen address used by ModeHttp and ModeBridgeHttp,
+	// e.g. "127.0.0.1:8080". Port 0 picks a random free port.
+	Addr         string              `json:"addr"`
+	FrontendLogs FrontendLogsSection `json:"frontend_logs"`
 }
 
+// FrontendLogsSection forwards the console output and uncaught errors of the
+// app's windows into the Go log.
+type FrontendLogsSection struct {
+	Enabled bool `json:"enabled"`
+	// Level is the lowest console method forwarded: "debug", "log", "info",
+	// "warn" (default) or "error". Uncaught errors are always forwarded.
+	Level string `json:"level"`
+	// SourceMaps maps the stack traces of forwarded errors back to the
+	// original sources through the .map files served with the frontend.
+	SourceMaps bool `json:"source_maps"`
+}
+
+// ProtocolSection registers a URL scheme, e.g. "myapp" for myapp:// links,
+// with the operating system.
+type ProtocolSection struct {
+	Scheme string `json:"scheme"`
+	// Name describes the scheme, e.g. "My App Link"; defaults to the
+	// display name of the app.
+	Name string `json:"name"`
+	// Role is the macOS CFBundleTypeRole: "Viewer" (default) or "Editor".
+	Role string `json:"role"`
+}
+
+// DocumentTypeSection associates files, e.g. "*.vnote", with the app.
+type DocumentTypeSection struct {
+	// Name describes the files, e.g. "Velo Note".
+	Name string `json:"name"`
+	// Extensions lists the file extensions without the leading dot.
+	Extensions []string `json:"extensions"`
+	// MimeType defaults to "application/x-" followed by the first
+	// extension.
+	MimeType string `json:"mime_type"`
+	// Icon is a PNG shown for the files, relative to the project.
+	Icon string `json:"icon"`
+	// Role is the macOS CFBundleTypeRole: "Editor" (default) or "Viewer".
+	Role string `json:"role"`
+}
+
+// MIMEType returns the MIME type of the files, applying the default.
+func (d DocumentTypeSection) MIMEType() string {
+	if d.MimeType != "" {
+		return d.MimeType
+	}
+	return "application/x-" + strings.ToLower(d.Extensions[0])
+}
+
+// IconName is the name the icon of the files is installed under.
+func (d DocumentTypeSection) IconName() string {
+	return strings.ReplaceAll(d.MIMEType(), "/", "-")
+}
+
+// ProgID returns the Windows programmatic identifier of the files of d
+// opened by appName, e.g. "notes.vnote".
+func ProgID(appName string, d DocumentTypeSection) string {
+	id := progIDPattern.ReplaceAllString(appName, "")
+	if id == "" {
+		id = "App"
+	}
+	return id + "." + strings.ToLower(d.Extensions[0])
+}
+
+var progIDPattern = regexp.MustCompile(`[^A-Za-z0-9]`)
+
 type ElectronSection struct {
 	Enabled        bool   `json:"enabled"`
 	PackageManager string `json:"package_manager"`
@@ -190,10 +259,24 @@ type Config struct {
 		Linux   LinuxSection   `json:"linux"`
 		IOS     IOSSection     `json:"ios"`
 	} `json:"platforms"`
-	Build   BuildSection   `json:"build"`
-	Desktop DesktopSection `json:"desktop"`
-	Release ReleaseSection `json:"release"`
-	Update  UpdateSection  `json:"update"`
+	Build     BuildSection      `json:"build"`
+	Desktop   DesktopSection    `json:"desktop"`
+	Protocols []ProtocolSection `json:"protocols"`
+	// DocumentTypes are the file types opened by the app.
+	DocumentTypes []DocumentTypeSection `json:"document_types"`
+	Release       ReleaseSection        `json:"release"`
+	Update        UpdateSection         `json:"update"`
+	CrashReports  CrashReportsSection   `json:"crash_reports"`
+}
+
+// CrashReportsSection configures the reports saved when the app crashes.
+type CrashReportsSection struct {
+	// Disabled turns off crash reports and the dialog shown at the launch
+	// after a crash.
+	Disabled bool `json:"disabled"`
+	// UploadURL receives the pending reports as JSON POST requests at
+	// launch.
+	UploadURL string `json:"upload_url"`
 }
 
 type IOSSection struct {
@@ -222,9 +305,68 @@ func (c *Config) Validate() error {
 	if c.App.Version == "" {
 		return fmt.Errorf("app.version is required")
 	}
+	for i, p := range c.Protocols {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("protocols[%d].%w", i, err)
+		}
+	}
+	for i, d := range c.DocumentTypes {
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("document_types[%d].%w", i, err)
+		}
+	}
+	switch c.Desktop.FrontendLogs.Level {
+	case "", "debug", "log", "info", "warn", "error":
+	default:
+		return fmt.Errorf("desktop.frontend_logs.level %q is not one of debug, log, info, warn or error", c.Desktop.FrontendLogs.Level)
+	}
+	return nil
+}
+
+// Validate checks the scheme of p.
+func (p ProtocolSection) Validate() error {
+	if !schemePattern.MatchString(p.Scheme) {
+		return fmt.Errorf("scheme %q is not a valid URL scheme", p.Scheme)
+	}
+	return nil
+}
+
+// Validate checks the extensions of d. MIMEType and ProgID must only be
+// called on a document type that passes it.
+func (d DocumentTypeSection) Validate() error {
+	if len(d.Extensions) == 0 {
+		return fmt.Errorf("extensions is required")
+	}
+	for _, ext := range d.Extensions {
+		if !extensionPattern.MatchString(ext) {
+			return fmt.Errorf("extensions: %q is not a file extension without the dot", ext)
+		}
+	}
 	return nil
 }
 
+var (
+	schemePattern    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*$`)
+	extensionPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// DocumentName returns the description of d, defaulting to the display name
+// followed by "Document".
+func (c *Config) DocumentName(d DocumentTypeSection) string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return c.DisplayName() + " Document"
+}
+
+// ProtocolName returns the description of p, defaulting to the display name.
+func (c *Config) ProtocolName(p ProtocolSection) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return c.DisplayName()
+}
+
 func (c *Config) DisplayName() string {
 	if c.App.DisplayName != "" {
 		return c.App.DisplayName
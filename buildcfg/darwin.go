@@ -39,6 +39,52 @@ var plistTmpl = template.Must(template.New("plist").Parse(`<?xml version="1.0" e
   <string>{{.Category}}</string>
   <key>NSHumanReadableCopyright</key>
   <string>{{.Copyright}}</string>
+{{- if .URLTypes}}
+  <key>CFBundleURLTypes</key>
+  <array>
+{{- range .URLTypes}}
+    <dict>
+      <key>CFBundleURLName</key>
+      <string>{{html .Name}}</string>
+      <key>CFBundleTypeRole</key>
+      <string>{{html .Role}}</string>
+      <key>CFBundleURLSchemes</key>
+      <array>
+        <string>{{.Scheme}}</string>
+      </array>
+    </dict>
+{{- end}}
+  </array>
+{{- end}}
+{{- if .DocumentTypes}}
+  <key>CFBundleDocumentTypes</key>
+  <array>
+{{- range .DocumentTypes}}
+    <dict>
+      <key>CFBundleTypeName</key>
+      <string>{{html .Name}}</string>
+      <key>CFBundleTypeRole</key>
+      <string>{{html .Role}}</string>
+      <key>LSHandlerRank</key>
+      <string>Owner</string>
+      <key>CFBundleTypeExtensions</key>
+      <array>
+{{- range .Extensions}}
+        <string>{{.}}</string>
+{{- end}}
+      </array>
+      <key>CFBundleTypeMIMETypes</key>
+      <array>
+        <string>{{html .MimeType}}</string>
+      </array>
+{{- if .IconFile}}
+      <key>CFBundleTypeIconFile</key>
+      <string>{{.IconFile}}</string>
+{{- end}}
+    </dict>
+{{- end}}
+  </array>
+{{- end}}
 </dict>
 </plist>
 `))
@@ -60,6 +106,22 @@ type plistData struct {
 	MinSystemVersion string
 	Category         string
 	Copyright        string
+	URLTypes         []urlTypeData
+	DocumentTypes    []documentTypeData
+}
+
+type urlTypeData struct {
+	Name   string
+	Role   string
+	Scheme string
+}
+
+type documentTypeData struct {
+	Name       string
+	Role       string
+	Extensions []string
+	MimeType   string
+	IconFile   string
 }
 
 type entitlementEntry struct {
@@ -94,6 +156,32 @@ func GenerateDarwinPlist(cfg *Config, outDir string) error {
 		Category:         category,
 		Copyright:        fmt.Sprintf("Copyright © 2024 %s", cfg.App.Author),
 	}
+	for _, p := range cfg.Protocols {
+		role := p.Role
+		if role == "" {
+			role = "Viewer"
+		}
+		data.URLTypes = append(data.URLTypes, urlTypeData{
+			Name:   bundleID + "." + p.Scheme,
+			Role:   role,
+			Scheme: p.Scheme,
+		})
+	}
+	for _, d := range cfg.DocumentTypes {
+		doc := documentTypeData{
+			Name:       cfg.DocumentName(d),
+			Role:       d.Role,
+			Extensions: d.Extensions,
+			MimeType:   d.MIMEType(),
+		}
+		if doc.Role == "" {
+			doc.Role = "Editor"
+		}
+		if d.Icon != "" {
+			doc.IconFile = d.IconName() + ".icns"
+		}
+		data.DocumentTypes = append(data.DocumentTypes, doc)
+	}
 
 	f, err := os.Create(filepath.Join(outDir, "Info.plist.template"))
 	if err != nil {
@@ -44,12 +44,9 @@ func GenerateIcons(cfg *Config, baseDir, outDir string) error {
 	}
 
 	// Generate PNG icons at various sizes
-	sizes := []int{16, 32, 48, 64, 128, 256, 512, 1024}
-	resized := make(map[int]image.Image)
-	for _, size := range sizes {
-		img := resize.Resize(uint(size), uint(size), src, resize.Lanczos3)
-		resized[size] = img
-		if err := savePNG(filepath.Join(iconsDir, fmt.Sprintf("icon_%d.png", size)), img); err != nil {
+	resized := resizeIcon(src)
+	for _, size := range iconSizes {
+		if err := savePNG(filepath.Join(iconsDir, fmt.Sprintf("icon_%d.png", size)), resized[size]); err != nil {
 			return err
 		}
 	}
@@ -65,7 +62,7 @@ func GenerateIcons(cfg *Config, baseDir, outDir string) error {
 	}
 
 	// macOS iconset + icns (only works on macOS with iconutil)
-	if err := generateIconset(src, resized, iconsDir); err != nil {
+	if err := generateIconset(src, resized, iconsDir, "AppIcon"); err != nil {
 		fmt.Fprintf(os.Stderr, "warning: icns generation skipped: %v\n", err)
 	}
 
@@ -84,9 +81,56 @@ func GenerateIcons(cfg *Config, baseDir, outDir string) error {
 		}
 	}
 
+	// Document icons: <name>.icns for macOS and a 256px <name>.png for the
+	// Linux icon theme; Windows embeds the PNG through winres.
+	for _, d := range cfg.DocumentTypes {
+		if d.Icon == "" {
+			continue
+		}
+		docSrc := d.Icon
+		if !filepath.IsAbs(docSrc) {
+			docSrc = filepath.Join(baseDir, docSrc)
+		}
+		img, err := decodePNG(docSrc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: document icon skipped: %v\n", err)
+			continue
+		}
+		docResized := resizeIcon(img)
+		if err := savePNG(filepath.Join(iconsDir, d.IconName()+".png"), docResized[256]); err != nil {
+			return err
+		}
+		if err := generateIconset(img, docResized, iconsDir, d.IconName()); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: icns generation skipped: %v\n", err)
+		}
+	}
+
 	return nil
 }
 
+var iconSizes = []int{16, 32, 48, 64, 128, 256, 512, 1024}
+
+func resizeIcon(src image.Image) map[int]image.Image {
+	resized := make(map[int]image.Image)
+	for _, size := range iconSizes {
+		resized[size] = resize.Resize(uint(size), uint(size), src, resize.Lanczos3)
+	}
+	return resized
+}
+
+func decodePNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return img, nil
+}
+
 func savePNG(path string, img image.Image) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -96,8 +140,8 @@ func savePNG(path string, img image.Image) error {
 	return png.Encode(f, img)
 }
 
-func generateIconset(src image.Image, resized map[int]image.Image, outDir string) error {
-	iconsetDir := filepath.Join(outDir, "AppIcon.iconset")
+func generateIconset(src image.Image, resized map[int]image.Image, outDir, name string) error {
+	iconsetDir := filepath.Join(outDir, name+".iconset")
 	if err := os.MkdirAll(iconsetDir, 0755); err != nil {
 		return err
 	}
@@ -123,7 +167,7 @@ func generateIconset(src image.Image, resized map[int]image.Image, outDir string
 	}
 
 	// Try iconutil (macOS only)
-	icnsPath := filepath.Join(outDir, "AppIcon.icns")
+	icnsPath := filepath.Join(outDir, name+".icns")
 	cmd := exec.Command("iconutil", "-c", "icns", iconsetDir, "-o", icnsPath)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("iconutil not available (macOS only): %w", err)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 )
 
@@ -14,12 +15,31 @@ Name={{.Name}}
 Name[zh_CN]={{.Name}}
 Comment={{.Description}}
 Comment[zh_CN]={{.Description}}
-Exec={{.Name}}
+Exec={{.Name}}{{if .Files}} %U{{else if .MimeTypes}} %u{{end}}
 Icon={{.Icon}}
 Terminal=false
 Categories={{.Categories}}
 Keywords={{.Keywords}}
 StartupNotify=true
+{{- if .MimeTypes}}
+MimeType={{range .MimeTypes}}{{.}};{{end}}
+{{- end}}
+`))
+
+var mimeInfoTmpl = template.Must(template.New("mime").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<mime-info xmlns="http://www.freedesktop.org/standards/shared-mime-info">
+{{- range .}}
+  <mime-type type="{{html .MimeType}}">
+    <comment>{{html .Name}}</comment>
+{{- if .IconFile}}
+    <icon name="{{.IconFile}}"/>
+{{- end}}
+{{- range .Extensions}}
+    <glob pattern="*.{{.}}"/>
+{{- end}}
+  </mime-type>
+{{- end}}
+</mime-info>
 `))
 
 type desktopData struct {
@@ -28,6 +48,8 @@ type desktopData struct {
 	Icon        string
 	Categories  string
 	Keywords    string
+	MimeTypes   []string
+	Files       bool
 }
 
 func GenerateLinuxDesktop(cfg *Config, outDir string) error {
@@ -49,6 +71,28 @@ func GenerateLinuxDesktop(cfg *Config, outDir string) error {
 		Categories:  cfg.Platforms.Linux.DesktopEntry.Categories,
 		Keywords:    cfg.Platforms.Linux.DesktopEntry.Keywords,
 	}
+	for _, p := range cfg.Protocols {
+		data.MimeTypes = append(data.MimeTypes, "x-scheme-handler/"+strings.ToLower(p.Scheme))
+	}
+	var docs []documentTypeData
+	for _, d := range cfg.DocumentTypes {
+		data.MimeTypes = append(data.MimeTypes, d.MIMEType())
+		data.Files = true
+		doc := documentTypeData{
+			Name:       cfg.DocumentName(d),
+			Extensions: d.Extensions,
+			MimeType:   d.MIMEType(),
+		}
+		if d.Icon != "" {
+			doc.IconFile = d.IconName()
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) > 0 {
+		if err := generateMimeInfo(docs, outDir); err != nil {
+			return err
+		}
+	}
 
 	f, err := os.Create(filepath.Join(outDir, "app.desktop.template"))
 	if err != nil {
@@ -58,3 +102,16 @@ func GenerateLinuxDesktop(cfg *Config, outDir string) error {
 
 	return desktopTmpl.Execute(f, data)
 }
+
+// generateMimeInfo writes app.mime.xml, the shared-mime-info package that
+// defines the document types; it is installed into
+// /usr/share/mime/packages alongside the desktop entry.
+func generateMimeInfo(docs []documentTypeData, outDir string) error {
+	f, err := os.Create(filepath.Join(outDir, "app.mime.xml"))
+	if err != nil {
+		return fmt.Errorf("creating app.mime.xml: %w", err)
+	}
+	defer f.Close()
+
+	return mimeInfoTmpl.Execute(f, docs)
+}
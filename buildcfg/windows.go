@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/template"
 )
 
 type winresJSON struct {
@@ -67,6 +69,14 @@ func GenerateWinres(cfg *Config, baseDir, outDir string) error {
 		},
 	}
 
+	for i, d := range cfg.DocumentTypes {
+		if d.Icon != "" {
+			data.RTGroupIcon[fmt.Sprintf("#%d", FirstDocumentIconID+i)] = map[string]interface{}{
+				"0000": []string{filepath.Base(d.Icon)},
+			}
+		}
+	}
+
 	out, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling winres.json: %w", err)
@@ -76,7 +86,11 @@ func GenerateWinres(cfg *Config, baseDir, outDir string) error {
 	}
 
 	// Copy icon files to winres directory
-	for _, iconSrc := range []string{cfg.Platforms.Windows.IconFiles.PNG, cfg.Platforms.Windows.IconFiles.PNG16} {
+	iconFiles := []string{cfg.Platforms.Windows.IconFiles.PNG, cfg.Platforms.Windows.IconFiles.PNG16}
+	for _, d := range cfg.DocumentTypes {
+		iconFiles = append(iconFiles, d.Icon)
+	}
+	for _, iconSrc := range iconFiles {
 		if iconSrc == "" {
 			continue
 		}
@@ -95,5 +109,98 @@ func GenerateWinres(cfg *Config, baseDir, outDir string) error {
 		}
 	}
 
-	return nil
+	return generateRegistry(cfg, outDir)
+}
+
+// FirstDocumentIconID is the resource ID of the icon of the first entry in
+// Config.DocumentTypes; the icon of entry i has ID FirstDocumentIconID+i.
+const FirstDocumentIconID = 2
+
+var registryTmpl = template.Must(template.New("registry").Parse(`REGEDIT4
+{{range .Protocols}}
+[HKEY_CURRENT_USER\Software\Classes\{{.Scheme}}]
+@="URL:{{.Name}}"
+"URL Protocol"=""
+
+[HKEY_CURRENT_USER\Software\Classes\{{.Scheme}}\DefaultIcon]
+@="${EXE_PATH},0"
+
+[HKEY_CURRENT_USER\Software\Classes\{{.Scheme}}\shell\open\command]
+@="\"${EXE_PATH}\" \"%1\""
+{{end}}
+{{- range .Documents}}
+{{- $doc := .}}
+{{- range .Extensions}}
+[HKEY_CURRENT_USER\Software\Classes\.{{.}}]
+@="{{$doc.ProgID}}"
+"Content Type"="{{$doc.MimeType}}"
+
+[HKEY_CURRENT_USER\Software\Classes\.{{.}}\OpenWithProgids]
+"{{$doc.ProgID}}"=""
+{{end}}
+[HKEY_CURRENT_USER\Software\Classes\{{.ProgID}}]
+@="{{.Name}}"
+
+[HKEY_CURRENT_USER\Software\Classes\{{.ProgID}}\DefaultIcon]
+@="${EXE_PATH},{{.Icon}}"
+
+[HKEY_CURRENT_USER\Software\Classes\{{.ProgID}}\shell\open\command]
+@="\"${EXE_PATH}\" \"%1\""
+{{end}}`))
+
+type registryData struct {
+	Protocols []urlTypeData
+	Documents []progIDData
+}
+
+type progIDData struct {
+	ProgID     string
+	Name       string
+	MimeType   string
+	Extensions []string
+	// Icon is the index of the icon in the executable: 0 for the app icon,
+	// a negated resource ID for a document icon.
+	Icon int
+}
+
+// generateRegistry writes registry.reg.template, the per-user registry
+// entries of the URL schemes and document types of cfg, for installers.
+// ${EXE_PATH} stands for the installed executable with its backslashes
+// escaped. Nothing is written when neither is configured; the app also
+// registers them itself on startup.
+func generateRegistry(cfg *Config, outDir string) error {
+	if len(cfg.Protocols) == 0 && len(cfg.DocumentTypes) == 0 {
+		return nil
+	}
+
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+	var data registryData
+	for _, p := range cfg.Protocols {
+		data.Protocols = append(data.Protocols, urlTypeData{
+			Name:   quote(cfg.ProtocolName(p)),
+			Scheme: strings.ToLower(p.Scheme),
+		})
+	}
+	for i, d := range cfg.DocumentTypes {
+		doc := progIDData{
+			ProgID:   ProgID(cfg.App.Name, d),
+			Name:     quote(cfg.DocumentName(d)),
+			MimeType: quote(d.MIMEType()),
+		}
+		for _, ext := range d.Extensions {
+			doc.Extensions = append(doc.Extensions, strings.ToLower(ext))
+		}
+		if d.Icon != "" {
+			doc.Icon = -(FirstDocumentIconID + i)
+		}
+		data.Documents = append(data.Documents, doc)
+	}
+
+	f, err := os.Create(filepath.Join(outDir, "registry.reg.template"))
+	if err != nil {
+		return fmt.Errorf("creating registry.reg.template: %w", err)
+	}
+	defer f.Close()
+
+	return registryTmpl.Execute(f, data)
 }
@@ -0,0 +1,56 @@
+package velo
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ltaoo/velo/webview"
+)
+
+const veloCallReplyMethod = "/__velo/call/reply"
+
+// relayCall forwards Webview.Call requests to WebSocket clients, which the
+// native bridge does not reach.
+func (b *Box) relayCall(payload []byte) bool {
+	return b.wsHub != nil && b.wsHub.BroadcastMessage(json.RawMessage(payload))
+}
+
+// windowHandle returns the handle of window name, with WebSocket clients
+// as a relay for Webview.Call.
+func (b *Box) windowHandle(name string) *webview.Webview {
+	return webview.NewHandle(name, b.webviewEngine).WithMessageRelay(b.relayCall)
+}
+
+// callerWindow returns the window a native bridge call came from, empty
+// for HTTP and WebSocket calls.
+func callerWindow(ctx context.Context) string {
+	if name, ok := strings.CutPrefix(callerFrom(ctx), windowCaller); ok {
+		return name
+	}
+	return ""
+}
+
+func (b *Box) registerCallRoutes() {
+	b.Post(veloCallReplyMethod, func(c *BoxContext) interface{} {
+		var body struct {
+			ID     string          `json:"id"`
+			Window string          `json:"window"`
+			Result json.RawMessage `json:"result"`
+			Error  string          `json:"error"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			return ErrBadRequest.Wrap(err)
+		}
+		// A native window cannot claim to be another one; pages on a
+		// WebSocket name their window themselves.
+		window := body.Window
+		if name := callerWindow(c.Context()); name != "" {
+			window = name
+		}
+		if !webview.ResolveCall(body.ID, window, body.Result, body.Error) {
+			return ErrNotFound.WithMessage("no call %q waiting for window %q", body.ID, window)
+		}
+		return c.Ok(nil)
+	})
+}
@@ -0,0 +1,171 @@
+package velo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ltaoo/velo/webview"
+)
+
+// answerTestCall reads a Webview.Call request from client and replies with
+// reply merged into the callback arguments.
+func answerTestCall(t *testing.T, client *testWSClient, reply H) {
+	t.Helper()
+	_, _, payload, err := readWSFrame(client.reader)
+	if err != nil {
+		t.Errorf("read call request: %v", err)
+		return
+	}
+	var frame struct {
+		Payload struct {
+			Type   string        `json:"type"`
+			ID     string        `json:"id"`
+			Fn     string        `json:"fn"`
+			Args   []interface{} `json:"args"`
+			Window string        `json:"window"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(payload, &frame); err != nil || frame.Payload.Type != "__velo_call" {
+		t.Errorf("call request = %s (%v)", payload, err)
+		return
+	}
+	if frame.Payload.Fn != "editor.isDirty" || frame.Payload.Window != "main" || len(frame.Payload.Args) != 1 {
+		t.Errorf("call request = %+v", frame.Payload)
+	}
+	reply["id"] = frame.Payload.ID
+	reply["window"] = frame.Payload.Window
+	msg, _ := json.Marshal(H{"id": "r-" + frame.Payload.ID, "method": veloCallReplyMethod, "httpMethod": "POST", "args": reply})
+	client.writeText(t, msg)
+	// Skip the invoke callback for the reply itself.
+	readWSFrame(client.reader)
+}
+
+func TestWebviewCallOverWebSocket(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	server := newTestServer(t, app)
+	client := dialTestWS(t, server.URL)
+	defer client.close()
+	wv := app.windowHandle("main")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	answered := make(chan struct{})
+	go func() {
+		answerTestCall(t, client, H{"result": H{"dirty": true}})
+		close(answered)
+	}()
+	result, err := wv.Call(ctx, "editor.isDirty", "doc-1")
+	<-answered
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	var got struct {
+		Dirty bool `json:"dirty"`
+	}
+	if err := json.Unmarshal(result, &got); err != nil || !got.Dirty {
+		t.Fatalf("result = %s (%v)", result, err)
+	}
+
+	answered = make(chan struct{})
+	go func() {
+		answerTestCall(t, client, H{"error": "editor not ready"})
+		close(answered)
+	}()
+	_, err = wv.Call(ctx, "editor.isDirty", "doc-1")
+	<-answered
+	var callErr *webview.CallError
+	if !errors.As(err, &callErr) || callErr.Message != "editor not ready" {
+		t.Fatalf("Call error = %v, want a CallError", err)
+	}
+}
+
+func TestWebviewCallTimesOut(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	server := newTestServer(t, app)
+	client := dialTestWS(t, server.URL)
+	defer client.close()
+	wv := app.windowHandle("main")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := wv.Call(ctx, "editor.isDirty"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call error = %v, want deadline exceeded", err)
+	}
+
+	client.close()
+	app.wsHub.closeAll()
+	if _, err := wv.Call(context.Background(), "editor.isDirty"); !errors.Is(err, webview.ErrNoPage) {
+		t.Fatalf("Call without pages = %v, want ErrNoPage", err)
+	}
+}
+
+func TestCallReplyFromAnotherWindowIsRejected(t *testing.T) {
+	h := webview.Headless()
+	h.Reset()
+	t.Cleanup(h.Reset)
+
+	app := NewApp(&VeloAppOpt{Mode: ModeBridge, WebviewEngine: webview.EngineHeadless})
+	main := app.NewWebview(&VeloWebviewOpt{Name: "main"})
+	done := make(chan struct{})
+	go func() {
+		app.Run()
+		close(done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := h.WaitWindow(ctx, "main"); err != nil {
+		t.Fatal(err)
+	}
+	app.OpenWindow(&VeloWebviewOpt{Name: "editor"})
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := main.Call(ctx, "editor.isDirty")
+		result <- err
+	}()
+	var id string
+	for id == "" {
+		for _, msg := range h.TakeMessages() {
+			var req struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			}
+			if json.Unmarshal([]byte(msg), &req) == nil && req.Type == "__velo_call" {
+				id = req.ID
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	reply := func(window string) BoxResult {
+		args, _ := json.Marshal(H{"id": id, "window": "main", "result": true})
+		_, raw, err := h.PostMessage(window, `{"id":"r","method":"`+veloCallReplyMethod+`","httpMethod":"POST","args":`+string(args)+`}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res BoxResult
+		json.Unmarshal([]byte(raw), &res)
+		return res
+	}
+
+	// The editor page claims to be main, but the bridge knows better.
+	if res := reply("editor"); res.Code != CodeNotFound {
+		t.Fatalf("reply from editor = %+v, want not found", res)
+	}
+	select {
+	case err := <-result:
+		t.Fatalf("call resolved by another window: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if res := reply("main"); res.Code != CodeOK {
+		t.Fatalf("reply from main = %+v", res)
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+
+	app.Quit(ctx)
+	<-done
+}
@@ -0,0 +1,104 @@
+package velo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// veloCancelMethod is the bridge method the JS runtime invokes to abort an
+// in-flight call. Its args carry the id of the call to cancel.
+const veloCancelMethod = "__velo/cancel"
+
+// inflightCalls tracks the cancel functions of running bridge and WebSocket
+// invocations, keyed by caller and invoke id, so that a page can only
+// cancel its own calls.
+type inflightCalls struct {
+	mu      sync.Mutex
+	cancels map[inflightKey]context.CancelFunc
+}
+
+type inflightKey struct {
+	caller string
+	id     string
+}
+
+func newInflightCalls() *inflightCalls {
+	return &inflightCalls{cancels: make(map[inflightKey]context.CancelFunc)}
+}
+
+type callerKey struct{}
+
+// windowCaller prefixes the caller of the calls made by a native window.
+const windowCaller = "window:"
+
+// withCaller marks the invocations made under ctx as coming from caller,
+// e.g. a window or a WebSocket client.
+func withCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func callerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// begin derives the handler context for invocation id of the caller set on
+// parent. A positive timeout (in milliseconds) becomes the context deadline.
+// The returned function must be called when the handler returns.
+func (f *inflightCalls) begin(parent context.Context, id string, timeout int64) (context.Context, func()) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, time.Duration(timeout)*time.Millisecond)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	if id == "" {
+		return ctx, cancel
+	}
+	key := inflightKey{caller: callerFrom(parent), id: id}
+	f.mu.Lock()
+	f.cancels[key] = cancel
+	f.mu.Unlock()
+	return ctx, func() {
+		f.mu.Lock()
+		delete(f.cancels, key)
+		f.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel aborts invocation id of caller. It reports whether the call was
+// still running.
+func (f *inflightCalls) cancel(caller, id string) bool {
+	key := inflightKey{caller: caller, id: id}
+	f.mu.Lock()
+	cancel, ok := f.cancels[key]
+	delete(f.cancels, key)
+	f.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// cancelAll aborts every running invocation.
+func (f *inflightCalls) cancelAll() {
+	f.mu.Lock()
+	cancels := f.cancels
+	f.cancels = make(map[inflightKey]context.CancelFunc)
+	f.mu.Unlock()
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+func cancelTarget(args interface{}) string {
+	values, ok := args.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := values["id"].(string)
+	return id
+}
@@ -0,0 +1,113 @@
+package velo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newBlockingApp(started chan<- struct{}, stopped chan<- error) *Box {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Get("/api/scan", func(c *BoxContext) interface{} {
+		started <- struct{}{}
+		select {
+		case <-c.Done():
+			stopped <- c.Err()
+			return c.Fail(c.Err())
+		case <-time.After(5 * time.Second):
+			stopped <- nil
+			return c.Ok(nil)
+		}
+	})
+	return app
+}
+
+func TestCancelPacketCancelsBridgeCall(t *testing.T) {
+	started := make(chan struct{}, 1)
+	stopped := make(chan error, 1)
+	app := newBlockingApp(started, stopped)
+
+	go app.handleMessage(`{"id":"scan-1","method":"/api/scan"}`)
+	<-started
+
+	id, result := app.handleMessage(`{"id":"","method":"__velo/cancel","args":{"id":"scan-1"}}`)
+	if id != "" || result != "" {
+		t.Fatalf("cancel packet produced a callback: %q %q", id, result)
+	}
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("handler err = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not cancelled")
+	}
+}
+
+func TestCancelIsScopedToTheCallingWindow(t *testing.T) {
+	started := make(chan struct{}, 1)
+	stopped := make(chan error, 1)
+	app := newBlockingApp(started, stopped)
+	main, other := app.windowMessageHandler("main"), app.windowMessageHandler("other")
+
+	go main(`{"id":"scan-4","method":"/api/scan"}`)
+	<-started
+
+	other(`{"id":"","method":"__velo/cancel","args":{"id":"scan-4"}}`)
+	app.handleMessage(`{"id":"","method":"__velo/cancel","args":{"id":"scan-4"}}`)
+	select {
+	case err := <-stopped:
+		t.Fatalf("another caller cancelled the call: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	main(`{"id":"","method":"__velo/cancel","args":{"id":"scan-4"}}`)
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("handler err = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the calling window could not cancel its call")
+	}
+}
+
+func TestTimeoutSetsHandlerDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	stopped := make(chan error, 1)
+	app := newBlockingApp(started, stopped)
+
+	go app.handleMessage(`{"id":"scan-2","method":"/api/scan","timeout":20}`)
+	<-started
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("handler err = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler deadline did not expire")
+	}
+}
+
+func TestWebSocketDisconnectCancelsInflightCalls(t *testing.T) {
+	started := make(chan struct{}, 1)
+	stopped := make(chan error, 1)
+	app := newBlockingApp(started, stopped)
+
+	server := newTestServer(t, app)
+
+	client := dialTestWS(t, server.URL)
+	client.writeText(t, []byte(`{"id":"scan-3","method":"/api/scan"}`))
+	<-started
+	client.close()
+
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("handler err = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not cancelled on disconnect")
+	}
+}
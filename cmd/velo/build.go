@@ -127,6 +127,18 @@ var buildTargets = map[string][]target{
 	"linux":   {{goos: "linux", goarch: "amd64", cgo: "0"}, {goos: "linux", goarch: "arm64", cgo: "0"}},
 }
 
+// webkitGTKTag is the build tag of the WebKitGTK webview backend on Linux.
+const webkitGTKTag = "webkit2gtk"
+
+// hasWebkitGTK reports whether the WebKitGTK development files needed by the
+// native Linux webview are installed on this machine.
+func hasWebkitGTK() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return exec.Command("pkg-config", "--exists", "gtk+-3.0", "webkit2gtk-4.1").Run() == nil
+}
+
 func runBuild(projectPath, platform, outDir, versionOverride string) error {
 	projectPath, err := filepath.Abs(projectPath)
 	if err != nil {
@@ -251,13 +263,26 @@ func runBuild(projectPath, platform, outDir, versionOverride string) error {
 				ldflags += " -H windowsgui"
 			}
 
+			args := []string{"build", "-o", outputPath, "-ldflags", ldflags}
+			cgo := t.cgo
+			if t.goos == "linux" {
+				// The WebKitGTK webview needs cgo, so it is only built for
+				// the arch of a Linux host that has its development files.
+				if t.goarch == runtime.GOARCH && hasWebkitGTK() {
+					args = append(args, "-tags", webkitGTKTag)
+					cgo = "1"
+				} else {
+					fmt.Printf("  note: %s/%s is built without the native webview; run it with the electron engine or in a browser\n", t.goos, t.goarch)
+				}
+			}
+
 			fmt.Printf("building %s/%s...\n", t.goos, t.goarch)
-			cmd := exec.Command("go", "build", "-o", outputPath, "-ldflags", ldflags, ".")
+			cmd := exec.Command("go", append(args, ".")...)
 			cmd.Dir = projectPath
 			cmd.Env = append(os.Environ(),
 				"GOOS="+t.goos,
 				"GOARCH="+t.goarch,
-				"CGO_ENABLED="+t.cgo,
+				"CGO_ENABLED="+cgo,
 			)
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
@@ -343,6 +368,15 @@ func createDarwinApp(cfg *buildcfg.Config, buildDir, distDir, binaryPath, arch,
 	if data, err := os.ReadFile(icnsPath); err == nil {
 		os.WriteFile(filepath.Join(resourcesDir, "AppIcon.icns"), data, 0644)
 	}
+	for _, d := range cfg.DocumentTypes {
+		if d.Icon == "" {
+			continue
+		}
+		name := d.IconName() + ".icns"
+		if data, err := os.ReadFile(filepath.Join(buildDir, "icons", name)); err == nil {
+			os.WriteFile(filepath.Join(resourcesDir, name), data, 0644)
+		}
+	}
 
 	fmt.Printf("  ✓ %s\n", filepath.Base(appDir))
 	return nil
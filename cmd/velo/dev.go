@@ -83,7 +83,11 @@ func runDev(dir string, engine string) error {
 		fmt.Printf("\033[2J\033[H\033[1;%dr\033[H", height-2)
 		drawFooter(width, height)
 
-		buildCmd := exec.Command("go", "build", "-ldflags", "-X github.com/ltaoo/velo.devMode=1", "-o", binPath, ".")
+		args := []string{"build", "-ldflags", "-X github.com/ltaoo/velo.devMode=1", "-o", binPath}
+		if hasWebkitGTK() {
+			args = append(args, "-tags", webkitGTKTag)
+		}
+		buildCmd := exec.Command("go", append(args, ".")...)
 		buildCmd.Dir = dir
 		crlf := crlfWriter{os.Stdout}
 		buildCmd.Stdout = crlf
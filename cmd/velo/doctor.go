@@ -219,10 +219,21 @@ func checkLinuxDeps() CheckResult {
 		}
 	}
 
+	// Check for webkit2gtk-4.1, needed by the native webview
+	cmd = exec.Command("pkg-config", "--exists", "webkit2gtk-4.1")
+	if err := cmd.Run(); err != nil {
+		return CheckResult{
+			Name:    "Linux Dependencies",
+			Passed:  false,
+			Message: "webkit2gtk-4.1 not found",
+			Details: "Install libwebkit2gtk-4.1-dev or equivalent for the native webview",
+		}
+	}
+
 	return CheckResult{
 		Name:    "Linux Dependencies",
 		Passed:  true,
-		Message: "GTK 3 development headers found",
+		Message: "GTK 3 and WebKitGTK development headers found",
 	}
 }
 
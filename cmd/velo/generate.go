@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// runGenerate runs the app in dir with VELO_BINDINGS_DIR set, which makes
+// Box.Run write the TypeScript bindings of the registered routes to outDir
+// instead of starting the app.
+func runGenerate(dir, outDir string) error {
+	outDir, err := projectPath(dir, outDir)
+	if err != nil {
+		return err
+	}
+	names := []string{"velo.d.ts", "client.ts"}
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(outDir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := runAppWithEnv(dir, "VELO_BINDINGS_DIR="+outDir); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := reportGenerated(filepath.Join(outDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runOpenAPI runs the app in dir with VELO_OPENAPI_FILE set, which makes
+// Box.Run write the OpenAPI document of the registered routes to outFile.
+func runOpenAPI(dir, outFile string) error {
+	outFile, err := projectPath(dir, outFile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(outFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := runAppWithEnv(dir, "VELO_OPENAPI_FILE="+outFile); err != nil {
+		return err
+	}
+	return reportGenerated(outFile)
+}
+
+// projectPath resolves path relative to the project directory.
+func projectPath(dir, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Abs(path)
+}
+
+func runAppWithEnv(dir string, env ...string) error {
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+		return fmt.Errorf("main.go not found in %s", dir)
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run app: %w", err)
+	}
+	return nil
+}
+
+func reportGenerated(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s was not written; does main call Box.Run?", path)
+	}
+	fmt.Printf("  ✓ %s\n", path)
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// An app that exits before Box.Run, e.g. a second instance that forwarded
+// its launch, must not leave the bindings of an earlier run looking fresh.
+func TestGenerateFailsWhenAppExitsBeforeRun(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.23\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outDir := filepath.Join(dir, "frontend", "src", "velo")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"velo.d.ts", "client.ts"} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte("// stale"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := runGenerate(dir, outDir)
+	if err == nil || !strings.Contains(err.Error(), "was not written") {
+		t.Fatalf("runGenerate() error = %v, want a not-written error", err)
+	}
+	for _, name := range []string{"velo.d.ts", "client.ts"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); !os.IsNotExist(err) {
+			t.Fatalf("stale %s kept: %v", name, err)
+		}
+	}
+}
@@ -11,7 +11,7 @@ var version = "dev"
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Fprintln(os.Stderr, "usage: velo <command> [options]")
-		fmt.Fprintln(os.Stderr, "commands: build, dev, doctor, version")
+		fmt.Fprintln(os.Stderr, "commands: build, dev, doctor, generate, openapi, version")
 		os.Exit(1)
 	}
 
@@ -51,6 +51,30 @@ func main() {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
+	case "generate":
+		fs := flag.NewFlagSet("generate", flag.ExitOnError)
+		outDir := fs.String("out", "frontend/src/velo", "output directory for velo.d.ts and client.ts, relative to the project")
+		fs.Parse(os.Args[2:])
+		dir := "."
+		if fs.NArg() > 0 {
+			dir = fs.Arg(0)
+		}
+		if err := runGenerate(dir, *outDir); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	case "openapi":
+		fs := flag.NewFlagSet("openapi", flag.ExitOnError)
+		outFile := fs.String("out", "openapi.json", "output file, relative to the project")
+		fs.Parse(os.Args[2:])
+		dir := "."
+		if fs.NArg() > 0 {
+			dir = fs.Arg(0)
+		}
+		if err := runOpenAPI(dir, *outFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
 		os.Exit(1)
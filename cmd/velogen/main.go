@@ -75,6 +75,9 @@ func main() {
 		os.Exit(1)
 	}
 	fmt.Println("  ✓ winres/winres.json")
+	if len(cfg.Protocols) > 0 || len(cfg.DocumentTypes) > 0 {
+		fmt.Println("  ✓ registry.reg.template")
+	}
 
 	if err := buildcfg.GenerateDarwinPlist(cfg, *outDir); err != nil {
 		fmt.Fprintf(os.Stderr, "darwin: %v\n", err)
@@ -88,6 +91,9 @@ func main() {
 		os.Exit(1)
 	}
 	fmt.Println("  ✓ app.desktop.template")
+	if len(cfg.DocumentTypes) > 0 {
+		fmt.Println("  ✓ app.mime.xml")
+	}
 
 	fmt.Println("done!")
 }
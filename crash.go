@@ -0,0 +1,363 @@
+package velo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ltaoo/velo/dir"
+	velerror "github.com/ltaoo/velo/error"
+)
+
+const (
+	crashDirName = "crashes"
+	// crashOutputExt ends the name of the crash output of each launch,
+	// "crash-<pid>.out".
+	crashOutputExt = ".out"
+	// maxCrashReports is the number of reports kept until they are uploaded.
+	maxCrashReports = 10
+	// crashLogLines is the number of log lines kept in a report.
+	crashLogLines = 50
+)
+
+// showErrorDialog tells the user about a crash of the previous launch.
+var showErrorDialog = velerror.ShowErrorDialog
+
+// CrashReport describes a panic that was not recovered and ended the app.
+type CrashReport struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// App and Version are the name and velo.json version of the app.
+	App       string `json:"app"`
+	Version   string `json:"version"`
+	Velo      string `json:"velo"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	GoVersion string `json:"go_version"`
+	// Panic is the first line of the crash, e.g. "panic: assignment to
+	// entry in nil map".
+	Panic string `json:"panic"`
+	// Stack holds the goroutine traces printed by the runtime.
+	Stack string `json:"stack"`
+	// Log holds the last lines of the app log before the crash.
+	Log []string `json:"log,omitempty"`
+
+	path string
+}
+
+// CrashUploader sends a crash report, e.g. to an error tracking service. A
+// report is deleted once Upload returns nil.
+type CrashUploader interface {
+	Upload(ctx context.Context, report *CrashReport) error
+}
+
+// CrashUploaderFunc adapts a function to CrashUploader.
+type CrashUploaderFunc func(ctx context.Context, report *CrashReport) error
+
+func (f CrashUploaderFunc) Upload(ctx context.Context, report *CrashReport) error {
+	return f(ctx, report)
+}
+
+// HTTPCrashUploader returns an uploader that posts each report as JSON to
+// url and expects a 2xx response.
+func HTTPCrashUploader(url string) CrashUploader {
+	return CrashUploaderFunc(func(ctx context.Context, report *CrashReport) error {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("upload crash report: %s", resp.Status)
+		}
+		return nil
+	})
+}
+
+// crashHeader is written at the start of the crash output of each launch;
+// the runtime appends the trace of a fatal panic after it.
+type crashHeader struct {
+	App       string    `json:"app"`
+	Version   string    `json:"version"`
+	Velo      string    `json:"velo"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	GoVersion string    `json:"go_version"`
+	PID       int       `json:"pid"`
+	Started   time.Time `json:"started"`
+}
+
+type crashReporter struct {
+	dir      string
+	logFile  string
+	uploader CrashUploader
+}
+
+// useCrashReports sends the fatal panics of this launch to a crash output
+// of its own. The outputs of earlier launches are turned into reports by
+// Run, so that a crash is not consumed by a NewApp that never shows it,
+// e.g. in velo generate or a test.
+func (b *Box) useCrashReports(o *VeloAppOpt) {
+	cfg := b.appConfig.CrashReports
+	if o.DisableCrashReports || cfg.Disabled {
+		return
+	}
+	r := &crashReporter{
+		dir:      filepath.Join(dir.New(b.appName).Data(), crashDirName),
+		uploader: o.CrashUploader,
+	}
+	if o.Logger == nil {
+		r.logFile = dir.New(b.appName).LogFile()
+	}
+	if r.uploader == nil && cfg.UploadURL != "" {
+		r.uploader = HTTPCrashUploader(cfg.UploadURL)
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		b.Log("crash").Error("creating crash report dir failed", "error", err)
+		return
+	}
+	if err := r.capture(crashHeader{
+		App:       b.appName,
+		Version:   b.appConfig.App.Version,
+		Velo:      Version,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		PID:       os.Getpid(),
+		Started:   time.Now(),
+	}); err != nil {
+		b.Log("crash").Error("capturing crash output failed", "error", err)
+	}
+	b.crashes = r
+}
+
+// outputPath returns the crash output of the launch with process id pid.
+func (r *crashReporter) outputPath(pid int) string {
+	return filepath.Join(r.dir, "crash-"+strconv.Itoa(pid)+crashOutputExt)
+}
+
+// collect saves the crash outputs of earlier launches that hold a crash as
+// reports, oldest first, and deletes the outputs. Outputs of launches that
+// are still running, like a second instance or this one, are left alone.
+func (r *crashReporter) collect() ([]*CrashReport, error) {
+	paths, _ := filepath.Glob(filepath.Join(r.dir, "crash-*"+crashOutputExt))
+	var found []*CrashReport
+	var errs []error
+	for _, path := range paths {
+		pid, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "crash-"), crashOutputExt))
+		if err != nil || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		report, err := r.collectOutput(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		os.Remove(path)
+		if report != nil {
+			found = append(found, report)
+		}
+	}
+	if len(found) > 0 {
+		r.prune()
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Time.Before(found[j].Time) })
+	return found, errors.Join(errs...)
+}
+
+// collectOutput reads the crash output at path and, when it holds a crash,
+// saves it as a report.
+func (r *crashReporter) collectOutput(path string) (*CrashReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	line, trace, _ := bytes.Cut(data, []byte("\n"))
+	trace = bytes.TrimSpace(trace)
+	if len(trace) == 0 {
+		return nil, nil
+	}
+	var header crashHeader
+	json.Unmarshal(line, &header)
+	t := time.Now()
+	if info, err := os.Stat(path); err == nil {
+		t = info.ModTime()
+	}
+	report := &CrashReport{
+		ID:        t.UTC().Format("20060102-150405") + "-" + generateID()[:8],
+		Time:      t,
+		App:       header.App,
+		Version:   header.Version,
+		Velo:      header.Velo,
+		OS:        header.OS,
+		Arch:      header.Arch,
+		GoVersion: header.GoVersion,
+		Panic:     firstLine(string(trace)),
+		Stack:     string(trace),
+		Log:       tailLines(r.logFile, crashLogLines),
+	}
+	if err := r.save(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// capture starts the crash output of this launch with header and has the
+// runtime write fatal panics to it in addition to stderr.
+func (r *crashReporter) capture(header crashHeader) error {
+	f, err := os.Create(r.outputPath(header.PID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return debug.SetCrashOutput(f, debug.CrashOptions{})
+}
+
+func (r *crashReporter) save(report *CrashReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	report.path = filepath.Join(r.dir, "crash-"+report.ID+".json")
+	return os.WriteFile(report.path, data, 0o644)
+}
+
+// reports returns the saved reports, oldest first.
+func (r *crashReporter) reports() []*CrashReport {
+	paths, _ := filepath.Glob(filepath.Join(r.dir, "crash-*.json"))
+	sort.Strings(paths)
+	var reports []*CrashReport
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var report CrashReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			continue
+		}
+		report.path = p
+		reports = append(reports, &report)
+	}
+	return reports
+}
+
+// prune deletes the oldest reports beyond maxCrashReports.
+func (r *crashReporter) prune() {
+	paths, _ := filepath.Glob(filepath.Join(r.dir, "crash-*.json"))
+	sort.Strings(paths)
+	for len(paths) > maxCrashReports {
+		os.Remove(paths[0])
+		paths = paths[1:]
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+// tailLines returns the last n lines of the file at path.
+func tailLines(path string, n int) []string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
+
+// CrashReports returns the reports of earlier crashes that have not been
+// uploaded yet, oldest first.
+func (b *Box) CrashReports() []*CrashReport {
+	if b.crashes == nil {
+		return nil
+	}
+	return b.crashes.reports()
+}
+
+// UploadCrashReports sends the pending crash reports with
+// VeloAppOpt.CrashUploader or to velo.json's crash_reports.upload_url, and
+// deletes the reports that were sent. Run calls it at launch when an
+// uploader is configured.
+func (b *Box) UploadCrashReports(ctx context.Context) error {
+	if b.crashes == nil || b.crashes.uploader == nil {
+		return nil
+	}
+	var errs []error
+	for _, report := range b.crashes.reports() {
+		if err := b.crashes.uploader.Upload(ctx, report); err != nil {
+			errs = append(errs, fmt.Errorf("crash report %s: %w", report.ID, err))
+			continue
+		}
+		os.Remove(report.path)
+	}
+	return errors.Join(errs...)
+}
+
+// reportCrashes saves the crashes of earlier launches as reports, tells
+// the user about the latest one and uploads the pending reports in the
+// background.
+func (b *Box) reportCrashes() {
+	if b.crashes == nil {
+		return
+	}
+	found, err := b.crashes.collect()
+	if err != nil {
+		b.Log("crash").Error("reading crash output failed", "error", err)
+	}
+	for _, report := range found {
+		b.Log("crash").Warn("previous launch crashed", "panic", report.Panic, "report", report.path)
+	}
+	if len(found) > 0 && b.mode != ModeHttp {
+		report := found[len(found)-1]
+		showErrorDialog(fmt.Sprintf("%s quit unexpectedly the last time it was running.\n\n%s\n\nA crash report was saved to %s.", b.appName, report.Panic, report.path))
+	}
+	if b.crashes.uploader != nil {
+		go func() {
+			if err := b.UploadCrashReports(context.Background()); err != nil {
+				b.Log("crash").Warn("uploading crash reports failed", "error", err)
+			}
+		}()
+	}
+}
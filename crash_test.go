@@ -0,0 +1,147 @@
+package velo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ltaoo/velo/dir"
+)
+
+func TestHandlerPanicReturnsErrPanic(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Get("/api/boom", func(c *BoxContext) interface{} {
+		var m map[string]int
+		m["x"] = 1
+		return c.Ok(nil)
+	})
+
+	_, raw := app.handleMessage(`{"id":"1","method":"/api/boom"}`)
+	var res BoxResult
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		t.Fatalf("unmarshal: %v; result=%s", err, raw)
+	}
+	if res.Code != CodeInternal || res.Reason != "panic" || !strings.Contains(res.Msg, "nil map") {
+		t.Fatalf("bridge result = %+v", res)
+	}
+
+	server := newTestServer(t, app)
+	resp, err := http.Get(server.URL + "/api/boom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("HTTP status = %d", resp.StatusCode)
+	}
+}
+
+// deadPID is above the pid limit of every platform, so no process has it.
+const deadPID = 1<<30 - 1
+
+func writeCrashOutput(t *testing.T, appName string, pid int, trace string) string {
+	t.Helper()
+	r := &crashReporter{dir: filepath.Join(dir.New(appName).Data(), crashDirName)}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	header := `{"app":"` + appName + `","version":"1.2.0","velo":"0.1.0","os":"linux","arch":"amd64","go_version":"go1.23","pid":` + strconv.Itoa(pid) + `}` + "\n"
+	if err := os.WriteFile(r.outputPath(pid), []byte(header+trace), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return r.outputPath(pid)
+}
+
+func TestCrashIsReportedAtNextLaunch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var dialogs []string
+	show := showErrorDialog
+	showErrorDialog = func(msg string) { dialogs = append(dialogs, msg) }
+	defer func() { showErrorDialog = show }()
+
+	writeCrashOutput(t, "velo-crash-test", deadPID, "panic: assignment to entry in nil map\n\ngoroutine 7 [running]:\nmain.save()\n")
+	if err := os.WriteFile(dir.New("velo-crash-test").LogFile(), []byte("level=INFO msg=starting\nlevel=INFO msg=saving\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A launch that never runs, like velo generate, leaves the crash for the
+	// next one.
+	NewApp(&VeloAppOpt{Mode: ModeBridge, AppName: "velo-crash-test"})
+
+	app := NewApp(&VeloAppOpt{Mode: ModeBridge, AppName: "velo-crash-test"})
+	if len(app.CrashReports()) != 0 {
+		t.Fatalf("reports before Run = %+v", app.CrashReports())
+	}
+	app.reportCrashes()
+	if len(dialogs) != 1 || !strings.Contains(dialogs[0], "panic: assignment to entry in nil map") {
+		t.Fatalf("dialogs = %q", dialogs)
+	}
+	reports := app.CrashReports()
+	if len(reports) != 1 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	r := reports[0]
+	if r.Version != "1.2.0" || r.Panic != "panic: assignment to entry in nil map" || !strings.Contains(r.Stack, "main.save()") {
+		t.Fatalf("report = %+v", r)
+	}
+	if len(r.Log) != 2 || r.Log[1] != "level=INFO msg=saving" {
+		t.Fatalf("report log = %q", r.Log)
+	}
+
+	// The crash output was turned into a report and is not reported again.
+	again := NewApp(&VeloAppOpt{Mode: ModeBridge, AppName: "velo-crash-test"})
+	again.reportCrashes()
+	if len(dialogs) != 1 || len(again.CrashReports()) != 1 {
+		t.Fatalf("second launch: dialogs = %q, reports = %d", dialogs, len(again.CrashReports()))
+	}
+}
+
+func TestUploadCrashReports(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeCrashOutput(t, "velo-upload-test", deadPID, "fatal error: concurrent map writes\n")
+
+	var got CrashReport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	app := NewApp(&VeloAppOpt{
+		Mode:          ModeHttp,
+		AppName:       "velo-upload-test",
+		CrashUploader: HTTPCrashUploader(server.URL),
+	})
+	if _, err := app.crashes.collect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.UploadCrashReports(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Panic != "fatal error: concurrent map writes" || got.App != "velo-upload-test" {
+		t.Fatalf("uploaded report = %+v", got)
+	}
+	if reports := app.CrashReports(); len(reports) != 0 {
+		t.Fatalf("reports left after upload = %+v", reports)
+	}
+}
+
+func TestCrashOutputOfRunningLaunchIsKept(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// The parent process of the test is running and owns this output.
+	path := writeCrashOutput(t, "velo-running-test", os.Getppid(), "")
+
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, AppName: "velo-running-test"})
+	app.reportCrashes()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("output of running launch: %v", err)
+	}
+	if _, err := os.Stat(app.crashes.outputPath(os.Getpid())); err != nil {
+		t.Fatalf("output of this launch: %v", err)
+	}
+}
@@ -1,10 +1,19 @@
 package velo
 
-import "github.com/ltaoo/velo/webview"
+import (
+	"sync/atomic"
 
-// SetDebug enables or disables Velo's webview diagnostic logging.
-// Debug logging is disabled by default. Call this before opening a window to
-// also control diagnostic messages injected into the browser console.
+	"github.com/ltaoo/velo/webview"
+)
+
+var debugMode atomic.Bool
+
+// SetDebug enables or disables Velo's diagnostic logging: webview
+// diagnostics, and debug records of the default logger, which then also
+// writes to stderr. Debug logging is disabled by default. Call this before
+// NewApp and before opening a window to also control diagnostic messages
+// injected into the browser console.
 func SetDebug(enabled bool) {
+	debugMode.Store(enabled)
 	webview.SetDebug(enabled)
 }
@@ -0,0 +1,20 @@
+//go:build darwin && !cgo
+
+package error
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// showErrorDialog shows an alert through osascript when the package is built
+// without cgo.
+func showErrorDialog(message string) {
+	script := "display alert \"Application Error\" message " + strconv.Quote(message) + " as critical"
+	if err := exec.Command("osascript", "-e", script).Run(); err == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", message)
+}
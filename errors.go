@@ -0,0 +1,191 @@
+package velo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Application error codes carried in BoxResult.Code. CodeError is the code
+// emitted by BoxContext.Error and for unknown methods, as it was before
+// structured errors; the others mirror their HTTP status.
+const (
+	CodeOK           = 0
+	CodeError        = 100
+	CodeBadRequest   = 400
+	CodeUnauthorized = 401
+	CodeForbidden    = 403
+	CodeNotFound     = 404
+	CodeConflict     = 409
+	CodeValidation   = 422
+	CodeCanceled     = 499
+	CodeInternal     = 500
+	CodeUnavailable  = 503
+	CodeTimeout      = 504
+)
+
+// FieldError describes a problem with a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a structured handler error. Handlers can return it (or any error
+// wrapping it) and the transport encodes Code, Reason, Message and Details
+// into the BoxResult; over HTTP, Status is written as the response status.
+//
+// Errors compare by Reason with errors.Is, so a wrapped or customised error
+// still matches its sentinel:
+//
+//	if errors.Is(err, velo.ErrNotFound) { ... }
+type Error struct {
+	Code    int
+	Reason  string
+	Message string
+	Status  int
+	Details []FieldError
+	Err     error
+}
+
+var (
+	ErrBadRequest   = &Error{Code: CodeBadRequest, Reason: "bad_request", Message: "bad request", Status: http.StatusBadRequest}
+	ErrUnauthorized = &Error{Code: CodeUnauthorized, Reason: "unauthorized", Message: "unauthorized", Status: http.StatusUnauthorized}
+	ErrForbidden    = &Error{Code: CodeForbidden, Reason: "forbidden", Message: "forbidden", Status: http.StatusForbidden}
+	ErrNotFound     = &Error{Code: CodeNotFound, Reason: "not_found", Message: "not found", Status: http.StatusNotFound}
+	ErrConflict     = &Error{Code: CodeConflict, Reason: "conflict", Message: "conflict", Status: http.StatusConflict}
+	ErrValidation   = &Error{Code: CodeValidation, Reason: "validation_failed", Message: "validation failed", Status: http.StatusUnprocessableEntity}
+	ErrInternal     = &Error{Code: CodeInternal, Reason: "internal", Message: "internal error", Status: http.StatusInternalServerError}
+	// ErrUnavailable is returned for calls that arrive while the app quits.
+	ErrUnavailable = &Error{Code: CodeUnavailable, Reason: "unavailable", Message: "service unavailable", Status: http.StatusServiceUnavailable}
+	// ErrCanceled and ErrTimeout report a handler stopped by its context.
+	ErrCanceled = &Error{Code: CodeCanceled, Reason: "canceled", Message: "request canceled", Status: 499}
+	ErrTimeout  = &Error{Code: CodeTimeout, Reason: "timeout", Message: "request timed out", Status: http.StatusGatewayTimeout}
+	// ErrUnknownMethod is returned for a bridge or WebSocket call to a
+	// method no handler is registered for. It keeps CodeError so that
+	// frontends checking code 100 keep working.
+	ErrUnknownMethod = &Error{Code: CodeError, Reason: "unknown_method", Message: "unknown method", Status: http.StatusNotFound}
+	// ErrPanic is returned for a handler that panicked.
+	ErrPanic = &Error{Code: CodeInternal, Reason: "panic", Message: "handler panicked", Status: http.StatusInternalServerError}
+)
+
+// NewError creates an error with the given HTTP status, application code,
+// machine-readable reason and message.
+func NewError(status, code int, reason, message string) *Error {
+	return &Error{Code: code, Reason: reason, Message: message, Status: status}
+}
+
+// ValidationError returns ErrValidation carrying the given field details.
+func ValidationError(details ...FieldError) *Error {
+	return ErrValidation.WithDetails(details...)
+}
+
+func (e *Error) Error() string {
+	// WithMessage("%v", err).Wrap(err) already carries err in the message.
+	if e.Err != nil && e.Err.Error() != e.Message {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same reason.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return e.Reason == t.Reason
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	c.Details = append([]FieldError(nil), e.Details...)
+	return &c
+}
+
+// WithMessage returns a copy of e with a different message.
+func (e *Error) WithMessage(format string, args ...interface{}) *Error {
+	c := e.clone()
+	c.Message = fmt.Sprintf(format, args...)
+	return c
+}
+
+// WithDetails returns a copy of e with details appended.
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	c := e.clone()
+	c.Details = append(c.Details, details...)
+	return c
+}
+
+// Wrap returns a copy of e that wraps err, keeping err reachable through
+// errors.Is and errors.As.
+func (e *Error) Wrap(err error) *Error {
+	c := e.clone()
+	c.Err = err
+	return c
+}
+
+// asError converts any error to *Error. Context errors map to ErrCanceled
+// and ErrTimeout; other errors that do not wrap an *Error are reported as
+// ErrInternal with their own message.
+func asError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled.Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout.Wrap(err)
+	}
+	return &Error{
+		Code:    ErrInternal.Code,
+		Reason:  ErrInternal.Reason,
+		Message: err.Error(),
+		Status:  ErrInternal.Status,
+		Err:     err,
+	}
+}
+
+// Fail encodes err into a BoxResult. When err is (or wraps) an *Error its
+// code, reason, details and HTTP status are used; any other error is
+// reported as ErrInternal.
+func (c *BoxContext) Fail(err error) string {
+	if err == nil {
+		return c.Ok(nil)
+	}
+	e := asError(err)
+	c.status = e.Status
+	var details interface{}
+	if len(e.Details) > 0 {
+		details = e.Details
+	}
+	return marshalResult(BoxResult{
+		Code:    e.Code,
+		Msg:     e.Message,
+		Reason:  e.Reason,
+		Details: details,
+	})
+}
+
+// render turns a handler result into the string sent to the caller. A
+// returned error is encoded with Fail; a []byte is kept in c.bytes for the
+// transport to send as binary data.
+func (c *BoxContext) render(result interface{}) string {
+	switch v := result.(type) {
+	case error:
+		return c.Fail(v)
+	case []byte:
+		if v == nil {
+			v = []byte{}
+		}
+		c.bytes = v
+		return c.Ok(nil)
+	}
+	return fmt.Sprintf("%v", result)
+}
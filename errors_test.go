@@ -0,0 +1,104 @@
+package velo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestErrorMatchesSentinelThroughWrapping(t *testing.T) {
+	cause := errors.New("row missing")
+	err := fmt.Errorf("load note: %w", ErrNotFound.WithMessage("note %d not found", 3).Wrap(cause))
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("errors.Is(err, ErrNotFound) = false")
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Fatal("errors.Is(err, ErrConflict) = true")
+	}
+	if !errors.Is(err, cause) {
+		t.Fatal("wrapped cause is not reachable")
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound || e.Message != "note 3 not found" {
+		t.Fatalf("errors.As = %+v", e)
+	}
+	if ErrNotFound.Message != "not found" {
+		t.Fatal("WithMessage modified the sentinel")
+	}
+	if got := ErrValidation.WithMessage("%v", cause).Wrap(cause).Error(); got != "row missing" {
+		t.Fatalf("Error() = %q, want the message once", got)
+	}
+}
+
+func TestReturnedErrorsAreEncodedOnEveryTransport(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Get("/api/notes/:id", func(c *BoxContext) interface{} {
+		return fmt.Errorf("lookup: %w", ErrNotFound.WithMessage("note %s not found", c.Param("id")))
+	})
+	app.Post("/api/notes", func(c *BoxContext) interface{} {
+		return ValidationError(FieldError{Field: "title", Message: "is required"})
+	})
+	app.Get("/api/crash", func(c *BoxContext) interface{} {
+		return errors.New("disk on fire")
+	})
+
+	type result struct {
+		Code    int          `json:"code"`
+		Msg     string       `json:"msg"`
+		Reason  string       `json:"reason"`
+		Details []FieldError `json:"details"`
+	}
+
+	_, raw := app.handleMessage(`{"id":"1","method":"/api/notes/9"}`)
+	var res result
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if res.Code != CodeNotFound || res.Reason != "not_found" || res.Msg != "note 9 not found" {
+		t.Fatalf("bridge result = %+v", res)
+	}
+
+	_, raw = app.handleMessage(`{"id":"1","method":"/api/missing"}`)
+	res = result{}
+	json.Unmarshal([]byte(raw), &res)
+	if res.Code != CodeError || res.Reason != "unknown_method" {
+		t.Fatalf("unknown method result = %+v, want code %d", res, CodeError)
+	}
+
+	server := newTestServer(t, app)
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		reason       string
+	}{
+		{http.MethodGet, "/api/notes/9", http.StatusNotFound, "not_found"},
+		{http.MethodPost, "/api/notes", http.StatusUnprocessableEntity, "validation_failed"},
+		{http.MethodGet, "/api/crash", http.StatusInternalServerError, "internal"},
+	} {
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, resp.StatusCode, tc.status)
+		}
+		res = result{}
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("unmarshal: %v; body=%s", err, body)
+		}
+		if res.Reason != tc.reason {
+			t.Errorf("%s %s reason = %q, want %q", tc.method, tc.path, res.Reason, tc.reason)
+		}
+		if tc.reason == "validation_failed" && (len(res.Details) != 1 || res.Details[0].Field != "title") {
+			t.Errorf("details = %+v", res.Details)
+		}
+	}
+}
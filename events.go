@@ -0,0 +1,137 @@
+package velo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+const (
+	veloEventType       = "__velo_event"
+	veloEventEmitMethod = "/__velo/events/emit"
+)
+
+// Event is a named event sent by a window with velo.emit.
+type Event struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Window is the name of the window that emitted the event.
+	Window string `json:"window,omitempty"`
+}
+
+// Bind decodes the event payload into v.
+func (e *Event) Bind(v interface{}) error {
+	if len(e.Payload) == 0 {
+		return fmt.Errorf("event %q has no payload", e.Name)
+	}
+	return json.Unmarshal(e.Payload, v)
+}
+
+// EventHandler receives events emitted by the frontend.
+type EventHandler func(e *Event)
+
+// eventMessage is the envelope of an event sent to the frontend. Window
+// limits delivery to one window; Source is the page that emitted the event,
+// which does not receive it a second time.
+type eventMessage struct {
+	Type    string      `json:"type"`
+	Name    string      `json:"name"`
+	Payload interface{} `json:"payload,omitempty"`
+	Window  string      `json:"window,omitempty"`
+	Source  string      `json:"source,omitempty"`
+}
+
+type eventListener struct {
+	fn EventHandler
+}
+
+type eventBus struct {
+	mu        sync.RWMutex
+	listeners map[string][]*eventListener
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{listeners: make(map[string][]*eventListener)}
+}
+
+func (e *eventBus) on(name string, fn EventHandler) func() {
+	l := &eventListener{fn: fn}
+	e.mu.Lock()
+	e.listeners[name] = append(e.listeners[name], l)
+	e.mu.Unlock()
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		list := e.listeners[name]
+		for i, v := range list {
+			if v == l {
+				e.listeners[name] = append(list[:i:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(e.listeners[name]) == 0 {
+			delete(e.listeners, name)
+		}
+	}
+}
+
+func (e *eventBus) dispatch(event *Event) {
+	e.mu.RLock()
+	list := append([]*eventListener(nil), e.listeners[event.Name]...)
+	e.mu.RUnlock()
+	for _, l := range list {
+		l.fn(event)
+	}
+}
+
+// On registers fn for the frontend event name, emitted with
+// velo.emit(name, payload). Listeners run in the order they were
+// registered. The returned function removes the listener.
+func (b *Box) On(name string, fn EventHandler) func() {
+	return b.events.on(name, fn)
+}
+
+// Emit sends the event name with payload to every window, where it reaches
+// the listeners registered with velo.on(name, fn). It reports whether any
+// transport delivered the event.
+func (b *Box) Emit(name string, payload interface{}) bool {
+	return b.SendMessage(eventMessage{Type: veloEventType, Name: name, Payload: payload})
+}
+
+// EmitTo is like Emit but only the window with the given name receives the
+// event. Pages served without a window, such as a browser in ModeHttp,
+// count as the "default" window.
+func (b *Box) EmitTo(window, name string, payload interface{}) bool {
+	return b.SendMessage(eventMessage{Type: veloEventType, Name: name, Payload: payload, Window: window})
+}
+
+func (b *Box) registerEventRoutes() {
+	b.Post(veloEventEmitMethod, func(c *BoxContext) interface{} {
+		var body struct {
+			Name    string          `json:"name"`
+			Payload json.RawMessage `json:"payload"`
+			Window  string          `json:"window"`
+			Source  string          `json:"source"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			return ErrBadRequest.Wrap(err)
+		}
+		if body.Name == "" {
+			return ErrBadRequest.WithMessage("missing event name")
+		}
+		// As with call replies, a native window cannot claim to be another
+		// one; pages on a WebSocket name their window themselves.
+		window := body.Window
+		if name := callerWindow(c.Context()); name != "" {
+			window = name
+		}
+		b.events.dispatch(&Event{Name: body.Name, Payload: body.Payload, Window: window})
+		// Other windows listen to the same bus.
+		var payload interface{}
+		if len(body.Payload) > 0 {
+			payload = body.Payload
+		}
+		b.SendMessage(eventMessage{Type: veloEventType, Name: body.Name, Payload: payload, Source: body.Source})
+		return c.Ok(nil)
+	})
+}
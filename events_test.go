@@ -0,0 +1,131 @@
+package velo
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ltaoo/velo/webview"
+)
+
+func readTestEvent(t *testing.T, client *testWSClient) eventMessage {
+	t.Helper()
+	_, _, payload, err := readWSFrame(client.reader)
+	if err != nil {
+		t.Fatalf("read websocket message: %v", err)
+	}
+	var frame struct {
+		Type    string       `json:"type"`
+		Payload eventMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(payload, &frame); err != nil {
+		t.Fatalf("unmarshal message frame: %v; payload=%s", err, payload)
+	}
+	if frame.Type != veloWSMessageType || frame.Payload.Type != veloEventType {
+		t.Fatalf("frame = %s, want an event message", payload)
+	}
+	return frame.Payload
+}
+
+func TestEmitReachesWebSocketClients(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	server := newTestServer(t, app)
+	client := dialTestWS(t, server.URL)
+	defer client.close()
+
+	if !app.Emit("note:saved", H{"id": 3}) {
+		t.Fatal("Emit returned false")
+	}
+	event := readTestEvent(t, client)
+	if event.Name != "note:saved" || event.Window != "" {
+		t.Fatalf("event = %+v", event)
+	}
+	if payload, _ := event.Payload.(map[string]interface{}); payload["id"] != float64(3) {
+		t.Fatalf("payload = %#v", event.Payload)
+	}
+
+	app.EmitTo("settings", "theme", "dark")
+	event = readTestEvent(t, client)
+	if event.Name != "theme" || event.Window != "settings" || event.Payload != "dark" {
+		t.Fatalf("targeted event = %+v", event)
+	}
+}
+
+func TestFrontendEventsReachGoListenersAndOtherWindows(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	var got []Event
+	off := app.On("note:edited", func(e *Event) {
+		got = append(got, *e)
+	})
+	server := newTestServer(t, app)
+	client := dialTestWS(t, server.URL)
+	defer client.close()
+
+	emit := `{"id":"1","method":"/__velo/events/emit","httpMethod":"POST","args":{"name":"note:edited","payload":{"id":7},"window":"main","source":"page-1"}}`
+	_, raw := app.handleMessage(emit)
+	var res BoxResult
+	if err := json.Unmarshal([]byte(raw), &res); err != nil || res.Code != CodeOK {
+		t.Fatalf("emit result = %s", raw)
+	}
+	if len(got) != 1 || got[0].Window != "main" {
+		t.Fatalf("listener events = %+v", got)
+	}
+	var payload struct {
+		ID int `json:"id"`
+	}
+	if err := got[0].Bind(&payload); err != nil || payload.ID != 7 {
+		t.Fatalf("Bind = %+v, %v", payload, err)
+	}
+
+	event := readTestEvent(t, client)
+	if event.Name != "note:edited" || event.Source != "page-1" {
+		t.Fatalf("rebroadcast event = %+v", event)
+	}
+
+	off()
+	app.handleMessage(emit)
+	if len(got) != 1 {
+		t.Fatalf("listener called after off: %+v", got)
+	}
+}
+
+func TestNativeWindowCannotEmitAsAnotherWindow(t *testing.T) {
+	h := webview.Headless()
+	h.Reset()
+	t.Cleanup(h.Reset)
+
+	app := NewApp(&VeloAppOpt{Mode: ModeBridge, WebviewEngine: webview.EngineHeadless})
+	app.NewWebview(&VeloWebviewOpt{Name: "main"})
+	events := make(chan Event, 1)
+	app.On("note:edited", func(e *Event) { events <- *e })
+	done := make(chan struct{})
+	go func() {
+		app.Run()
+		close(done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := h.WaitWindow(ctx, "main"); err != nil {
+		t.Fatal(err)
+	}
+	app.OpenWindow(&VeloWebviewOpt{Name: "editor"})
+	if _, err := h.WaitWindow(ctx, "editor"); err != nil {
+		t.Fatal(err)
+	}
+
+	emit := `{"id":"1","method":"/__velo/events/emit","httpMethod":"POST","args":{"name":"note:edited","window":"main"}}`
+	if _, _, err := h.PostMessage("editor", emit); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		if e.Window != "editor" {
+			t.Fatalf("event window = %q, want editor", e.Window)
+		}
+	case <-ctx.Done():
+		t.Fatal("listener not called")
+	}
+	app.Quit(ctx)
+	<-done
+}
@@ -0,0 +1,294 @@
+package velo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ltaoo/velo/internal/sourcemap"
+)
+
+const (
+	veloLogMethod = "/__velo/log"
+	// maxSourceMaps bounds the source maps kept for resolving stack traces.
+	maxSourceMaps = 64
+)
+
+// frontendLogLevels are the console methods forwarded by the runtime, from
+// the lowest level.
+var frontendLogLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"log":   slog.LevelInfo,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// stackLocationPattern matches the script locations in the stack traces of
+// WebKit, Chromium and Firefox, e.g. "http://127.0.0.1:8080/assets/index.js:1:2345".
+var stackLocationPattern = regexp.MustCompile(`((?:https?|velo|file)://[^\s()@]+):(\d+):(\d+)`)
+
+type veloRuntimeFrontendLogs struct {
+	Level string `json:"level"`
+}
+
+type frontendLogEntry struct {
+	Level string `json:"level"`
+	// Kind is "console", "error" for an uncaught error or "rejection" for
+	// an unhandled promise rejection.
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Stack   string `json:"stack,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	// Time is when the entry was logged, in milliseconds since the epoch.
+	Time int64 `json:"time"`
+}
+
+type frontendLogBatch struct {
+	Window  string             `json:"window"`
+	URL     string             `json:"url"`
+	Entries []frontendLogEntry `json:"entries"`
+	// Dropped counts the entries discarded because the page logged faster
+	// than they were sent.
+	Dropped int `json:"dropped"`
+}
+
+// frontendLogs forwards the console output and uncaught errors of the
+// windows into the log of the app.
+type frontendLogs struct {
+	level      string
+	sourceMaps bool
+
+	mu   sync.Mutex
+	maps map[string]*sourcemap.Map
+}
+
+// useFrontendLogs enables forwarding when VeloAppOpt.FrontendLogs or
+// velo.json's desktop.frontend_logs section asks for it.
+func (b *Box) useFrontendLogs(o *VeloAppOpt) {
+	cfg := b.appConfig.Desktop.FrontendLogs
+	if o.FrontendLogs != nil {
+		cfg = *o.FrontendLogs
+	}
+	if !cfg.Enabled {
+		return
+	}
+	level := strings.ToLower(cfg.Level)
+	if _, ok := frontendLogLevels[level]; !ok {
+		level = "warn"
+	}
+	b.frontendLogs = &frontendLogs{
+		level:      level,
+		sourceMaps: cfg.SourceMaps,
+		maps:       make(map[string]*sourcemap.Map),
+	}
+	b.Post(veloLogMethod, func(c *BoxContext) interface{} {
+		var batch frontendLogBatch
+		if err := c.BindJSON(&batch); err != nil {
+			return ErrBadRequest.Wrap(err)
+		}
+		b.logFrontend(c.Context(), &batch)
+		return c.Ok(nil)
+	})
+}
+
+func (b *Box) runtimeFrontendLogs() *veloRuntimeFrontendLogs {
+	if b.frontendLogs == nil {
+		return nil
+	}
+	return &veloRuntimeFrontendLogs{Level: b.frontendLogs.level}
+}
+
+// logFrontend writes a batch sent by a window, with the window name and page
+// URL on each record.
+func (b *Box) logFrontend(ctx context.Context, batch *frontendLogBatch) {
+	window := batch.Window
+	if window == "" {
+		window = "default"
+	}
+	logger := b.Log("frontend").With("window", window, "url", batch.URL)
+	h := logger.Handler()
+	for _, e := range batch.Entries {
+		level, ok := frontendLogLevels[e.Level]
+		if !ok {
+			level = slog.LevelInfo
+		}
+		if !h.Enabled(ctx, level) {
+			continue
+		}
+		t := time.Now()
+		if e.Time > 0 {
+			t = time.UnixMilli(e.Time)
+		}
+		r := slog.NewRecord(t, level, e.Message, 0)
+		if e.Kind != "" && e.Kind != "console" {
+			r.AddAttrs(slog.String("kind", e.Kind))
+		}
+		if e.Source != "" {
+			source := e.Source
+			if e.Line > 0 {
+				source += ":" + strconv.Itoa(e.Line)
+				if e.Column > 0 {
+					source += ":" + strconv.Itoa(e.Column)
+				}
+			}
+			r.AddAttrs(slog.String("source", b.resolveStack(window, source)))
+		}
+		if e.Stack != "" {
+			r.AddAttrs(slog.String("stack", b.resolveStack(window, e.Stack)))
+		}
+		h.Handle(ctx, r)
+	}
+	if batch.Dropped > 0 {
+		logger.Warn("frontend log entries dropped", "count", batch.Dropped)
+	}
+}
+
+// resolveStack replaces the script locations in stack with their original
+// sources when source maps are enabled and served with the frontend.
+// Locations that cannot be mapped are kept.
+func (b *Box) resolveStack(window, stack string) string {
+	if b.frontendLogs == nil || !b.frontendLogs.sourceMaps {
+		return stack
+	}
+	return stackLocationPattern.ReplaceAllStringFunc(stack, func(loc string) string {
+		m := stackLocationPattern.FindStringSubmatch(loc)
+		line, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		sm := b.sourceMap(window, m[1])
+		if sm == nil {
+			return loc
+		}
+		pos, ok := sm.Lookup(line, column)
+		if !ok {
+			return loc
+		}
+		return pos.String()
+	})
+}
+
+// sourceMap returns the source map of a script served to window, or nil.
+// Results, including misses, are cached.
+func (b *Box) sourceMap(window, script string) *sourcemap.Map {
+	u, err := url.Parse(script)
+	if err != nil || u.Path == "" {
+		return nil
+	}
+	key := window + "\x00" + u.Path
+	f := b.frontendLogs
+	f.mu.Lock()
+	sm, ok := f.maps[key]
+	f.mu.Unlock()
+	if ok {
+		return sm
+	}
+	sm = b.loadSourceMap(window, u.Path)
+	f.mu.Lock()
+	if len(f.maps) >= maxSourceMaps {
+		f.maps = make(map[string]*sourcemap.Map)
+	}
+	f.maps[key] = sm
+	f.mu.Unlock()
+	return sm
+}
+
+// loadSourceMap reads the map named by the sourceMappingURL comment of the
+// script, or script.map, from the files served to window.
+func (b *Box) loadSourceMap(window, script string) *sourcemap.Map {
+	h := b.frontendHandler(window)
+	if h == nil {
+		return nil
+	}
+	mapPath := script + ".map"
+	if js, ok := serveFile(h, script); ok {
+		if ref := sourceMappingURL(js); ref != "" {
+			if data, ok := strings.CutPrefix(ref, "data:application/json;base64,"); ok {
+				raw, err := base64.StdEncoding.DecodeString(data)
+				if err != nil {
+					return nil
+				}
+				sm, _ := sourcemap.Parse(raw)
+				return sm
+			}
+			if strings.Contains(ref, "://") {
+				return nil
+			}
+			if path.IsAbs(ref) {
+				mapPath = ref
+			} else {
+				mapPath = path.Join(path.Dir(script), ref)
+			}
+		}
+	}
+	data, ok := serveFile(h, mapPath)
+	if !ok {
+		return nil
+	}
+	sm, err := sourcemap.Parse(data)
+	if err != nil {
+		b.Log("frontend").Debug("invalid source map", "path", mapPath, "error", err)
+		return nil
+	}
+	return sm
+}
+
+// frontendHandler returns the handler serving the files of window.
+func (b *Box) frontendHandler(window string) http.Handler {
+	for _, w := range b.webviews {
+		if w.Name == window && w.Mux != nil {
+			return w.Mux
+		}
+	}
+	if b.mux != nil {
+		return b.mux
+	}
+	if len(b.webviews) > 0 {
+		return b.webviews[0].Mux
+	}
+	return nil
+}
+
+func sourceMappingURL(js []byte) string {
+	const marker = "# sourceMappingURL="
+	i := bytes.LastIndex(js, []byte(marker))
+	if i < 0 {
+		return ""
+	}
+	ref := js[i+len(marker):]
+	if end := bytes.IndexAny(ref, " \t\r\n*"); end >= 0 {
+		ref = ref[:end]
+	}
+	return string(ref)
+}
+
+// serveFile fetches p from h in process.
+func serveFile(h http.Handler, p string) ([]byte, bool) {
+	req, err := http.NewRequest(http.MethodGet, p, nil)
+	if err != nil {
+		return nil, false
+	}
+	w := &fileResponse{header: make(http.Header), code: http.StatusOK}
+	h.ServeHTTP(w, req)
+	return w.body.Bytes(), w.code == http.StatusOK
+}
+
+type fileResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *fileResponse) Header() http.Header         { return w.header }
+func (w *fileResponse) WriteHeader(code int)        { w.code = code }
+func (w *fileResponse) Write(p []byte) (int, error) { return w.body.Write(p) }
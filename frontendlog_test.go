@@ -0,0 +1,91 @@
+package velo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ltaoo/velo/buildcfg"
+)
+
+func newFrontendLogsApp(t *testing.T, cfg buildcfg.FrontendLogsSection) (*Box, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, Logger: logger, FrontendLogs: &cfg})
+	return app, &out
+}
+
+func postFrontendLogs(t *testing.T, app *Box, batch frontendLogBatch) BoxResult {
+	t.Helper()
+	args, _ := json.Marshal(batch)
+	_, raw := app.handleMessage(`{"id":"1","method":"/__velo/log","httpMethod":"POST","args":` + string(args) + `}`)
+	var res BoxResult
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		t.Fatalf("unmarshal result: %v; raw=%s", err, raw)
+	}
+	return res
+}
+
+func TestFrontendLogsAreWrittenToLog(t *testing.T) {
+	app, out := newFrontendLogsApp(t, buildcfg.FrontendLogsSection{Enabled: true, Level: "info"})
+	if info := app.runtimeInfo(nil); info.FrontendLogs == nil || info.FrontendLogs.Level != "info" {
+		t.Fatalf("runtime frontend_logs = %+v", info.FrontendLogs)
+	}
+	res := postFrontendLogs(t, app, frontendLogBatch{
+		Window: "settings",
+		URL:    "http://127.0.0.1:8080/settings",
+		Entries: []frontendLogEntry{
+			{Level: "warn", Kind: "console", Message: "low disk"},
+			{Level: "error", Kind: "error", Message: "boom", Source: "http://127.0.0.1:8080/app.js", Line: 3, Column: 7, Stack: "Error: boom\n    at http://127.0.0.1:8080/app.js:3:7"},
+		},
+		Dropped: 2,
+	})
+	if res.Code != CodeOK {
+		t.Fatalf("result = %+v", res)
+	}
+	got := out.String()
+	for _, want := range []string{
+		`level=WARN msg="low disk" subsystem=frontend window=settings url=http://127.0.0.1:8080/settings`,
+		`level=ERROR msg=boom subsystem=frontend window=settings url=http://127.0.0.1:8080/settings kind=error source=http://127.0.0.1:8080/app.js:3:7 stack=`,
+		`msg="frontend log entries dropped" subsystem=frontend window=settings url=http://127.0.0.1:8080/settings count=2`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("log missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFrontendLogsDisabled(t *testing.T) {
+	app, _ := newFrontendLogsApp(t, buildcfg.FrontendLogsSection{})
+	if info := app.runtimeInfo(nil); info.FrontendLogs != nil {
+		t.Fatalf("runtime frontend_logs = %+v, want none", info.FrontendLogs)
+	}
+	if res := postFrontendLogs(t, app, frontendLogBatch{}); res.Code == CodeOK {
+		t.Fatalf("log route is registered while disabled: %+v", res)
+	}
+}
+
+func TestFrontendLogsResolveSourceMaps(t *testing.T) {
+	app, out := newFrontendLogsApp(t, buildcfg.FrontendLogsSection{Enabled: true, SourceMaps: true})
+	app.mux = app.setupMux(fstest.MapFS{
+		"frontend/index.html":        {Data: []byte("<html></html>")},
+		"frontend/assets/app.js":     {Data: []byte("throw new Error('boom')\n//# sourceMappingURL=app.js.map\n")},
+		"frontend/assets/app.js.map": {Data: []byte(`{"version":3,"sources":["../src/App.tsx"],"names":[],"mappings":"cASE"}`)},
+		"frontend/assets/vendor.js":  {Data: []byte("var x\n")},
+	}, "")
+	postFrontendLogs(t, app, frontendLogBatch{Entries: []frontendLogEntry{{
+		Level:   "error",
+		Kind:    "error",
+		Message: "boom",
+		Stack:   "Error: boom\n    at render (http://127.0.0.1:8080/assets/app.js:1:15)\n    at http://127.0.0.1:8080/assets/vendor.js:1:1",
+	}}})
+	got := out.String()
+	want := `stack="Error: boom\n    at render (../src/App.tsx:10:3)\n    at http://127.0.0.1:8080/assets/vendor.js:1:1"`
+	if !strings.Contains(got, want) {
+		t.Fatalf("log missing %s:\n%s", want, got)
+	}
+}
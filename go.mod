@@ -1,11 +1,12 @@
 module github.com/ltaoo/velo
 
-go 1.20
+go 1.23
 
 require (
 	github.com/blang/semver/v4 v4.0.0
 	github.com/ebitengine/purego v0.10.0
 	github.com/glebarez/sqlite v1.11.0
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/golang-migrate/migrate/v4 v4.17.1
 	github.com/leanovate/gopter v0.2.11
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
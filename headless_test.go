@@ -0,0 +1,137 @@
+package velo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ltaoo/velo/webview"
+)
+
+func TestRunWithHeadlessEngine(t *testing.T) {
+	h := webview.Headless()
+	h.Reset()
+	t.Cleanup(h.Reset)
+
+	app := NewApp(&VeloAppOpt{Mode: ModeBridge, WebviewEngine: webview.EngineHeadless})
+	app.Get("/api/ping", func(c *BoxContext) interface{} {
+		return c.Ok(H{"pong": true})
+	})
+	app.NewWebview(&VeloWebviewOpt{Name: "main", Title: "Notes", Width: 800, Height: 600})
+	done := make(chan struct{})
+	go func() {
+		app.Run()
+		close(done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	w, err := h.WaitWindow(ctx, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.URL != "velo://localhost/" || w.Title != "Notes" || w.Width != 800 {
+		t.Fatalf("window = %+v", w)
+	}
+
+	_, raw, err := h.PostMessage("main", `{"id":"1","method":"/api/ping"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res BoxResult
+	if err := json.Unmarshal([]byte(raw), &res); err != nil || res.Code != CodeOK {
+		t.Fatalf("ping result = %s", raw)
+	}
+
+	app.Webview.SetTitle("Notes - draft")
+	if w, _ := h.Window("main"); w.Title != "Notes - draft" {
+		t.Fatalf("title = %q", w.Title)
+	}
+	if !app.Emit("note:saved", H{"id": 3}) {
+		t.Fatal("Emit returned false")
+	}
+	if msgs := h.TakeMessages(); len(msgs) != 1 || !strings.Contains(msgs[0], `"name":"note:saved"`) {
+		t.Fatalf("messages = %q", msgs)
+	}
+
+	if err := app.Quit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("Run did not return after Quit")
+	}
+}
+
+// notifyWriter signals ch when a log line contains match.
+type notifyWriter struct {
+	match string
+	ch    chan struct{}
+}
+
+func (w notifyWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte(w.match)) {
+		select {
+		case w.ch <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func TestClosingTheLastWindowAsksBeforeQuitting(t *testing.T) {
+	h := webview.Headless()
+	h.Reset()
+	t.Cleanup(h.Reset)
+
+	vetoed := make(chan struct{}, 1)
+	logger := slog.New(slog.NewTextHandler(notifyWriter{match: "closing the last window was vetoed", ch: vetoed}, nil))
+	app := NewApp(&VeloAppOpt{Mode: ModeBridge, WebviewEngine: webview.EngineHeadless, Logger: logger})
+	asked := make(chan bool, 2)
+	var allow atomic.Bool
+	app.OnBeforeQuit(func(ctx context.Context) bool {
+		ok := allow.Load()
+		asked <- ok
+		return ok
+	})
+	app.NewWebview(&VeloWebviewOpt{Name: "main"})
+	done := make(chan struct{})
+	go func() {
+		app.Run()
+		close(done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := h.WaitWindow(ctx, "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Webview.Close()
+	select {
+	case <-asked:
+	case <-ctx.Done():
+		t.Fatal("OnBeforeQuit was not asked")
+	}
+	// Wait until the veto is handled, so the next close asks again.
+	select {
+	case <-vetoed:
+	case <-ctx.Done():
+		t.Fatal("the veto was not handled")
+	}
+	if _, ok := h.Window("main"); !ok {
+		t.Fatal("vetoed close destroyed the window")
+	}
+
+	allow.Store(true)
+	app.Webview.Close()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("Run did not return after the close was allowed")
+	}
+}
@@ -0,0 +1,205 @@
+package velo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ltaoo/velo/dir"
+	"github.com/ltaoo/velo/webview"
+)
+
+const (
+	instanceSocketName = "instance.sock"
+	// instanceTakeoverSuffix names the file that serializes taking over a
+	// lock left behind by a crashed instance.
+	instanceTakeoverSuffix = ".takeover"
+	// maxSocketPath keeps the socket path under the sockaddr_un limit of
+	// every platform (104 bytes on macOS).
+	maxSocketPath  = 100
+	forwardTimeout = 3 * time.Second
+	takeoverRetry  = 20 * time.Millisecond
+)
+
+// errTakeoverBusy means another launch is taking over the lock.
+var errTakeoverBusy = errors.New("lock is being taken over")
+
+// exitSecondInstance ends a launch whose arguments were forwarded to the
+// running instance.
+var exitSecondInstance = func() { os.Exit(0) }
+
+// SecondInstanceHandler receives the arguments (without the program name)
+// and working directory of a launch that found the app already running.
+type SecondInstanceHandler func(args []string, cwd string)
+
+type instanceMessage struct {
+	Args []string `json:"args"`
+	Cwd  string   `json:"cwd"`
+}
+
+// instanceLock is the per-user lock held by the first instance: a Unix
+// socket that later launches connect to in order to hand over their
+// arguments.
+type instanceLock struct {
+	ln       net.Listener
+	launches *launchQueue[instanceMessage]
+}
+
+func newInstanceLock(ln net.Listener) *instanceLock {
+	return &instanceLock{ln: ln, launches: newLaunchQueue[instanceMessage]()}
+}
+
+// OnSecondInstance registers fn to run in the first instance each time the
+// app is launched again while VeloAppOpt.SingleInstance is set. The main
+// window is brought to the front before fn runs. Like OnOpenURL, launches
+// forwarded before fn is registered or the app has started are delivered
+// once both have happened.
+func (b *Box) OnSecondInstance(fn SecondInstanceHandler) {
+	if b.instance == nil {
+		return
+	}
+	b.instance.launches.add(func(msg instanceMessage) {
+		fn(msg.Args, msg.Cwd)
+	})
+}
+
+// instanceSocketPath returns the lock socket in the data dir of appName, or
+// a per-user path in the temp dir when that would be too long.
+func instanceSocketPath(appName string) string {
+	p := filepath.Join(dir.New(appName).Data(), instanceSocketName)
+	if len(p) < maxSocketPath {
+		return p
+	}
+	sum := sha256.Sum256([]byte(p))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("velo-%x.sock", sum[:8]))
+}
+
+// acquireInstance takes the lock at path. When another instance holds it,
+// args and cwd are forwarded to that instance and first is false. A lock
+// left behind by a crashed instance is taken over.
+func acquireInstance(path string, args []string, cwd string) (lock *instanceLock, first bool, err error) {
+	msg := instanceMessage{Args: args, Cwd: cwd}
+	deadline := time.Now().Add(forwardTimeout)
+	for {
+		if ln, err := net.Listen("unix", path); err == nil {
+			return newInstanceLock(ln), true, nil
+		}
+		if err := forwardInstance(path, msg); err == nil {
+			return nil, false, nil
+		}
+		ln, err := takeOverInstance(path)
+		if err == nil {
+			return newInstanceLock(ln), true, nil
+		}
+		if !errors.Is(err, errTakeoverBusy) || time.Now().After(deadline) {
+			return nil, true, fmt.Errorf("lock %s: %w", path, err)
+		}
+		// Another launch is taking over; forward to it once it listens.
+		time.Sleep(takeoverRetry)
+	}
+}
+
+// takeOverInstance replaces the socket of a crashed instance. Launches
+// racing for the same stale socket take turns through an O_EXCL file, so
+// that one cannot remove the socket the other has just bound and both end
+// up listening as the first instance.
+func takeOverInstance(path string) (net.Listener, error) {
+	guard := path + instanceTakeoverSuffix
+	f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		// A guard left by a launch that crashed while taking over is stale.
+		if info, err := os.Stat(guard); err == nil && time.Since(info.ModTime()) > forwardTimeout {
+			os.Remove(guard)
+		}
+		return nil, errTakeoverBusy
+	}
+	f.Close()
+	defer os.Remove(guard)
+	// The launch that held the guard before may be listening by now.
+	if conn, err := net.DialTimeout("unix", path, forwardTimeout); err == nil {
+		conn.Close()
+		return nil, errTakeoverBusy
+	}
+	os.Remove(path)
+	return net.Listen("unix", path)
+}
+
+// forwardInstance sends msg to the instance listening at path and waits
+// for it to acknowledge.
+func forwardInstance(path string, msg instanceMessage) error {
+	conn, err := net.DialTimeout("unix", path, forwardTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(forwardTimeout))
+	if err := json.NewEncoder(conn).Encode(msg); err != nil {
+		return err
+	}
+	_, err = bufio.NewReader(conn).ReadString('\n')
+	return err
+}
+
+// serve accepts the launches forwarded by later instances until the lock is
+// closed.
+func (l *instanceLock) serve(handle func(instanceMessage)) {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(forwardTimeout))
+			var msg instanceMessage
+			if err := json.NewDecoder(conn).Decode(&msg); err != nil {
+				return
+			}
+			conn.Write([]byte("ok\n"))
+			handle(msg)
+		}()
+	}
+}
+
+func (l *instanceLock) close() error {
+	if l == nil {
+		return nil
+	}
+	return l.ln.Close()
+}
+
+// useSingleInstance takes the lock for the app or, when it is already
+// running, forwards this launch to it and exits.
+func (b *Box) useSingleInstance() {
+	cwd, _ := os.Getwd()
+	lock, first, err := acquireInstance(instanceSocketPath(b.appName), os.Args[1:], cwd)
+	if err != nil {
+		b.Log("instance").Error("single instance lock failed", "error", err)
+	}
+	if !first {
+		exitSecondInstance()
+		return
+	}
+	if lock == nil {
+		return
+	}
+	b.instance = lock
+	go lock.serve(b.handleSecondInstance)
+}
+
+func (b *Box) handleSecondInstance(msg instanceMessage) {
+	if b.mode != ModeHttp && len(b.webviews) > 0 {
+		main := b.webviews[0]
+		w := webview.NewHandle(main.Name, main.Engine)
+		w.Restore()
+		w.Show()
+	}
+	b.instance.launches.open(msg)
+	b.openLaunchArgs(msg.Args, msg.Cwd)
+}
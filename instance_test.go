@@ -0,0 +1,104 @@
+package velo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSecondInstanceForwardsArgs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	exited := false
+	exitSecondInstance = func() { exited = true }
+	defer func() { exitSecondInstance = func() { os.Exit(0) } }()
+
+	opt := &VeloAppOpt{Mode: ModeHttp, AppName: "velo-instance-test", SingleInstance: true}
+	first := NewApp(opt)
+	if first.instance == nil || exited {
+		t.Fatal("first launch did not take the lock")
+	}
+	second := NewApp(opt)
+	if !exited || second.instance != nil {
+		t.Fatalf("second launch exited = %v, holds lock = %v", exited, second.instance != nil)
+	}
+
+	// The launch was forwarded before a handler was registered; it waits
+	// for one, as Run starts the queue.
+	first.instance.launches.start()
+	got := make(chan instanceMessage, 1)
+	first.OnSecondInstance(func(args []string, cwd string) {
+		got <- instanceMessage{Args: args, Cwd: cwd}
+	})
+	cwd, _ := os.Getwd()
+	select {
+	case msg := <-got:
+		if !reflect.DeepEqual(msg.Args, os.Args[1:]) || msg.Cwd != cwd {
+			t.Fatalf("forwarded %+v, want args %v in %s", msg, os.Args[1:], cwd)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnSecondInstance not called")
+	}
+
+	if err := first.shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	exited = false
+	third := NewApp(opt)
+	if exited || third.instance == nil {
+		t.Fatal("launch after shutdown did not take the lock")
+	}
+	third.instance.close()
+}
+
+func TestAcquireInstanceTakesOverStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), instanceSocketName)
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	lock, first, err := acquireInstance(path, nil, "")
+	if err != nil || !first || lock == nil {
+		t.Fatalf("acquireInstance = %v, %v, %v", lock, first, err)
+	}
+	lock.close()
+}
+
+func TestConcurrentTakeoverElectsOneInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), instanceSocketName)
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		locks []*instanceLock
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, first, err := acquireInstance(path, nil, "")
+			if err != nil {
+				t.Errorf("acquireInstance: %v", err)
+				return
+			}
+			if !first {
+				return
+			}
+			go lock.serve(func(instanceMessage) {})
+			mu.Lock()
+			locks = append(locks, lock)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for _, lock := range locks {
+		lock.close()
+	}
+	if len(locks) != 1 {
+		t.Fatalf("%d launches became the first instance, want 1", len(locks))
+	}
+}
@@ -0,0 +1,188 @@
+// Package jsontype describes Go types the way encoding/json encodes them,
+// for the generators that turn handler types into TypeScript and OpenAPI.
+package jsontype
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	TimeType       = reflect.TypeOf(time.Time{})
+	RawMessageType = reflect.TypeOf(json.RawMessage{})
+	BytesType      = reflect.TypeOf([]byte{})
+
+	enumType          = reflect.TypeOf((*Enum)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Enum is implemented by named types whose values form a fixed set.
+type Enum interface {
+	EnumValues() []interface{}
+}
+
+// EnumValues returns the values of a named type implementing Enum.
+func EnumValues(t reflect.Type) ([]interface{}, bool) {
+	if t.Name() == "" || !t.Implements(enumType) {
+		return nil, false
+	}
+	return reflect.Zero(t).Interface().(Enum).EnumValues(), true
+}
+
+// Marshaler reports how a type with custom JSON encoding is encoded: "text"
+// for encoding.TextMarshaler, "json" for json.Marshaler, "" otherwise.
+func Marshaler(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface {
+		return ""
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return "json"
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return "text"
+	}
+	return ""
+}
+
+// Field is a struct field as encoded by encoding/json.
+type Field struct {
+	Name string
+	Type reflect.Type
+	// OmitEmpty is set for omitempty and omitzero fields.
+	OmitEmpty bool
+	// String is set by the ",string" option.
+	String bool
+	// Enum holds the values of an `enum:"a,b"` tag, as strings for string
+	// fields and json.Number otherwise.
+	Enum []interface{}
+}
+
+// Fields returns the encoded fields of the struct type t, promoting the
+// fields of embedded structs.
+func Fields(t reflect.Type) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, Fields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		field := Field{
+			Name:      name,
+			Type:      f.Type,
+			OmitEmpty: hasOption(opts, "omitempty") || hasOption(opts, "omitzero"),
+			String:    hasOption(opts, "string"),
+		}
+		if tag := f.Tag.Get("enum"); tag != "" {
+			field.Enum = enumTag(tag, f.Type)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func enumTag(tag string, t reflect.Type) []interface{} {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	var values []interface{}
+	for _, v := range strings.Split(tag, ",") {
+		v = strings.TrimSpace(v)
+		if t.Kind() == reflect.String {
+			values = append(values, v)
+		} else {
+			values = append(values, json.Number(v))
+		}
+	}
+	return values
+}
+
+var (
+	qualifierPattern = regexp.MustCompile(`[\w./-]*\.`)
+	nonIdentPattern  = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// Namer assigns unique exported names to named Go types.
+type Namer struct {
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+func NewNamer() *Namer {
+	return &Namer{
+		names: make(map[reflect.Type]string),
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// Lookup returns the name already assigned to t.
+func (n *Namer) Lookup(t reflect.Type) (string, bool) {
+	name, ok := n.names[t]
+	return name, ok
+}
+
+// Name assigns a name to t: its Go name with type arguments reduced to
+// their names, prefixed with the package name when another type has it.
+func (n *Namer) Name(t reflect.Type) string {
+	if name, ok := n.names[t]; ok {
+		return name
+	}
+	base := Export(qualifierPattern.ReplaceAllString(t.Name(), ""))
+	name := base
+	if other, ok := n.types[name]; ok && other != t {
+		pkg := t.PkgPath()
+		if i := strings.LastIndex(pkg, "/"); i >= 0 {
+			pkg = pkg[i+1:]
+		}
+		name = Export(pkg) + base
+		for i := 2; n.types[name] != nil; i++ {
+			name = Export(pkg) + base + strconv.Itoa(i)
+		}
+	}
+	n.names[t] = name
+	n.types[name] = t
+	return name
+}
+
+// Export strips the characters that are not valid in an identifier and
+// upper-cases the first letter.
+func Export(s string) string {
+	s = nonIdentPattern.ReplaceAllString(s, "")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
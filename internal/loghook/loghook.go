@@ -0,0 +1,11 @@
+// Package loghook holds the loggers of packages that the app sets up but
+// does not import, so that wiring them does not link their native code.
+package loghook
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// Tray is the logger of the tray package.
+var Tray atomic.Pointer[slog.Logger]
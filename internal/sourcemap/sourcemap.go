@@ -0,0 +1,163 @@
+// Package sourcemap decodes version 3 source maps and maps positions in
+// generated JavaScript back to the original sources.
+package sourcemap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Map is a decoded source map.
+type Map struct {
+	sources []string
+	names   []string
+	lines   [][]segment
+}
+
+// segment maps a generated column to a source position. src is -1 for
+// segments that have no source.
+type segment struct {
+	col     int
+	src     int
+	srcLine int
+	srcCol  int
+	name    int
+}
+
+// Position is a place in an original source. Line and Column are 1-based
+// like the positions in JavaScript stack traces.
+type Position struct {
+	Source string
+	Line   int
+	Column int
+	Name   string
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.Source, p.Line, p.Column)
+}
+
+// Parse decodes a version 3 source map. Index maps with sections are not
+// supported.
+func Parse(data []byte) (*Map, error) {
+	var raw struct {
+		Version    int               `json:"version"`
+		SourceRoot string            `json:"sourceRoot"`
+		Sources    []string          `json:"sources"`
+		Names      []string          `json:"names"`
+		Mappings   string            `json:"mappings"`
+		Sections   []json.RawMessage `json:"sections"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", raw.Version)
+	}
+	if raw.Sections != nil {
+		return nil, errors.New("index source maps are not supported")
+	}
+	m := &Map{names: raw.Names, sources: make([]string, len(raw.Sources))}
+	for i, s := range raw.Sources {
+		if raw.SourceRoot != "" && !strings.Contains(s, "://") && !path.IsAbs(s) {
+			s = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + s
+		}
+		m.sources[i] = s
+	}
+	if err := m.decode(raw.Mappings); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Map) decode(mappings string) error {
+	var src, srcLine, srcCol, name int
+	for _, line := range strings.Split(mappings, ";") {
+		var segs []segment
+		col := 0
+		for _, field := range strings.Split(line, ",") {
+			if field == "" {
+				continue
+			}
+			values, err := decodeVLQ(field)
+			if err != nil {
+				return err
+			}
+			col += values[0]
+			seg := segment{col: col, src: -1, name: -1}
+			switch len(values) {
+			case 1:
+			case 4, 5:
+				src += values[1]
+				srcLine += values[2]
+				srcCol += values[3]
+				if src < 0 || src >= len(m.sources) {
+					return fmt.Errorf("source index %d out of range", src)
+				}
+				seg.src, seg.srcLine, seg.srcCol = src, srcLine, srcCol
+				if len(values) == 5 {
+					name += values[4]
+					seg.name = name
+				}
+			default:
+				return fmt.Errorf("invalid mapping segment %q", field)
+			}
+			segs = append(segs, seg)
+		}
+		sort.SliceStable(segs, func(i, j int) bool { return segs[i].col < segs[j].col })
+		m.lines = append(m.lines, segs)
+	}
+	return nil
+}
+
+// Lookup returns the original position of the 1-based line and column of
+// the generated file.
+func (m *Map) Lookup(line, column int) (Position, bool) {
+	if line < 1 || line > len(m.lines) {
+		return Position{}, false
+	}
+	segs := m.lines[line-1]
+	col := column - 1
+	i := sort.Search(len(segs), func(i int) bool { return segs[i].col > col }) - 1
+	if i < 0 || segs[i].src < 0 {
+		return Position{}, false
+	}
+	seg := segs[i]
+	p := Position{Source: m.sources[seg.src], Line: seg.srcLine + 1, Column: seg.srcCol + 1}
+	if seg.name >= 0 && seg.name < len(m.names) {
+		p.Name = m.names[seg.name]
+	}
+	return p, true
+}
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+func decodeVLQ(s string) ([]int, error) {
+	var values []int
+	var value, shift int
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(base64Chars, s[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base64 digit %q in mapping", s[i])
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated mapping segment %q", s)
+	}
+	return values, nil
+}
@@ -0,0 +1,45 @@
+package sourcemap
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	m, err := Parse([]byte(`{
+		"version": 3,
+		"sourceRoot": "webpack:///",
+		"sources": ["src/App.tsx"],
+		"names": ["render"],
+		"mappings": "AAAA,IAAIA;AACA;;"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		line, col int
+		want      string
+		name      string
+	}{
+		{1, 1, "webpack:///src/App.tsx:1:1", ""},
+		{1, 6, "webpack:///src/App.tsx:1:5", "render"},
+		{2, 10, "webpack:///src/App.tsx:2:5", ""},
+	} {
+		p, ok := m.Lookup(tc.line, tc.col)
+		if !ok || p.String() != tc.want || p.Name != tc.name {
+			t.Fatalf("Lookup(%d, %d) = %+v, %v; want %s %q", tc.line, tc.col, p, ok, tc.want, tc.name)
+		}
+	}
+	if _, ok := m.Lookup(3, 1); ok {
+		t.Fatal("Lookup of an unmapped line succeeded")
+	}
+}
+
+func TestParseRejectsInvalidMaps(t *testing.T) {
+	for _, data := range []string{
+		`{"version": 2, "mappings": ""}`,
+		`{"version": 3, "sources": [], "mappings": "AACA"}`,
+		`{"version": 3, "sources": ["a.js"], "mappings": "A!"}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Fatalf("Parse(%s) succeeded", data)
+		}
+	}
+}
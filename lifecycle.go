@@ -0,0 +1,507 @@
+package velo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ltaoo/velo/webview"
+)
+
+const (
+	// DefaultHookTimeout bounds a single lifecycle hook, and the time the
+	// frontend gets to answer a quit request.
+	DefaultHookTimeout = 5 * time.Second
+	// DefaultShutdownTimeout bounds the whole shutdown when quitting is
+	// triggered by a signal or by the last window closing.
+	DefaultShutdownTimeout = 10 * time.Second
+
+	veloQuitGuardMethod = "/__velo/lifecycle/guard"
+	veloQuitReplyMethod = "/__velo/lifecycle/reply"
+	veloBeforeQuitType  = "__velo_before_quit"
+)
+
+// ErrQuitVetoed is returned by Box.Quit when an OnBeforeQuit hook or the
+// frontend refuses to quit.
+var ErrQuitVetoed = errors.New("velo: quit vetoed")
+
+type lifecycleState int
+
+const (
+	lifecycleRunning lifecycleState = iota
+	lifecycleAsking
+	lifecycleStopping
+	lifecycleStopped
+)
+
+type quitReply struct {
+	page  string
+	allow bool
+}
+
+// lifecycle holds the hooks and shutdown state of a Box. It also counts the
+// handlers in flight so that shutdown can wait for them.
+type lifecycle struct {
+	mu              sync.Mutex
+	state           lifecycleState
+	hookTimeout     time.Duration
+	shutdownTimeout time.Duration
+	startup         []func(ctx context.Context) error
+	beforeQuit      []func(ctx context.Context) bool
+	shutdown        []func(ctx context.Context) error
+	// asked is closed when the current quit request has been confirmed or
+	// vetoed; done is closed when shutdown has finished, with err set.
+	asked  chan struct{}
+	done   chan struct{}
+	err    error
+	active int
+	idle   chan struct{}
+	// guards are the frontend pages that registered an onBeforeQuit
+	// handler; asks are the quit requests waiting for their replies.
+	guards map[string]bool
+	asks   map[string]chan quitReply
+}
+
+func newLifecycle(hookTimeout, shutdownTimeout time.Duration) *lifecycle {
+	if hookTimeout <= 0 {
+		hookTimeout = DefaultHookTimeout
+	}
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+	return &lifecycle{
+		hookTimeout:     hookTimeout,
+		shutdownTimeout: shutdownTimeout,
+		done:            make(chan struct{}),
+		guards:          make(map[string]bool),
+		asks:            make(map[string]chan quitReply),
+	}
+}
+
+// OnStartup registers a hook that runs when Run starts, before the HTTP
+// server is started or the first window opens. Hooks run in the order they
+// were registered; an error stops Run.
+func (b *Box) OnStartup(fn func(ctx context.Context) error) {
+	b.lifecycle.mu.Lock()
+	b.lifecycle.startup = append(b.lifecycle.startup, fn)
+	b.lifecycle.mu.Unlock()
+}
+
+// OnBeforeQuit registers a hook that is asked before Quit shuts the app
+// down. Returning false keeps the app running and makes Quit return
+// ErrQuitVetoed. With QuitOnLastWindowClosed, closing the last window asks
+// it too, before the window is destroyed; a veto keeps the window open.
+func (b *Box) OnBeforeQuit(fn func(ctx context.Context) bool) {
+	b.lifecycle.mu.Lock()
+	b.lifecycle.beforeQuit = append(b.lifecycle.beforeQuit, fn)
+	b.lifecycle.mu.Unlock()
+}
+
+// OnShutdown registers a hook that runs during shutdown, after running
+// handlers have finished and WebSocket clients are closed, but before Box.DB
+// is closed and the Store flushed. Hooks run in the order they were
+// registered, each bounded by VeloAppOpt.HookTimeout.
+func (b *Box) OnShutdown(fn func(ctx context.Context) error) {
+	b.lifecycle.mu.Lock()
+	b.lifecycle.shutdown = append(b.lifecycle.shutdown, fn)
+	b.lifecycle.mu.Unlock()
+}
+
+// Quit asks the OnBeforeQuit hooks and the frontend whether the app may
+// quit, then shuts it down: new calls are refused, running handlers are
+// drained, the HTTP server and WebSocket clients are closed, OnShutdown hooks
+// run, Box.DB is closed and the Store flushed. Finally the windows are closed
+// and Run returns. ctx bounds the whole sequence.
+//
+// Quit waits for running handlers, so a handler that quits the app should
+// call it from a new goroutine.
+func (b *Box) Quit(ctx context.Context) error {
+	l := b.lifecycle
+	l.mu.Lock()
+	switch l.state {
+	case lifecycleRunning:
+		l.state = lifecycleAsking
+		l.asked = make(chan struct{})
+	case lifecycleAsking:
+		asked := l.asked
+		l.mu.Unlock()
+		select {
+		case <-asked:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+		vetoed := l.state == lifecycleRunning
+		l.mu.Unlock()
+		if vetoed {
+			return ErrQuitVetoed
+		}
+		return b.waitShutdown(ctx)
+	default:
+		l.mu.Unlock()
+		return b.waitShutdown(ctx)
+	}
+	asked := l.asked
+	l.mu.Unlock()
+
+	ok := b.confirmQuit(ctx)
+	l.mu.Lock()
+	if !ok && l.state == lifecycleAsking {
+		l.state = lifecycleRunning
+	}
+	close(asked)
+	l.mu.Unlock()
+	if !ok {
+		return ErrQuitVetoed
+	}
+
+	err := b.shutdown(ctx)
+	if b.mode != ModeHttp {
+		webview.Quit()
+	}
+	return err
+}
+
+func (b *Box) confirmQuit(ctx context.Context) bool {
+	l := b.lifecycle
+	l.mu.Lock()
+	hooks := append([]func(context.Context) bool(nil), l.beforeQuit...)
+	l.mu.Unlock()
+	for _, fn := range hooks {
+		allow := true
+		err := l.runHook(ctx, func(ctx context.Context) error {
+			allow = fn(ctx)
+			return nil
+		})
+		if err != nil {
+			b.Log("lifecycle").Warn("before quit hook failed", "error", err)
+			continue
+		}
+		if !allow {
+			return false
+		}
+	}
+	return b.askFrontend(ctx)
+}
+
+// askFrontend sends a before-quit message to the pages that registered an
+// onBeforeQuit handler and waits for their answers. Pages that do not answer
+// within the hook timeout do not block quitting.
+func (b *Box) askFrontend(ctx context.Context) bool {
+	l := b.lifecycle
+	l.mu.Lock()
+	if len(l.guards) == 0 {
+		l.mu.Unlock()
+		return true
+	}
+	pending := make(map[string]bool, len(l.guards))
+	for page := range l.guards {
+		pending[page] = true
+	}
+	id := generateID()
+	replies := make(chan quitReply, len(pending))
+	l.asks[id] = replies
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.asks, id)
+		l.mu.Unlock()
+	}()
+
+	b.SendMessage(H{"type": veloBeforeQuitType, "id": id})
+
+	ctx, cancel := context.WithTimeout(ctx, l.hookTimeout)
+	defer cancel()
+	for len(pending) > 0 {
+		select {
+		case r := <-replies:
+			if !r.allow {
+				return false
+			}
+			delete(pending, r.page)
+		case <-ctx.Done():
+			return true
+		}
+	}
+	return true
+}
+
+func (l *lifecycle) setGuard(page string, active bool) {
+	if page == "" {
+		return
+	}
+	l.mu.Lock()
+	if active {
+		l.guards[page] = true
+	} else {
+		delete(l.guards, page)
+	}
+	l.mu.Unlock()
+}
+
+func (l *lifecycle) reply(id string, r quitReply) {
+	l.mu.Lock()
+	replies := l.asks[id]
+	l.mu.Unlock()
+	if replies == nil {
+		return
+	}
+	select {
+	case replies <- r:
+	default:
+	}
+}
+
+// shutdown stops the app without asking for confirmation. Concurrent calls
+// wait for the first one to finish.
+func (b *Box) shutdown(ctx context.Context) error {
+	l := b.lifecycle
+	l.mu.Lock()
+	if l.state == lifecycleStopping || l.state == lifecycleStopped {
+		l.mu.Unlock()
+		return b.waitShutdown(ctx)
+	}
+	l.state = lifecycleStopping
+	l.mu.Unlock()
+
+	var errs []error
+	if err := l.drain(ctx); err != nil {
+		b.inflight.cancelAll()
+		errs = append(errs, fmt.Errorf("drain handlers: %w", err))
+	}
+
+	b.listenMu.Lock()
+	server, ln := b.server, b.listener
+	b.listenMu.Unlock()
+	if server != nil {
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			errs = append(errs, fmt.Errorf("http server: %w", err))
+		}
+	} else if ln != nil {
+		ln.Close()
+	}
+	if b.wsHub != nil {
+		b.wsHub.closeAll()
+	}
+
+	l.mu.Lock()
+	hooks := append([]func(context.Context) error(nil), l.shutdown...)
+	l.mu.Unlock()
+	for _, fn := range hooks {
+		if err := l.runHook(ctx, fn); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook: %w", err))
+		}
+	}
+
+	if b.DB != nil {
+		if db, err := b.DB.DB(); err == nil {
+			if err := db.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close database: %w", err))
+			}
+		}
+	}
+	if b.Store != nil {
+		if err := b.Store.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("flush store: %w", err))
+		}
+	}
+	// Release the single-instance lock last, so that a new launch cannot
+	// open the storage and database before they are closed.
+	b.instance.close()
+
+	err := errors.Join(errs...)
+	l.mu.Lock()
+	l.state = lifecycleStopped
+	l.err = err
+	close(l.done)
+	l.mu.Unlock()
+	return err
+}
+
+func (b *Box) waitShutdown(ctx context.Context) error {
+	l := b.lifecycle
+	select {
+	case <-l.done:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startup runs the OnStartup hooks in order and stops at the first error.
+func (b *Box) startup() error {
+	l := b.lifecycle
+	l.mu.Lock()
+	hooks := append([]func(context.Context) error(nil), l.startup...)
+	l.mu.Unlock()
+	for _, fn := range hooks {
+		if err := l.runHook(context.Background(), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish shuts the app down once Run's server or window loop has returned.
+func (b *Box) finish() {
+	ctx, cancel := context.WithTimeout(context.Background(), b.lifecycle.shutdownTimeout)
+	defer cancel()
+	if err := b.shutdown(ctx); err != nil {
+		b.Log("lifecycle").Error("shutdown failed", "error", err)
+	}
+	if b.logFile != nil {
+		b.logFile.Close()
+	}
+}
+
+// runHook runs fn with its own deadline. A hook that ignores its context is
+// abandoned once the deadline passes so that it cannot block quitting.
+func (l *lifecycle) runHook(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, l.hookTimeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- fn(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enter registers a running handler. It returns false once shutdown has
+// started, in which case the call must be refused.
+func (l *lifecycle) enter() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.state == lifecycleStopping || l.state == lifecycleStopped {
+		return false
+	}
+	l.active++
+	return true
+}
+
+func (l *lifecycle) leave() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if l.active == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+}
+
+// drain waits until every running handler has returned.
+func (l *lifecycle) drain(ctx context.Context) error {
+	l.mu.Lock()
+	if l.active == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.idle == nil {
+		l.idle = make(chan struct{})
+	}
+	idle := l.idle
+	l.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// quitRequested handles the user closing the last window when
+// QuitOnLastWindowClosed is set. The backend keeps the window open until the
+// OnBeforeQuit hooks and the frontend have agreed to quit.
+func (b *Box) quitRequested(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.lifecycle.shutdownTimeout)
+	defer cancel()
+	if err := b.Quit(ctx); errors.Is(err, ErrQuitVetoed) {
+		b.Log("lifecycle").Info("closing the last window was vetoed", "window", name)
+	} else if err != nil {
+		b.Log("lifecycle").Error("quit failed", "error", err)
+	}
+}
+
+// handleSignals quits the app on SIGINT or SIGTERM. A second signal skips
+// the OnBeforeQuit hooks and the frontend, a third exits immediately.
+func (b *Box) handleSignals() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stop := make(chan struct{})
+	go func() {
+		count := 0
+		for {
+			select {
+			case <-stop:
+				return
+			case sig := <-signals:
+				count++
+				switch count {
+				case 1:
+					b.Log("lifecycle").Info("quitting on signal", "signal", sig.String())
+					go func() {
+						ctx, cancel := context.WithTimeout(context.Background(), b.lifecycle.shutdownTimeout)
+						defer cancel()
+						if err := b.Quit(ctx); errors.Is(err, ErrQuitVetoed) {
+							b.Log("lifecycle").Warn("quit vetoed; send the signal again to force it")
+						} else if err != nil {
+							b.Log("lifecycle").Error("quit failed", "error", err)
+						}
+					}()
+				case 2:
+					b.Log("lifecycle").Warn("forcing shutdown on second signal", "signal", sig.String())
+					go func() {
+						b.finish()
+						if b.mode != ModeHttp {
+							webview.Quit()
+						}
+					}()
+				default:
+					os.Exit(1)
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(stop)
+	}
+}
+
+func (b *Box) registerLifecycleRoutes() {
+	b.Post(veloQuitGuardMethod, func(c *BoxContext) interface{} {
+		var body struct {
+			Page   string `json:"page"`
+			Active bool   `json:"active"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			return ErrBadRequest.Wrap(err)
+		}
+		b.lifecycle.setGuard(body.Page, body.Active)
+		return c.Ok(nil)
+	})
+	b.Post(veloQuitReplyMethod, func(c *BoxContext) interface{} {
+		var body struct {
+			ID    string `json:"id"`
+			Page  string `json:"page"`
+			Allow bool   `json:"allow"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			return ErrBadRequest.Wrap(err)
+		}
+		b.lifecycle.reply(body.ID, quitReply{page: body.Page, allow: body.Allow})
+		return c.Ok(nil)
+	})
+}
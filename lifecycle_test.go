@@ -0,0 +1,149 @@
+package velo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuitRunsHooksInOrderAndRefusesNewCalls(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	var calls []string
+	app.OnBeforeQuit(func(ctx context.Context) bool {
+		calls = append(calls, "before")
+		return true
+	})
+	app.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	app.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "second")
+		return errors.New("flush failed")
+	})
+	app.Get("/api/ping", func(c *BoxContext) interface{} { return c.Ok("pong") })
+
+	err := app.Quit(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "flush failed") {
+		t.Fatalf("Quit error = %v, want the shutdown hook error", err)
+	}
+	if got := strings.Join(calls, ","); got != "before,first,second" {
+		t.Fatalf("calls = %s", got)
+	}
+
+	_, raw := app.handleMessage(`{"id":"1","method":"/api/ping"}`)
+	var res BoxResult
+	json.Unmarshal([]byte(raw), &res)
+	if res.Code != CodeUnavailable {
+		t.Fatalf("call after quit = %s, want unavailable", raw)
+	}
+	if err := app.Quit(context.Background()); err == nil {
+		t.Fatal("second Quit should report the first shutdown result")
+	}
+}
+
+func TestQuitWaitsForRunningHandlers(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	app.Get("/api/slow", func(c *BoxContext) interface{} {
+		close(started)
+		<-release
+		return c.Ok(nil)
+	})
+	var shutdownAt, releasedAt time.Time
+	app.OnShutdown(func(ctx context.Context) error {
+		shutdownAt = time.Now()
+		return nil
+	})
+
+	go app.handleMessage(`{"id":"1","method":"/api/slow"}`)
+	<-started
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		releasedAt = time.Now()
+		close(release)
+	}()
+	if err := app.Quit(context.Background()); err != nil {
+		t.Fatalf("Quit: %v", err)
+	}
+	if shutdownAt.Before(releasedAt) {
+		t.Fatal("shutdown hooks ran before the running handler returned")
+	}
+}
+
+func TestHookTimeoutDoesNotBlockShutdown(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, HookTimeout: 20 * time.Millisecond})
+	app.OnShutdown(func(ctx context.Context) error {
+		select {}
+	})
+	done := make(chan error, 1)
+	go func() { done <- app.Quit(context.Background()) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Quit error = %v, want deadline exceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Quit blocked on a stuck hook")
+	}
+}
+
+func TestBeforeQuitVeto(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	allow := false
+	app.OnBeforeQuit(func(ctx context.Context) bool { return allow })
+	shutdown := false
+	app.OnShutdown(func(ctx context.Context) error {
+		shutdown = true
+		return nil
+	})
+
+	if err := app.Quit(context.Background()); !errors.Is(err, ErrQuitVetoed) {
+		t.Fatalf("Quit error = %v, want ErrQuitVetoed", err)
+	}
+	if shutdown {
+		t.Fatal("vetoed quit ran the shutdown hooks")
+	}
+	allow = true
+	if err := app.Quit(context.Background()); err != nil {
+		t.Fatalf("Quit after allowing: %v", err)
+	}
+	if !shutdown {
+		t.Fatal("shutdown hooks did not run")
+	}
+}
+
+func TestFrontendCanVetoQuit(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.handleMessage(`{"id":"1","method":"/__velo/lifecycle/guard","httpMethod":"POST","args":{"page":"editor","active":true}}`)
+
+	answer := func(allow bool) {
+		for {
+			app.lifecycle.mu.Lock()
+			var id string
+			for k := range app.lifecycle.asks {
+				id = k
+			}
+			app.lifecycle.mu.Unlock()
+			if id != "" {
+				args, _ := json.Marshal(H{"id": id, "page": "editor", "allow": allow})
+				app.handleMessage(`{"id":"2","method":"/__velo/lifecycle/reply","httpMethod":"POST","args":` + string(args) + `}`)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	go answer(false)
+	if err := app.Quit(context.Background()); !errors.Is(err, ErrQuitVetoed) {
+		t.Fatalf("Quit error = %v, want ErrQuitVetoed", err)
+	}
+	go answer(true)
+	if err := app.Quit(context.Background()); err != nil {
+		t.Fatalf("Quit: %v", err)
+	}
+}
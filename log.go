@@ -0,0 +1,67 @@
+package velo
+
+import (
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/ltaoo/velo/dir"
+	"github.com/ltaoo/velo/internal/loghook"
+	"github.com/ltaoo/velo/logging"
+	"github.com/ltaoo/velo/webview"
+	"github.com/rs/zerolog"
+)
+
+// logLevel is the level of the default logger: the configured level, or
+// debug while SetDebug is on.
+type logLevel slog.Level
+
+func (l logLevel) Level() slog.Level {
+	if debugMode.Load() {
+		return slog.LevelDebug
+	}
+	return slog.Level(l)
+}
+
+// useLogger sets up the logger of the app and routes the diagnostics of
+// the webview and tray packages through it. The tray's logger is set
+// through loghook so that apps without a tray do not link it.
+func (b *Box) useLogger(o *VeloAppOpt) {
+	if o.Logger != nil {
+		b.logger = o.Logger
+	} else {
+		var console io.Writer
+		if o.LogToConsole || debugMode.Load() {
+			console = os.Stderr
+		}
+		b.logger, b.logFile = logging.New(logging.Options{
+			Level:   logLevel(o.LogLevel),
+			File:    dir.New(b.appName).LogFile(),
+			Console: console,
+		})
+	}
+	webview.SetLogger(b.Log("webview"))
+	loghook.Tray.Store(b.Log("tray"))
+}
+
+// Logger returns the logger of the app, set with VeloAppOpt.Logger or
+// writing to dir.Dir.LogFile() by default.
+func (b *Box) Logger() *slog.Logger {
+	if b.logger == nil {
+		return slog.Default()
+	}
+	return b.logger
+}
+
+// Log returns the logger of the app for a subsystem, whose records carry
+// subsystem=name.
+func (b *Box) Log(subsystem string) *slog.Logger {
+	return logging.Subsystem(b.Logger(), subsystem)
+}
+
+// UpdaterLogger returns a zerolog logger, as taken by updater/api.NewUpdater,
+// that logs through the app's logger with subsystem=updater.
+func (b *Box) UpdaterLogger() *zerolog.Logger {
+	l := logging.Zerolog(b.Log("updater"))
+	return &l
+}
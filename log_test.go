@@ -0,0 +1,54 @@
+package velo
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ltaoo/velo/dir"
+	"github.com/ltaoo/velo/internal/loghook"
+)
+
+func TestLoggerOption(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, Logger: logger})
+	app.Get("/api/ping", func(c *BoxContext) interface{} {
+		c.Logger().Info("pinged")
+		return c.Ok(nil)
+	})
+	app.handleMessage(`{"id":"1","method":"/api/ping?x=1"}`)
+	app.UpdaterLogger().Info().Msg("checked")
+	loghook.Tray.Load().Warn("decode icon")
+
+	got := out.String()
+	for _, want := range []string{
+		"level=DEBUG msg=call subsystem=bridge method=/api/ping",
+		"level=INFO msg=pinged",
+		"level=INFO msg=checked subsystem=updater",
+		"level=WARN msg=\"decode icon\" subsystem=tray",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("log missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDefaultLoggerWritesLogFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, AppName: "velo-log-test"})
+	app.Log("http").Debug("request", "path", "/api/secret")
+	app.Log("http").Info("listening", "addr", "127.0.0.1:0")
+	app.finish()
+
+	data, err := os.ReadFile(dir.New("velo-log-test").LogFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if strings.Contains(got, "/api/secret") || !strings.Contains(got, "level=INFO msg=listening subsystem=http addr=127.0.0.1:0") {
+		t.Fatalf("log file:\n%s", got)
+	}
+}
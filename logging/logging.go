@@ -0,0 +1,117 @@
+// Package logging builds the log/slog logger of a Velo app: leveled
+// records written to a size-rotated file and optionally the console, with
+// a subsystem attribute per component and bridges for components that log
+// through other libraries.
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// SubsystemKey is the attribute naming the component that logged a record,
+// e.g. subsystem=http.
+const SubsystemKey = "subsystem"
+
+// Options configures New.
+type Options struct {
+	// Level is the minimum level logged; nil means slog.LevelInfo.
+	Level slog.Leveler
+	// File is the log file, rotated once it reaches MaxSize. Empty logs to
+	// Console only.
+	File string
+	// MaxSize is the size in bytes at which File is rotated; defaults to
+	// DefaultMaxSize.
+	MaxSize int64
+	// MaxBackups is the number of rotated files kept; defaults to
+	// DefaultMaxBackups.
+	MaxBackups int
+	// Console, when set, also receives the records, e.g. os.Stderr during
+	// development.
+	Console io.Writer
+}
+
+// New returns a logger writing text records to the file and console of opt.
+// The returned closer closes the log file; writes after Close reopen it.
+func New(opt Options) (*slog.Logger, io.Closer) {
+	level := opt.Level
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handlers []slog.Handler
+	var file *RotatingFile
+	if opt.File != "" {
+		file = NewRotatingFile(opt.File, opt.MaxSize, opt.MaxBackups)
+		handlers = append(handlers, slog.NewTextHandler(file, handlerOpts))
+	}
+	if opt.Console != nil {
+		handlers = append(handlers, slog.NewTextHandler(opt.Console, handlerOpts))
+	}
+	var closer io.Closer = nopCloser{}
+	if file != nil {
+		closer = file
+	}
+	return slog.New(Fanout(handlers...)), closer
+}
+
+// Subsystem returns l with the subsystem attribute set to name.
+func Subsystem(l *slog.Logger, name string) *slog.Logger {
+	return l.With(slog.String(SubsystemKey, name))
+}
+
+// Discard returns a logger that drops every record.
+func Discard() *slog.Logger {
+	return slog.New(Fanout())
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// Fanout returns a handler that passes each record to every handler that
+// is enabled for its level.
+func Fanout(handlers ...slog.Handler) slog.Handler {
+	return fanout(handlers)
+}
+
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	f := NewRotatingFile(path, 10, 2)
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"app.log":   "fourth\n",
+		"app.log.1": "third\n",
+		"app.log.2": "second\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil || string(data) != want {
+			t.Fatalf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("kept more than 2 backups: %v", err)
+	}
+
+	if _, err := f.Write([]byte("fifth\n")); err != nil {
+		t.Fatalf("write after close: %v", err)
+	}
+	f.Close()
+}
+
+func TestNewLevelsAndSubsystem(t *testing.T) {
+	var console bytes.Buffer
+	file := filepath.Join(t.TempDir(), "app.log")
+	l, closer := New(Options{Level: slog.LevelWarn, File: file, Console: &console})
+	Subsystem(l, "http").Info("hidden")
+	Subsystem(l, "http").Warn("shown", "addr", "127.0.0.1:0")
+	closer.Close()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{"file": string(data), "console": console.String()} {
+		if strings.Contains(out, "hidden") || !strings.Contains(out, "level=WARN msg=shown subsystem=http addr=127.0.0.1:0") {
+			t.Fatalf("%s output = %q", name, out)
+		}
+	}
+}
+
+func TestZerolog(t *testing.T) {
+	var out bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	z := Zerolog(Subsystem(l, "updater"))
+	z.Debug().Msg("hidden")
+	z.Warn().Str("source", "github").Int("attempt", 2).Msg("check failed")
+
+	got := out.String()
+	if strings.Contains(got, "hidden") || !strings.Contains(got, "level=WARN msg=\"check failed\" subsystem=updater attempt=2 source=github") {
+		t.Fatalf("output = %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+
+	"github.com/rs/zerolog"
+)
+
+// Zerolog returns a zerolog.Logger, as taken by the updater packages, whose
+// events are logged through l.
+func Zerolog(l *slog.Logger) zerolog.Logger {
+	return zerolog.New(zerologWriter{l})
+}
+
+type zerologWriter struct {
+	logger *slog.Logger
+}
+
+func (w zerologWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w zerologWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	ctx := context.Background()
+	slevel := slogLevel(level)
+	if !w.logger.Enabled(ctx, slevel) {
+		return len(p), nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		w.logger.Log(ctx, slevel, string(p))
+		return len(p), nil
+	}
+	msg, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	w.logger.LogAttrs(ctx, slevel, msg, attrs...)
+	return len(p), nil
+}
+
+func slogLevel(level zerolog.Level) slog.Level {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return slog.LevelDebug
+	case zerolog.WarnLevel:
+		return slog.LevelWarn
+	case zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,19 @@
+package velo
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain points HOME at a temporary directory so that the log files and
+// data dirs of the apps created by the tests stay out of the user's home.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "velo-test-home")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
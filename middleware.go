@@ -0,0 +1,87 @@
+package velo
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a Handler with cross-cutting behaviour such as
+// authorization, logging or timing. Middlewares run for every transport:
+// the native bridge, the WebSocket hub and plain HTTP.
+type Middleware func(Handler) Handler
+
+// Use appends global middlewares. They wrap every route, including routes
+// registered before Use is called, and run before per-route middlewares.
+// The first middleware passed is the outermost one.
+func (b *Box) Use(middlewares ...Middleware) {
+	b.middlewares = append(b.middlewares, middlewares...)
+}
+
+// chain wraps handler so that middlewares[0] runs first.
+func chain(handler Handler, middlewares []Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			handler = middlewares[i](handler)
+		}
+	}
+	return handler
+}
+
+// dispatch runs a matched route handler through the global middlewares.
+// Calls that arrive once the app has started shutting down are refused, and
+// a handler that panics returns ErrPanic instead of crashing the app.
+func (b *Box) dispatch(handler Handler, c *BoxContext) (result interface{}) {
+	if !b.lifecycle.enter() {
+		return c.Fail(ErrUnavailable.WithMessage("application is shutting down"))
+	}
+	defer b.lifecycle.leave()
+	defer func() {
+		if r := recover(); r != nil {
+			result = recovered(c, r)
+		}
+	}()
+	return chain(handler, b.middlewares)(c)
+}
+
+// recovered logs the panic r of the handler for c and returns its error
+// result. http.ErrAbortHandler keeps aborting the HTTP response.
+func recovered(c *BoxContext, r interface{}) string {
+	if r == http.ErrAbortHandler {
+		panic(r)
+	}
+	c.Logger().Error("handler panicked", "route", c.Route(), "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	return c.Fail(ErrPanic.WithMessage("handler panicked: %v", r))
+}
+
+// Recover returns a middleware that turns a panicking handler into an
+// ErrPanic result. Every handler is recovered by the app already; Recover
+// recovers at its place in the chain, so that the middlewares used before
+// it, such as Logger, see the ErrPanic result.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(c *BoxContext) (result interface{}) {
+			defer func() {
+				if r := recover(); r != nil {
+					result = recovered(c, r)
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// Logger returns a middleware that logs the route and duration of each
+// request at info level, and the requested path at debug level.
+func Logger() Middleware {
+	return func(next Handler) Handler {
+		return func(c *BoxContext) interface{} {
+			start := time.Now()
+			result := next(c)
+			c.Logger().Info("call", "route", c.Route(), "duration", time.Since(start))
+			c.Logger().Debug("call", "method", c.Method(), "route", c.Route())
+			return result
+		}
+	}
+}
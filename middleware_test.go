@@ -0,0 +1,109 @@
+package velo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareRunsInOrderOnEveryTransport(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(c *BoxContext) interface{} {
+				calls = append(calls, name)
+				return next(c)
+			}
+		}
+	}
+	app.Get("/api/notes/:id", func(c *BoxContext) interface{} {
+		calls = append(calls, "handler")
+		return c.Ok(c.Param("id"))
+	}, trace("route"))
+	// Global middlewares apply to routes registered before Use too.
+	app.Use(trace("outer"), trace("inner"))
+
+	want := "outer,inner,route,handler"
+
+	app.handleMessage(`{"id":"1","method":"/api/notes/1"}`)
+	if got := strings.Join(calls, ","); got != want {
+		t.Fatalf("bridge calls = %s, want %s", got, want)
+	}
+
+	calls = nil
+	server := newTestServer(t, app)
+	resp, err := http.Get(server.URL + "/api/notes/1")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if got := strings.Join(calls, ","); got != want {
+		t.Fatalf("HTTP calls = %s, want %s", got, want)
+	}
+}
+
+func TestMiddlewareCanShortCircuit(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Use(func(next Handler) Handler {
+		return func(c *BoxContext) interface{} {
+			if c.GetHeader("Authorization") != "secret" {
+				return c.Error("unauthorized")
+			}
+			return next(c)
+		}
+	})
+	app.Get("/api/private", func(c *BoxContext) interface{} {
+		return c.Ok("ok")
+	})
+
+	server := newTestServer(t, app)
+
+	for _, tc := range []struct {
+		auth string
+		want int
+	}{
+		{"", 100},
+		{"secret", 0},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/private", nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		var res BoxResult
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("unmarshal: %v; body=%s", err, body)
+		}
+		if res.Code != tc.want {
+			t.Errorf("auth %q: code = %d, want %d", tc.auth, res.Code, tc.want)
+		}
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Use(Recover())
+	app.Get("/api/boom", func(c *BoxContext) interface{} {
+		panic("boom")
+	})
+
+	id, result := app.handleMessage(`{"id":"req-1","method":"/api/boom"}`)
+	if id != "req-1" {
+		t.Fatalf("id = %q, want req-1", id)
+	}
+	var res BoxResult
+	if err := json.Unmarshal([]byte(result), &res); err != nil {
+		t.Fatalf("unmarshal: %v; result=%s", err, result)
+	}
+	if res.Code == 0 || !strings.Contains(res.Msg, "boom") {
+		t.Fatalf("result = %+v", res)
+	}
+}
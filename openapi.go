@@ -0,0 +1,52 @@
+package velo
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ltaoo/velo/openapi"
+)
+
+// VeloOpenAPIPath serves the OpenAPI document of the registered routes.
+const VeloOpenAPIPath = "/__velo/openapi.json"
+
+// OpenAPI describes the routes registered with Get, Post and Bind as an
+// OpenAPI 3.1 document. Typed handlers and bound services contribute request
+// and response schemas; the launch token is declared as the security scheme
+// when the API requires it.
+func (b *Box) OpenAPI() *openapi.Document {
+	var routes []openapi.Route
+	for _, r := range b.Routes() {
+		routes = append(routes, openapi.Route{
+			Method:   r.Method,
+			Path:     r.Path,
+			Request:  r.Request,
+			Response: r.Response,
+			Service:  r.Service,
+		})
+	}
+	opt := openapi.Options{Title: b.title}
+	if opt.Title == "" {
+		opt.Title = b.appName
+	}
+	if b.appConfig != nil {
+		opt.Version = b.appConfig.App.Version
+		opt.Description = b.appConfig.App.Description
+	}
+	if b.authRequired() {
+		opt.TokenHeader = VeloTokenHeader
+		opt.TokenQuery = VeloTokenParam
+	}
+	return openapi.Generate(routes, opt)
+}
+
+func (b *Box) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	doc := b.OpenAPI()
+	doc.Servers = []openapi.Server{{URL: "http://" + r.Host}}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
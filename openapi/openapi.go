@@ -0,0 +1,416 @@
+// Package openapi builds an OpenAPI 3.1 document from the routes registered
+// on a velo Box, with schemas for the request and response types of typed
+// handlers.
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/ltaoo/velo/internal/jsontype"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.1.0"
+
+// Route describes a registered route.
+type Route struct {
+	// Method is "GET" or "POST".
+	Method string
+	// Path is the registered pattern, e.g. "/api/notes/:id".
+	Path string
+	// Request and Response are the Go types of the route; nil when the
+	// handler is not typed.
+	Request  reflect.Type
+	Response reflect.Type
+	// Service is set for methods bound with Box.Bind and used as the tag.
+	Service string
+}
+
+// Options describes the API as a whole.
+type Options struct {
+	Title       string
+	Version     string
+	Description string
+	// Servers are the base URLs the API is reachable at.
+	Servers []string
+	// TokenHeader and TokenQuery name the launch token credentials. The
+	// document declares no security when both are empty.
+	TokenHeader string
+	TokenQuery  string
+}
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required,omitempty"`
+	Schema   Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]Schema         `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+// Schema is a JSON Schema object.
+type Schema map[string]interface{}
+
+const resultSchema = "BoxResult"
+
+// Generate builds the document for routes. Every JSON response is wrapped
+// in the BoxResult envelope; typed handlers describe its data field, and a
+// []byte result is documented as an octet-stream body.
+func Generate(routes []Route, opt Options) *Document {
+	g := &generator{namer: jsontype.NewNamer(), schemas: make(map[string]Schema)}
+	g.schemas[resultSchema] = Schema{
+		"type": "object",
+		"properties": map[string]Schema{
+			"code":    {"type": "integer"},
+			"msg":     {"type": "string"},
+			"data":    {},
+			"reason":  {"type": "string"},
+			"details": {},
+		},
+		"required": []string{"code", "msg"},
+	}
+	version := opt.Version
+	if version == "" {
+		version = "0.0.0"
+	}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: opt.Title, Version: version, Description: opt.Description},
+		Paths:   make(map[string]PathItem),
+	}
+	for _, s := range opt.Servers {
+		doc.Servers = append(doc.Servers, Server{URL: s})
+	}
+	for _, r := range routes {
+		path, params := pathTemplate(r.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = g.operation(r, params)
+	}
+	doc.Components.Schemas = g.schemas
+	if opt.TokenHeader != "" || opt.TokenQuery != "" {
+		doc.Components.SecuritySchemes = make(map[string]SecurityScheme)
+		if opt.TokenHeader != "" {
+			doc.Components.SecuritySchemes["veloToken"] = SecurityScheme{Type: "apiKey", Name: opt.TokenHeader, In: "header"}
+			doc.Security = append(doc.Security, map[string][]string{"veloToken": {}})
+		}
+		if opt.TokenQuery != "" {
+			doc.Components.SecuritySchemes["veloTokenQuery"] = SecurityScheme{Type: "apiKey", Name: opt.TokenQuery, In: "query"}
+			doc.Security = append(doc.Security, map[string][]string{"veloTokenQuery": {}})
+		}
+	}
+	return doc
+}
+
+type generator struct {
+	namer   *jsontype.Namer
+	schemas map[string]Schema
+}
+
+func (g *generator) operation(r Route, params []string) *Operation {
+	op := &Operation{
+		OperationID: operationID(r.Method, r.Path),
+		Responses:   make(map[string]Response),
+	}
+	if r.Service != "" {
+		op.Tags = []string{r.Service}
+	}
+
+	fields := make(map[string]jsontype.Field)
+	if r.Request != nil && r.Request.Kind() == reflect.Struct {
+		for _, f := range jsontype.Fields(r.Request) {
+			fields[f.Name] = f
+		}
+	}
+	for _, name := range params {
+		schema := Schema{"type": "string"}
+		if f, ok := fields[name]; ok {
+			schema = g.field(f)
+			delete(fields, name)
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+
+	if r.Method == "GET" {
+		// GET handlers bind their scalar fields from the query string.
+		if r.Request != nil && r.Request.Kind() == reflect.Struct {
+			for _, f := range jsontype.Fields(r.Request) {
+				if _, ok := fields[f.Name]; !ok || !scalar(f.Type) {
+					continue
+				}
+				op.Parameters = append(op.Parameters, Parameter{Name: f.Name, In: "query", Schema: g.field(f)})
+			}
+		}
+	} else if r.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schema(r.Request)}},
+		}
+	}
+
+	envelope := Schema{"$ref": ref(resultSchema)}
+	switch {
+	case r.Response == jsontype.BytesType:
+		op.Responses["200"] = Response{
+			Description: "Binary result",
+			Content: map[string]MediaType{
+				"application/octet-stream": {Schema: Schema{"type": "string", "format": "binary"}},
+			},
+		}
+	case r.Response != nil:
+		op.Responses["200"] = Response{
+			Description: "Success",
+			Content: map[string]MediaType{"application/json": {Schema: Schema{
+				"allOf": []Schema{envelope, {"properties": map[string]Schema{"data": g.schema(r.Response)}}},
+			}}},
+		}
+	default:
+		op.Responses["200"] = Response{
+			Description: "Result",
+			Content:     map[string]MediaType{"application/json": {Schema: envelope}},
+		}
+	}
+	op.Responses["default"] = Response{
+		Description: "Error result",
+		Content:     map[string]MediaType{"application/json": {Schema: envelope}},
+	}
+	return op
+}
+
+func scalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func ref(name string) string {
+	return "#/components/schemas/" + name
+}
+
+// schema returns the JSON Schema of t, adding the named types it uses to the
+// components.
+func (g *generator) schema(t reflect.Type) Schema {
+	if t == nil {
+		return Schema{}
+	}
+	if name, ok := g.namer.Lookup(t); ok {
+		return Schema{"$ref": ref(name)}
+	}
+	switch t {
+	case jsontype.TimeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case jsontype.BytesType:
+		return Schema{"type": "string", "contentEncoding": "base64"}
+	case jsontype.RawMessageType:
+		return Schema{}
+	}
+	if values, ok := jsontype.EnumValues(t); ok {
+		name := g.namer.Name(t)
+		g.schemas[name] = withEnum(kindSchema(t), values)
+		return Schema{"$ref": ref(name)}
+	}
+	switch jsontype.Marshaler(t) {
+	case "json":
+		return Schema{}
+	case "text":
+		return Schema{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := g.namer.Name(t)
+		g.schemas[name] = Schema{}
+		g.schemas[name] = g.object(t)
+		return Schema{"$ref": ref(name)}
+	}
+	return kindSchema(t)
+}
+
+func (g *generator) object(t reflect.Type) Schema {
+	properties := make(map[string]Schema)
+	var required []string
+	for _, f := range jsontype.Fields(t) {
+		properties[f.Name] = g.field(f)
+		if !f.OmitEmpty {
+			required = append(required, f.Name)
+		}
+	}
+	s := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *generator) field(f jsontype.Field) Schema {
+	switch {
+	case f.Enum != nil:
+		return enumField(f.Type, f.Enum)
+	case f.String:
+		return Schema{"type": "string"}
+	}
+	return g.schema(f.Type)
+}
+
+// enumField applies the pointers and slices of t to the values of an enum
+// tag.
+func enumField(t reflect.Type, values []interface{}) Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(enumField(t.Elem(), values))
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": enumField(t.Elem(), values)}
+	}
+	return withEnum(kindSchema(t), values)
+}
+
+func withEnum(s Schema, values []interface{}) Schema {
+	s["enum"] = values
+	return s
+}
+
+func nullable(s Schema) Schema {
+	if typ, ok := s["type"].(string); ok {
+		out := Schema{}
+		for k, v := range s {
+			out[k] = v
+		}
+		out["type"] = []string{typ, "null"}
+		return out
+	}
+	return Schema{"anyOf": []Schema{s, {"type": "null"}}}
+}
+
+func kindSchema(t reflect.Type) Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return Schema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return Schema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return Schema{"type": "number", "format": "double"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	}
+	return Schema{}
+}
+
+// pathTemplate turns ":id" and "*path" segments into OpenAPI "{id}"
+// templates and returns the parameter names.
+func pathTemplate(path string) (string, []string) {
+	segs := strings.Split(path, "/")
+	var params []string
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			params = append(params, seg[1:])
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// operationID derives an id from the method and path, e.g.
+// "POST /api/notes/:id" becomes postApiNotesById.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			b.WriteString("By")
+			seg = seg[1:]
+		}
+		upper := true
+		for _, r := range seg {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
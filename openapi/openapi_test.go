@@ -0,0 +1,117 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type state string
+
+func (state) EnumValues() []interface{} {
+	return []interface{}{"open", "closed"}
+}
+
+type item struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Note   *string  `json:"note,omitempty"`
+	State  state    `json:"state"`
+	Labels []string `json:"labels" enum:"red,blue"`
+}
+
+type listReq struct {
+	Page  int    `json:"page,omitempty"`
+	Query string `json:"q,omitempty"`
+}
+
+func generate(t *testing.T) map[string]interface{} {
+	t.Helper()
+	doc := Generate([]Route{
+		{Method: "GET", Path: "/api/items", Request: reflect.TypeOf(listReq{}), Response: reflect.TypeOf([]item{})},
+		{Method: "POST", Path: "/api/items/:id", Request: reflect.TypeOf(item{}), Response: reflect.TypeOf(item{})},
+		{Method: "GET", Path: "/api/items/:id/raw", Response: reflect.TypeOf([]byte{})},
+		{Method: "POST", Path: "/svc/Items/Purge", Service: "Items"},
+	}, Options{Title: "Items", TokenHeader: "X-Velo-Token"})
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out map[string]interface{}
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// lookup walks a decoded JSON document along keys.
+func lookup(t *testing.T, v interface{}, keys ...string) interface{} {
+	t.Helper()
+	for _, key := range keys {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			v = node[key]
+		case []interface{}:
+			var i int
+			json.Unmarshal([]byte(key), &i)
+			v = node[i]
+		default:
+			t.Fatalf("no %q in %v", keys, v)
+		}
+	}
+	return v
+}
+
+func TestGenerateDescribesOperations(t *testing.T) {
+	doc := generate(t)
+	if doc["openapi"] != Version || lookup(t, doc, "info", "title") != "Items" || lookup(t, doc, "info", "version") != "0.0.0" {
+		t.Fatalf("header = %v %v", doc["openapi"], doc["info"])
+	}
+
+	list := lookup(t, doc, "paths", "/api/items", "get").(map[string]interface{})
+	if list["operationId"] != "getApiItems" {
+		t.Fatalf("operationId = %v", list["operationId"])
+	}
+	if lookup(t, list, "parameters", "0", "name") != "page" || lookup(t, list, "parameters", "1", "in") != "query" {
+		t.Fatalf("query parameters = %v", list["parameters"])
+	}
+	data := lookup(t, list, "responses", "200", "content", "application/json", "schema", "allOf", "1", "properties", "data")
+	if lookup(t, data, "items", "$ref") != "#/components/schemas/Item" {
+		t.Fatalf("list data schema = %v", data)
+	}
+
+	update := lookup(t, doc, "paths", "/api/items/{id}", "post").(map[string]interface{})
+	if p := lookup(t, update, "parameters", "0").(map[string]interface{}); p["in"] != "path" || p["required"] != true || lookup(t, p, "schema", "type") != "integer" {
+		t.Fatalf("path parameter = %v", p)
+	}
+	if lookup(t, update, "requestBody", "content", "application/json", "schema", "$ref") != "#/components/schemas/Item" {
+		t.Fatalf("request body = %v", update["requestBody"])
+	}
+
+	raw := lookup(t, doc, "paths", "/api/items/{id}/raw", "get", "responses", "200", "content")
+	if _, ok := raw.(map[string]interface{})["application/octet-stream"]; !ok {
+		t.Fatalf("binary response = %v", raw)
+	}
+	if tags := lookup(t, doc, "paths", "/svc/Items/Purge", "post", "tags", "0"); tags != "Items" {
+		t.Fatalf("service tag = %v", tags)
+	}
+	if lookup(t, doc, "components", "securitySchemes", "veloToken", "name") != "X-Velo-Token" {
+		t.Fatalf("security = %v", lookup(t, doc, "components"))
+	}
+}
+
+func TestGenerateSchemasFollowStructTags(t *testing.T) {
+	doc := generate(t)
+	item := lookup(t, doc, "components", "schemas", "Item").(map[string]interface{})
+	required, _ := json.Marshal(item["required"])
+	if string(required) != `["id","name","state","labels"]` {
+		t.Fatalf("required = %s", required)
+	}
+	if got, _ := json.Marshal(lookup(t, item, "properties", "note", "type")); string(got) != `["string","null"]` {
+		t.Fatalf("nullable note = %s", got)
+	}
+	if got, _ := json.Marshal(lookup(t, item, "properties", "labels", "items", "enum")); string(got) != `["red","blue"]` {
+		t.Fatalf("labels enum = %s", got)
+	}
+	if got, _ := json.Marshal(lookup(t, doc, "components", "schemas", "State", "enum")); string(got) != `["open","closed"]` {
+		t.Fatalf("State enum = %s", got)
+	}
+}
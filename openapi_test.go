@@ -0,0 +1,55 @@
+package velo
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestServeOpenAPI(t *testing.T) {
+	app := newTypedTestApp()
+	server := newTestServer(t, app)
+
+	resp, err := http.Get(server.URL + VeloOpenAPIPath)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Servers    []struct{ URL string }                `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas         map[string]json.RawMessage `json:"schemas"`
+			SecuritySchemes map[string]json.RawMessage `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.OpenAPI == "" || len(doc.Servers) != 1 || doc.Servers[0].URL != server.URL {
+		t.Fatalf("document = %+v", doc)
+	}
+	if _, ok := doc.Paths["/api/notes/{id}"]["post"]; !ok {
+		t.Fatalf("paths = %v", doc.Paths)
+	}
+	for path := range doc.Paths {
+		if path == VeloOpenAPIPath || path == veloEventEmitMethod {
+			t.Fatalf("internal route %s documented", path)
+		}
+	}
+	if doc.Components.Schemas["CreateNoteReq"] == nil || doc.Components.SecuritySchemes["veloToken"] == nil {
+		t.Fatalf("components = %+v", doc.Components)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+VeloOpenAPIPath, nil)
+	req.Header.Set(VeloTokenHeader, "wrong")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET with bad token: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status with bad token = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,195 @@
+package velo
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// route is a handler registered under a pattern that contains path
+// parameters (":name") or a trailing wildcard ("*name"). Routes with a
+// literal pattern are looked up directly in Box.get_handlers/post_handlers.
+type route struct {
+	pattern  string
+	segments []string
+	handler  Handler
+}
+
+const (
+	segmentStatic = iota
+	segmentParam
+	segmentWildcard
+)
+
+func segmentKind(segment string) int {
+	switch {
+	case strings.HasPrefix(segment, ":"):
+		return segmentParam
+	case strings.HasPrefix(segment, "*"):
+		return segmentWildcard
+	default:
+		return segmentStatic
+	}
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
+
+// isDynamicPattern reports whether pattern captures path parameters.
+func isDynamicPattern(pattern string) bool {
+	for _, segment := range splitPath(pattern) {
+		if segmentKind(segment) != segmentStatic {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePattern panics on a pattern whose wildcard is not the last
+// segment, the way http.ServeMux rejects malformed patterns at
+// registration rather than silently never matching.
+func validatePattern(pattern string) {
+	segments := splitPath(pattern)
+	for i, segment := range segments {
+		if segmentKind(segment) == segmentWildcard && i != len(segments)-1 {
+			panic(fmt.Sprintf("velo: wildcard %q must be the last segment of pattern %q", segment, pattern))
+		}
+	}
+}
+
+// unescapeSegment decodes one escaped path segment, keeping it as is when
+// it is not valid percent-encoding.
+func unescapeSegment(segment string) string {
+	if s, err := url.PathUnescape(segment); err == nil {
+		return s
+	}
+	return segment
+}
+
+func newRoute(pattern string, handler Handler) *route {
+	return &route{
+		pattern:  pattern,
+		segments: splitPath(pattern),
+		handler:  handler,
+	}
+}
+
+// match reports whether path satisfies the route pattern and returns the
+// captured parameters. A wildcard segment swallows the rest of the path,
+// including slashes, and may be empty. path is the escaped path, so an
+// encoded "%2F" stays inside its segment; captured values are unescaped.
+func (r *route) match(path string) (map[string]string, bool) {
+	parts := splitPath(path)
+	params := make(map[string]string)
+	for i, segment := range r.segments {
+		switch segmentKind(segment) {
+		case segmentWildcard:
+			name := segment[1:]
+			if name == "" {
+				name = "*"
+			}
+			if i < len(parts) {
+				params[name] = unescapeSegment(strings.Join(parts[i:], "/"))
+			} else {
+				params[name] = ""
+			}
+			return params, true
+		case segmentParam:
+			if i >= len(parts) || parts[i] == "" {
+				return nil, false
+			}
+			params[segment[1:]] = unescapeSegment(parts[i])
+		default:
+			if i >= len(parts) || unescapeSegment(parts[i]) != segment {
+				return nil, false
+			}
+		}
+	}
+	if len(parts) != len(r.segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// muxPrefix returns the http.ServeMux subtree that covers every path the
+// route can match: the literal segments before the first parameter.
+func (r *route) muxPrefix() string {
+	var static []string
+	for _, segment := range r.segments {
+		if segmentKind(segment) != segmentStatic {
+			break
+		}
+		static = append(static, segment)
+	}
+	if len(static) == 0 {
+		return "/"
+	}
+	return "/" + strings.Join(static, "/") + "/"
+}
+
+// routeBefore orders routes so that the most specific pattern is tried
+// first: literal segments win over parameters, parameters over wildcards.
+func routeBefore(a, b *route) bool {
+	for i := 0; i < len(a.segments) && i < len(b.segments); i++ {
+		ka, kb := segmentKind(a.segments[i]), segmentKind(b.segments[i])
+		if ka != kb {
+			return ka < kb
+		}
+	}
+	return len(a.segments) > len(b.segments)
+}
+
+func addRoute(routes []*route, pattern string, handler Handler) []*route {
+	next := make([]*route, 0, len(routes)+1)
+	for _, r := range routes {
+		if r.pattern != pattern {
+			next = append(next, r)
+		}
+	}
+	next = append(next, newRoute(pattern, handler))
+	sort.SliceStable(next, func(i, j int) bool {
+		return routeBefore(next[i], next[j])
+	})
+	return next
+}
+
+// lookupRoute finds the handler for the escaped path (URL.EscapedPath)
+// among literal handlers and dynamic routes. It returns the matched pattern
+// and captured parameters.
+func lookupRoute(handlers map[string]Handler, routes []*route, escapedPath string) (Handler, string, map[string]string, bool) {
+	path := unescapeSegment(escapedPath)
+	if h, ok := handlers[path]; ok && !isDynamicPattern(path) {
+		return h, path, nil, true
+	}
+	for _, r := range routes {
+		if params, ok := r.match(escapedPath); ok {
+			return r.handler, r.pattern, params, true
+		}
+	}
+	return nil, "", nil, false
+}
+
+func (b *Box) lookupGet(path string) (Handler, string, map[string]string, bool) {
+	return lookupRoute(b.get_handlers, b.get_routes, path)
+}
+
+func (b *Box) lookupPost(path string) (Handler, string, map[string]string, bool) {
+	return lookupRoute(b.post_handlers, b.post_routes, path)
+}
+
+// lookupHandler resolves a bridge or WebSocket invocation. The requested
+// HTTP method picks which table is tried first; the other one is used as
+// a fallback so that a plain invoke() reaches POST-only routes too.
+func (b *Box) lookupHandler(httpMethod, path string) (Handler, string, map[string]string, bool) {
+	first, second := b.lookupGet, b.lookupPost
+	if httpMethod == http.MethodPost {
+		first, second = b.lookupPost, b.lookupGet
+	}
+	if h, pattern, params, ok := first(path); ok {
+		return h, pattern, params, true
+	}
+	return second(path)
+}
@@ -0,0 +1,145 @@
+package velo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRouteMatchesParamsAndWildcards(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    map[string]string
+		ok      bool
+	}{
+		{"/api/notes/:id", "/api/notes/42", map[string]string{"id": "42"}, true},
+		{"/api/notes/:id", "/api/notes/", nil, false},
+		{"/api/notes/:id", "/api/notes/42/tags", nil, false},
+		{"/api/notes/:id/tags/:tag", "/api/notes/1/tags/go", map[string]string{"id": "1", "tag": "go"}, true},
+		{"/api/files/*path", "/api/files/a/b/c.txt", map[string]string{"path": "a/b/c.txt"}, true},
+		{"/api/files/*path", "/api/files/", map[string]string{"path": ""}, true},
+		{"/api/files/*", "/api/files/x", map[string]string{"*": "x"}, true},
+		{"/api/files/*path", "/api/other/x", nil, false},
+		{"/api/notes/:id", "/api/notes/a%2Fb", map[string]string{"id": "a/b"}, true},
+		{"/api/notes/:id/tags", "/api/notes/a%2Fb/tags", map[string]string{"id": "a/b"}, true},
+		{"/api/files/*path", "/api/files/a%20b/c", map[string]string{"path": "a b/c"}, true},
+	}
+	for _, tc := range cases {
+		params, ok := newRoute(tc.pattern, nil).match(tc.path)
+		if ok != tc.ok {
+			t.Errorf("%s match %s = %v, want %v", tc.pattern, tc.path, ok, tc.ok)
+			continue
+		}
+		for k, v := range tc.want {
+			if params[k] != v {
+				t.Errorf("%s match %s: param %q = %q, want %q", tc.pattern, tc.path, k, params[k], v)
+			}
+		}
+	}
+}
+
+func TestNonTerminalWildcardIsRejected(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Get accepted a wildcard before the last segment")
+		}
+	}()
+	app.Get("/files/*path/edit", func(c *BoxContext) interface{} { return nil })
+}
+
+func TestRoutesPreferMostSpecificPattern(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Get("/api/notes/*rest", func(c *BoxContext) interface{} { return c.Ok("wildcard") })
+	app.Get("/api/notes/:id", func(c *BoxContext) interface{} { return c.Ok("param") })
+	app.Get("/api/notes/recent", func(c *BoxContext) interface{} { return c.Ok("static") })
+
+	for path, want := range map[string]string{
+		"/api/notes/recent": "static",
+		"/api/notes/7":      "param",
+		"/api/notes/7/tags": "wildcard",
+	} {
+		_, result := app.handleMessage(`{"id":"1","method":"` + path + `"}`)
+		var res BoxResult
+		if err := json.Unmarshal([]byte(result), &res); err != nil {
+			t.Fatalf("unmarshal %s: %v", path, err)
+		}
+		if res.Data != want {
+			t.Errorf("%s dispatched to %v, want %s", path, res.Data, want)
+		}
+	}
+}
+
+func TestPathParamsOnBridgeAndHTTP(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Get("/api/notes/:id", func(c *BoxContext) interface{} {
+		return c.Ok(H{"id": c.Param("id"), "route": c.Route(), "q": c.Query("q")})
+	})
+	app.Post("/api/files/*path", func(c *BoxContext) interface{} {
+		return c.Ok(H{"path": c.Param("path")})
+	})
+
+	_, result := app.handleMessage(`{"id":"1","method":"/api/notes/abc?q=x"}`)
+	var res struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(result), &res); err != nil {
+		t.Fatalf("unmarshal bridge result: %v", err)
+	}
+	if res.Data["id"] != "abc" || res.Data["route"] != "/api/notes/:id" || res.Data["q"] != "x" {
+		t.Fatalf("bridge data = %#v", res.Data)
+	}
+
+	server := newTestServer(t, app)
+
+	resp, err := http.Get(server.URL + "/api/notes/abc?q=x")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatalf("unmarshal HTTP result: %v; body=%s", err, body)
+	}
+	if res.Data["id"] != "abc" || res.Data["route"] != "/api/notes/:id" {
+		t.Fatalf("HTTP data = %#v", res.Data)
+	}
+
+	resp, err = http.Get(server.URL + "/api/notes/a%2Fb")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatalf("unmarshal HTTP result: %v; body=%s", err, body)
+	}
+	if res.Data["id"] != "a/b" {
+		t.Fatalf("HTTP escaped param = %#v", res.Data)
+	}
+
+	resp, err = http.Post(server.URL+"/api/files/docs/a.txt", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatalf("unmarshal HTTP result: %v; body=%s", err, body)
+	}
+	if res.Data["path"] != "docs/a.txt" {
+		t.Fatalf("HTTP wildcard data = %#v", res.Data)
+	}
+
+	resp, err = http.Get(server.URL + "/api/files/docs/a.txt")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET on POST-only route status = %d, want 405", resp.StatusCode)
+	}
+}
@@ -0,0 +1,87 @@
+package velo
+
+import (
+	"net"
+	"net/http"
+	"os"
+)
+
+// DefaultAddr is the HTTP listen address used when neither VeloAppOpt.Addr,
+// the VELO_ADDR environment variable nor velo.json's desktop.addr is set.
+const DefaultAddr = "127.0.0.1:8080"
+
+func resolveAddr(cfg *AppConfig, override string) string {
+	if override != "" {
+		return override
+	}
+	if env := os.Getenv("VELO_ADDR"); env != "" {
+		return env
+	}
+	if cfg != nil && cfg.Desktop.Addr != "" {
+		return cfg.Desktop.Addr
+	}
+	return DefaultAddr
+}
+
+// listen binds the HTTP listener once. Run calls it before the first window
+// opens, so that with port 0 the port chosen by the OS is in the window URL
+// and runtime config.
+func (b *Box) listen() (net.Listener, error) {
+	b.listenMu.Lock()
+	defer b.listenMu.Unlock()
+	if b.listener != nil {
+		return b.listener, nil
+	}
+	ln, err := net.Listen("tcp", b.addr)
+	if err != nil {
+		return nil, err
+	}
+	b.listener = ln
+	return ln, nil
+}
+
+// Addr returns the address the HTTP server listens on. Once the listener is
+// bound it reports the actual address, including a port picked for ":0".
+func (b *Box) Addr() string {
+	b.listenMu.Lock()
+	defer b.listenMu.Unlock()
+	if b.listener != nil {
+		return b.listener.Addr().String()
+	}
+	return b.addr
+}
+
+// httpBase returns the base URL of the HTTP server: the bound address once
+// Run has bound the listener, the configured one before. It never binds.
+func (b *Box) httpBase() string {
+	addr := b.Addr()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// serve runs the HTTP server on the bound listener until it stops.
+func (b *Box) serve(handler http.Handler) error {
+	ln, err := b.listen()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler}
+	b.listenMu.Lock()
+	b.server = server
+	b.listenMu.Unlock()
+	err = server.Serve(ln)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func usesHTTP(mode Mode) bool {
+	return mode == ModeHttp || mode == ModeBridgeHttp
+}
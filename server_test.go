@@ -0,0 +1,81 @@
+package velo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRandomPortFlowsIntoURLsAndRuntime(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeBridgeHttp, Addr: "127.0.0.1:0"})
+	t.Cleanup(func() {
+		if app.listener != nil {
+			app.listener.Close()
+		}
+	})
+
+	if info := app.runtimeInfo(nil); info.HTTPBase != "http://127.0.0.1:0" || app.listener != nil {
+		t.Fatalf("runtimeInfo bound the listener or reported %q", info.HTTPBase)
+	}
+	// Run binds the listener before the first window opens.
+	if !app.bindHTTP() {
+		t.Fatal("bind failed")
+	}
+	addr := app.Addr()
+	if strings.HasSuffix(addr, ":0") || !strings.HasPrefix(addr, "127.0.0.1:") {
+		t.Fatalf("Addr() = %q, want a bound 127.0.0.1 port", addr)
+	}
+
+	if got, want := app.webviewURL("", "/settings"), "http://"+addr+"/settings?"+VeloTokenParam+"="+app.Token(); got != want {
+		t.Fatalf("webviewURL = %q, want %q", got, want)
+	}
+	if info := app.runtimeInfo(nil); info.HTTPBase != "http://"+addr {
+		t.Fatalf("runtime http_base = %q, want http://%s", info.HTTPBase, addr)
+	}
+}
+
+func TestServeUsesConfiguredAddress(t *testing.T) {
+	cfg := &AppConfig{}
+	cfg.Desktop.Addr = "127.0.0.1:0"
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp, AppConfig: cfg})
+	app.Get("/api/ping", func(c *BoxContext) interface{} { return c.Ok("pong") })
+	app.mux = app.setupMux(nil, "")
+
+	if !app.bindHTTP() {
+		t.Fatal("bind failed")
+	}
+	go app.listenAndServe()
+	t.Cleanup(func() {
+		app.listenMu.Lock()
+		server := app.server
+		app.listenMu.Unlock()
+		if server != nil {
+			server.Close()
+		}
+	})
+
+	base := app.httpBase()
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		req, _ := http.NewRequest(http.MethodGet, base+"/api/ping", nil)
+		req.Header.Set(VeloTokenHeader, app.Token())
+		resp, err = http.DefaultClient.Do(req)
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("GET %s: %v", base, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var res BoxResult
+	if err := json.Unmarshal(body, &res); err != nil || res.Data != "pong" {
+		t.Fatalf("response = %s (%v)", body, err)
+	}
+}
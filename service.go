@@ -0,0 +1,115 @@
+package velo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// ServicePrefix is the path under which Bind registers service methods.
+const ServicePrefix = "/svc/"
+
+var (
+	contextType    = reflect.TypeOf((*context.Context)(nil)).Elem()
+	boxContextType = reflect.TypeOf((*BoxContext)(nil))
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Bind registers the exported methods of service as POST routes at
+// /svc/<Type>/<Method>, where Type is the name of the service's type. A
+// method is bound when its signature is one of
+//
+//	func(ctx) error
+//	func(ctx) (Resp, error)
+//	func(ctx, Req) error
+//	func(ctx, Req) (Resp, error)
+//
+// with ctx a context.Context or a *BoxContext; other methods are skipped.
+// Req and Resp are handled as in Typed. The routes go through the same
+// dispatcher as Get and Post, so they work in every Mode, and the frontend
+// calls them as velo.services.<Type>.<Method>(args), which resolves with the
+// result data.
+func (b *Box) Bind(service interface{}) error {
+	if service == nil {
+		return errors.New("velo: Bind of nil service")
+	}
+	v := reflect.ValueOf(service)
+	t := v.Type()
+	name := t.Name()
+	if t.Kind() == reflect.Pointer {
+		name = t.Elem().Name()
+	}
+	if name == "" {
+		return fmt.Errorf("velo: Bind of unnamed type %s", t)
+	}
+	var methods []string
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		h, types, ok := serviceHandler(v.Method(i))
+		if !ok {
+			continue
+		}
+		b.handle(http.MethodPost, ServicePrefix+name+"/"+m.Name, h, types, nil)
+		methods = append(methods, m.Name)
+	}
+	if len(methods) == 0 {
+		return fmt.Errorf("velo: %s has no methods to bind", t)
+	}
+	b.services[name] = methods
+	return nil
+}
+
+// serviceHandler adapts a bound method to a Handler and its request and
+// response types, reporting false when its signature is not one Bind
+// accepts.
+func serviceHandler(fn reflect.Value) (Handler, *routeTypes, bool) {
+	t := fn.Type()
+	if t.IsVariadic() || t.NumIn() < 1 || t.NumIn() > 2 || t.NumOut() < 1 || t.NumOut() > 2 {
+		return nil, nil, false
+	}
+	ctxType := t.In(0)
+	if ctxType != contextType && ctxType != boxContextType {
+		return nil, nil, false
+	}
+	if t.Out(t.NumOut()-1) != errorType {
+		return nil, nil, false
+	}
+	var reqType, respType reflect.Type
+	if t.NumIn() == 2 {
+		reqType = t.In(1)
+	}
+	if t.NumOut() == 2 {
+		respType = t.Out(0)
+	}
+
+	h := Handler(func(c *BoxContext) interface{} {
+		in := make([]reflect.Value, 0, 2)
+		if ctxType == boxContextType {
+			in = append(in, reflect.ValueOf(c))
+		} else {
+			ctx := c.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			in = append(in, reflect.ValueOf(&ctx).Elem())
+		}
+		if reqType != nil {
+			req := reflect.New(reqType)
+			if err := c.decodeRequest(req.Interface()); err != nil {
+				return c.Fail(err)
+			}
+			in = append(in, req.Elem())
+		}
+		out := fn.Call(in)
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return c.Fail(err)
+		}
+		if respType == nil {
+			return c.Ok(nil)
+		}
+		return c.respond(out[0].Interface())
+	})
+	return h, &routeTypes{Request: reqType, Response: respType}, true
+}
@@ -0,0 +1,94 @@
+package velo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type NoteService struct {
+	saved []createNoteReq
+}
+
+func (s *NoteService) Create(ctx context.Context, req createNoteReq) (createNoteResp, error) {
+	if ctx == nil {
+		return createNoteResp{}, errors.New("no context")
+	}
+	s.saved = append(s.saved, req)
+	return createNoteResp{ID: len(s.saved), Title: req.Title}, nil
+}
+
+func (s *NoteService) Count(c *BoxContext) (int, error) {
+	return len(s.saved), nil
+}
+
+func (s *NoteService) Clear(ctx context.Context) error {
+	return ErrForbidden.WithMessage("notes are read-only")
+}
+
+// Helper has no context parameter and is not bound.
+func (s *NoteService) Helper() string {
+	return "skipped"
+}
+
+func TestBindRegistersServiceMethods(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	svc := &NoteService{}
+	if err := app.Bind(svc); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	if got := app.runtimeInfo(nil).Services["NoteService"]; !reflect.DeepEqual(got, []string{"Clear", "Count", "Create"}) {
+		t.Fatalf("runtime services = %v", got)
+	}
+
+	_, raw := app.handleMessage(`{"id":"1","method":"/svc/NoteService/Create","args":{"title":"hello"}}`)
+	res, data := decodeTypedResult(t, raw)
+	if res.Code != CodeOK || data.ID != 1 || data.Title != "hello" {
+		t.Fatalf("Create = %s", raw)
+	}
+	_, raw = app.handleMessage(`{"id":"2","method":"/svc/NoteService/Create","args":{}}`)
+	if res, _ := decodeTypedResult(t, raw); res.Code != CodeValidation {
+		t.Fatalf("invalid Create = %s, want a validation error", raw)
+	}
+	_, raw = app.handleMessage(`{"id":"3","method":"/svc/NoteService/Clear"}`)
+	if res, _ := decodeTypedResult(t, raw); res.Code != CodeForbidden {
+		t.Fatalf("Clear = %s, want forbidden", raw)
+	}
+	_, raw = app.handleMessage(`{"id":"4","method":"/svc/NoteService/Helper"}`)
+	if res, _ := decodeTypedResult(t, raw); res.Code != CodeError || res.Reason != "unknown_method" {
+		t.Fatalf("Helper = %s, want unknown method", raw)
+	}
+
+	server := newTestServer(t, app)
+	resp, err := http.Post(server.URL+"/svc/NoteService/Count", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"data":1`) {
+		t.Fatalf("Count over HTTP = %s", body)
+	}
+
+	types := app.route_types["POST /svc/NoteService/Create"]
+	if types == nil || types.Request != reflect.TypeOf(createNoteReq{}) || types.Response != reflect.TypeOf(createNoteResp{}) {
+		t.Fatalf("route types = %+v", types)
+	}
+}
+
+func TestBindRejectsServicesWithoutMethods(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	if err := app.Bind(nil); err == nil {
+		t.Fatal("Bind(nil) succeeded")
+	}
+	if err := app.Bind(struct{}{}); err == nil {
+		t.Fatal("Bind of an unnamed type succeeded")
+	}
+	if err := app.Bind(createNoteReq{}); err == nil {
+		t.Fatal("Bind of a type without bindable methods succeeded")
+	}
+}
@@ -129,3 +129,12 @@ func (s *Store) Delete(key string) error {
 	delete(s.data.Config, key)
 	return s.save()
 }
+
+// Flush writes the current contents to disk. Writes already persist on every
+// change; Flush is called on shutdown so nothing is left behind if the file
+// was removed or a previous write failed.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
@@ -0,0 +1,127 @@
+package velo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ltaoo/velo/webview"
+)
+
+// callbackSink delivers an intermediate callback for invoke id. The WebSocket
+// hub installs one per client and windows one per window; calls without one
+// fall back to webview.SendCallback.
+type callbackSink func(id, result string) error
+
+type callbackSinkKey struct{}
+
+func withCallbackSink(ctx context.Context, sink callbackSink) context.Context {
+	return context.WithValue(ctx, callbackSinkKey{}, sink)
+}
+
+func callbackSinkFrom(ctx context.Context) callbackSink {
+	if sink, ok := ctx.Value(callbackSinkKey{}).(callbackSink); ok && sink != nil {
+		return sink
+	}
+	return func(id, result string) error {
+		webview.SendCallback(id, result)
+		return nil
+	}
+}
+
+// streamChunk is the callback payload for one streamed value. The runtime
+// recognises the marker and keeps the invoke pending until the final
+// BoxResult arrives under the same id.
+type streamChunk struct {
+	Stream bool        `json:"__velo_stream"`
+	Seq    int         `json:"seq"`
+	Data   interface{} `json:"data"`
+}
+
+// Stream runs fn and pushes every value passed to send to the caller as an
+// incremental chunk of this invocation. Over the bridge and WebSocket the
+// chunks share the invoke id and the return value of Stream is the final
+// result; over HTTP they are written as Server-Sent Events when the client
+// accepts text/event-stream and as newline-delimited JSON otherwise, ending
+// with the final BoxResult. send fails once the request is cancelled.
+//
+//	return c.Stream(func(send func(interface{}) error) error {
+//		for line := range lines {
+//			if err := send(line); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+func (c *BoxContext) Stream(fn func(send func(interface{}) error) error) interface{} {
+	if c.Writer != nil {
+		return c.streamHTTP(fn)
+	}
+	sink := callbackSinkFrom(c.ctx)
+	seq := 0
+	send := func(v interface{}) error {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		if c.id == "" {
+			return nil
+		}
+		seq++
+		data, err := json.Marshal(streamChunk{Stream: true, Seq: seq, Data: v})
+		if err != nil {
+			return err
+		}
+		return sink(c.id, string(data))
+	}
+	if err := fn(send); err != nil {
+		return c.Fail(err)
+	}
+	return c.Ok(nil)
+}
+
+func (c *BoxContext) streamHTTP(fn func(send func(interface{}) error) error) interface{} {
+	w := c.Writer
+	flusher, _ := w.(http.Flusher)
+	sse := c.Request != nil && strings.Contains(c.Request.Header.Get("Accept"), "text/event-stream")
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+	write := func(event string, data []byte) error {
+		var err error
+		if sse {
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		} else {
+			_, err = fmt.Fprintf(w, "%s\n", data)
+		}
+		if err == nil && flusher != nil {
+			flusher.Flush()
+		}
+		return err
+	}
+	seq := 0
+	send := func(v interface{}) error {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		seq++
+		data, err := json.Marshal(streamChunk{Stream: true, Seq: seq, Data: v})
+		if err != nil {
+			return err
+		}
+		return write("chunk", data)
+	}
+	var result string
+	if err := fn(send); err != nil {
+		result = c.Fail(err)
+	} else {
+		result = c.Ok(nil)
+	}
+	_ = write("result", []byte(result))
+	return nil
+}
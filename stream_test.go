@@ -0,0 +1,179 @@
+package velo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ltaoo/velo/webview"
+)
+
+func newStreamTestApp() *Box {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.Get("/api/tail", func(c *BoxContext) interface{} {
+		return c.Stream(func(send func(interface{}) error) error {
+			for _, line := range []string{"one", "two", "three"} {
+				if err := send(line); err != nil {
+					return err
+				}
+			}
+			if c.Query("fail") != "" {
+				return ErrConflict.WithMessage("stopped")
+			}
+			return nil
+		})
+	})
+	return app
+}
+
+func TestStreamOverWebSocket(t *testing.T) {
+	app := newStreamTestApp()
+	server := newTestServer(t, app)
+
+	client := dialTestWS(t, server.URL)
+	defer client.close()
+	client.writeText(t, []byte(`{"id":"tail-1","method":"/api/tail"}`))
+
+	var chunks []string
+	for {
+		_, _, payload, err := readWSFrame(client.reader)
+		if err != nil {
+			t.Fatalf("read frame: %v", err)
+		}
+		var frame struct {
+			Type   string          `json:"type"`
+			ID     string          `json:"id"`
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(payload, &frame); err != nil {
+			t.Fatalf("unmarshal frame: %v", err)
+		}
+		if frame.Type != veloWSCallbackType || frame.ID != "tail-1" {
+			t.Fatalf("frame = %s", payload)
+		}
+		var chunk streamChunk
+		json.Unmarshal(frame.Result, &chunk)
+		if chunk.Stream {
+			chunks = append(chunks, chunk.Data.(string))
+			continue
+		}
+		var res BoxResult
+		json.Unmarshal(frame.Result, &res)
+		if res.Code != CodeOK {
+			t.Fatalf("final result = %+v", res)
+		}
+		break
+	}
+	if strings.Join(chunks, ",") != "one,two,three" {
+		t.Fatalf("chunks = %v", chunks)
+	}
+}
+
+func TestStreamOverNativeBridgeReachesTheCallingWindow(t *testing.T) {
+	h := webview.Headless()
+	h.Reset()
+	t.Cleanup(h.Reset)
+
+	app := NewApp(&VeloAppOpt{Mode: ModeBridge, WebviewEngine: webview.EngineHeadless})
+	app.Get("/api/tail", newStreamTestApp().get_handlers["/api/tail"])
+	app.NewWebview(&VeloWebviewOpt{Name: "main"})
+	done := make(chan struct{})
+	go func() {
+		app.Run()
+		close(done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := h.WaitWindow(ctx, "main"); err != nil {
+		t.Fatal(err)
+	}
+	app.OpenWindow(&VeloWebviewOpt{Name: "editor"})
+
+	id, raw, err := h.PostMessage("editor", `{"id":"tail-3","method":"/api/tail"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res BoxResult
+	if err := json.Unmarshal([]byte(raw), &res); err != nil || id != "tail-3" || res.Code != CodeOK {
+		t.Fatalf("final result = %s %s", id, raw)
+	}
+	var chunks []string
+	for _, cb := range h.Callbacks() {
+		if cb.Window != "editor" || cb.ID != "tail-3" {
+			t.Fatalf("chunk sent to %q for %q, want editor", cb.Window, cb.ID)
+		}
+		var chunk streamChunk
+		json.Unmarshal([]byte(cb.Result), &chunk)
+		chunks = append(chunks, chunk.Data.(string))
+	}
+	if strings.Join(chunks, ",") != "one,two,three" {
+		t.Fatalf("chunks = %v", chunks)
+	}
+
+	if err := app.Quit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestStreamOverHTTP(t *testing.T) {
+	app := newStreamTestApp()
+	server := newTestServer(t, app)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/tail?fail=1", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	var events []string
+	var final BoxResult
+	scanner := bufio.NewScanner(resp.Body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+			events = append(events, event)
+			if event == "result" {
+				json.Unmarshal([]byte(data), &final)
+			}
+		}
+	}
+	if strings.Join(events, ",") != "chunk,chunk,chunk,result" {
+		t.Fatalf("events = %v", events)
+	}
+	if final.Code != CodeConflict || final.Msg != "stopped" {
+		t.Fatalf("final = %+v", final)
+	}
+}
+
+func TestStreamSendFailsAfterCancel(t *testing.T) {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	sendErr := make(chan error, 1)
+	app.Get("/api/tail", func(c *BoxContext) interface{} {
+		return c.Stream(func(send func(interface{}) error) error {
+			<-c.Done()
+			err := send("late")
+			sendErr <- err
+			return err
+		})
+	})
+	go app.handleMessage(`{"id":"tail-2","method":"/api/tail","timeout":10}`)
+	if err := <-sendErr; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("send after deadline = %v, want context.DeadlineExceeded", err)
+	}
+}
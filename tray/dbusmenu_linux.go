@@ -0,0 +1,286 @@
+package tray
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	menuPath      = dbus.ObjectPath("/MenuBar")
+	menuInterface = "com.canonical.dbusmenu"
+)
+
+// The dbusmenu id of an item is its MenuItem.ID, so events map straight
+// back through getMenuItem; 0 is the root.
+type menuNode struct {
+	props    map[string]dbus.Variant
+	children []int32
+}
+
+// menuLayout is the recursive (ia{sv}av) returned by GetLayout.
+type menuLayout struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+	Children   []dbus.Variant
+}
+
+type menuItemProperties struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+}
+
+type menuItemRemovedProperties struct {
+	ID         int32
+	Properties []string
+}
+
+type menuEvent struct {
+	ID        int32
+	EventID   string
+	Data      dbus.Variant
+	Timestamp uint32
+}
+
+func buildMenuNodes(menu *Menu) map[int32]*menuNode {
+	nodes := map[int32]*menuNode{
+		0: {props: map[string]dbus.Variant{"children-display": dbus.MakeVariant("submenu")}},
+	}
+	if menu != nil {
+		addMenuNodes(nodes, 0, menu)
+	}
+	return nodes
+}
+
+func addMenuNodes(nodes map[int32]*menuNode, parent int32, menu *Menu) {
+	for _, item := range menu.Items {
+		id := int32(item.ID)
+		nodes[id] = &menuNode{props: menuItemProps(item)}
+		nodes[parent].children = append(nodes[parent].children, id)
+		if item.SubMenu != nil && !item.IsSeparator {
+			addMenuNodes(nodes, id, item.SubMenu)
+		}
+	}
+}
+
+func menuItemProps(item *MenuItem) map[string]dbus.Variant {
+	if item.IsSeparator {
+		return map[string]dbus.Variant{"type": dbus.MakeVariant("separator")}
+	}
+	props := map[string]dbus.Variant{
+		"label":   dbus.MakeVariant(mnemonicLabel(item.Label)),
+		"enabled": dbus.MakeVariant(!item.Disabled),
+		"visible": dbus.MakeVariant(true),
+	}
+	// Only items that start checked get a checkmark slot; Check adds one later.
+	if item.Checked {
+		props["toggle-type"] = dbus.MakeVariant("checkmark")
+		props["toggle-state"] = dbus.MakeVariant(toggleState(true))
+	}
+	if item.Tooltip != "" {
+		props["accessible-desc"] = dbus.MakeVariant(item.Tooltip)
+	}
+	if len(item.Image) > 0 {
+		props["icon-data"] = dbus.MakeVariant(item.Image)
+	}
+	if shortcut := menuShortcut(item.Shortcut); shortcut != nil {
+		props["shortcut"] = dbus.MakeVariant(shortcut)
+	}
+	if item.SubMenu != nil {
+		props["children-display"] = dbus.MakeVariant("submenu")
+	}
+	return props
+}
+
+// mnemonicLabel escapes underscores, which dbusmenu reads as access keys.
+func mnemonicLabel(label string) string {
+	return strings.ReplaceAll(label, "_", "__")
+}
+
+func toggleState(checked bool) int32 {
+	if checked {
+		return 1
+	}
+	return 0
+}
+
+// menuShortcut turns "Ctrl+Shift+P" into dbusmenu's [["Control", "Shift", "P"]].
+func menuShortcut(shortcut string) [][]string {
+	if shortcut == "" {
+		return nil
+	}
+	var keys []string
+	for _, part := range strings.Split(shortcut, "+") {
+		part = strings.TrimSpace(part)
+		switch strings.ToLower(part) {
+		case "":
+			continue
+		case "ctrl", "control", "cmd", "command", "cmdorctrl", "commandorcontrol":
+			part = "Control"
+		case "alt", "option", "opt":
+			part = "Alt"
+		case "shift":
+			part = "Shift"
+		case "super", "meta", "win":
+			part = "Super"
+		}
+		keys = append(keys, part)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return [][]string{keys}
+}
+
+func filterProps(props map[string]dbus.Variant, names []string) map[string]dbus.Variant {
+	out := make(map[string]dbus.Variant, len(props))
+	if len(names) == 0 {
+		for k, v := range props {
+			out[k] = v
+		}
+		return out
+	}
+	for _, name := range names {
+		if v, ok := props[name]; ok {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+// layout must be called with s.mu held.
+func (s *statusNotifier) layout(id int32, depth int32, names []string) menuLayout {
+	node := s.menu[id]
+	l := menuLayout{ID: id, Properties: filterProps(node.props, names), Children: []dbus.Variant{}}
+	if depth == 0 {
+		return l
+	}
+	for _, child := range node.children {
+		l.Children = append(l.Children, dbus.MakeVariant(s.layout(child, depth-1, names)))
+	}
+	return l
+}
+
+// updateItem merges props into an item and tells the host. Ids from
+// another tray's menu are ignored.
+func (s *statusNotifier) updateItem(id int32, props map[string]dbus.Variant) {
+	s.mu.Lock()
+	node, ok := s.menu[id]
+	if ok {
+		for k, v := range props {
+			node.props[k] = v
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	s.emit(menuPath, menuInterface+".ItemsPropertiesUpdated",
+		[]menuItemProperties{{ID: id, Properties: props}}, []menuItemRemovedProperties{})
+}
+
+func unknownMenuID(id int32) *dbus.Error {
+	return dbus.MakeFailedError(fmt.Errorf("unknown menu item id %d", id))
+}
+
+// dbusMenu implements com.canonical.dbusmenu.
+type dbusMenu struct {
+	s *statusNotifier
+}
+
+func menuProperties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Version":       dbus.MakeVariant(uint32(3)),
+		"TextDirection": dbus.MakeVariant("ltr"),
+		"Status":        dbus.MakeVariant("normal"),
+		"IconThemePath": dbus.MakeVariant([]string{}),
+	}
+}
+
+func (m *dbusMenu) GetLayout(parentID int32, recursionDepth int32, propertyNames []string) (uint32, menuLayout, *dbus.Error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if _, ok := m.s.menu[parentID]; !ok {
+		return 0, menuLayout{}, unknownMenuID(parentID)
+	}
+	return m.s.revision, m.s.layout(parentID, recursionDepth, propertyNames), nil
+}
+
+func (m *dbusMenu) GetGroupProperties(ids []int32, propertyNames []string) ([]menuItemProperties, *dbus.Error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if len(ids) == 0 {
+		for id := range m.s.menu {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	out := []menuItemProperties{}
+	for _, id := range ids {
+		if node, ok := m.s.menu[id]; ok {
+			out = append(out, menuItemProperties{ID: id, Properties: filterProps(node.props, propertyNames)})
+		}
+	}
+	return out, nil
+}
+
+func (m *dbusMenu) GetProperty(id int32, name string) (dbus.Variant, *dbus.Error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	node, ok := m.s.menu[id]
+	if !ok {
+		return dbus.Variant{}, unknownMenuID(id)
+	}
+	v, ok := node.props[name]
+	if !ok {
+		return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("menu item %d has no property %q", id, name))
+	}
+	return v, nil
+}
+
+func (m *dbusMenu) Event(id int32, eventID string, data dbus.Variant, timestamp uint32) *dbus.Error {
+	if !m.event(id, eventID) {
+		return unknownMenuID(id)
+	}
+	return nil
+}
+
+func (m *dbusMenu) EventGroup(events []menuEvent) ([]int32, *dbus.Error) {
+	idErrors := []int32{}
+	for _, e := range events {
+		if !m.event(e.ID, e.EventID) {
+			idErrors = append(idErrors, e.ID)
+		}
+	}
+	return idErrors, nil
+}
+
+func (m *dbusMenu) AboutToShow(id int32) (bool, *dbus.Error) {
+	return false, nil
+}
+
+func (m *dbusMenu) AboutToShowGroup(ids []int32) ([]int32, []int32, *dbus.Error) {
+	return []int32{}, []int32{}, nil
+}
+
+// event runs the item's Click for "clicked"; hover, opened and closed
+// need nothing. It reports whether id is in the current menu.
+func (m *dbusMenu) event(id int32, eventID string) bool {
+	m.s.mu.Lock()
+	_, ok := m.s.menu[id]
+	m.s.mu.Unlock()
+	if !ok || id == 0 {
+		return ok
+	}
+	if eventID == "clicked" {
+		go func() {
+			item := getMenuItem(uint32(id))
+			if item != nil && item.Click != nil {
+				item.Click(item)
+			}
+		}()
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+package tray
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+
+	"github.com/ltaoo/velo/internal/loghook"
+)
+
+// SetLogger routes the package's warnings through l at slog.LevelWarn.
+// Without a logger they go to the standard log package. velo.NewApp sets
+// it to the app's logger.
+func SetLogger(l *slog.Logger) {
+	loghook.Tray.Store(l)
+}
+
+func warnf(format string, args ...interface{}) {
+	if l := loghook.Tray.Load(); l != nil {
+		l.Warn(fmt.Sprintf(format, args...))
+		return
+	}
+	log.Printf("tray: "+format, args...)
+}
@@ -17,9 +17,9 @@ type Tray struct {
 	IsTemplate bool
 	// Menu is the context menu.
 	Menu *Menu
-	// OnLeftClick handles the left click event (Windows only).
+	// OnLeftClick handles the left click event (Windows/Linux).
 	OnLeftClick func()
-	// OnRightClick handles the right click event (Windows only).
+	// OnRightClick handles the right click event (Windows/Linux).
 	OnRightClick func()
 }
 
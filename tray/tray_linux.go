@@ -1,31 +1,452 @@
 package tray
 
-func setupNative(t *Tray) {}
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// On Linux the tray is a StatusNotifierItem exported on the session bus,
+// with its menu served over com.canonical.dbusmenu. The panel that shows
+// it (the StatusNotifierWatcher) may start after us, so registration is
+// repeated whenever the watcher's bus name gets a new owner.
+const (
+	itemPath       = dbus.ObjectPath("/StatusNotifierItem")
+	itemInterface  = "org.kde.StatusNotifierItem"
+	watcherName    = "org.kde.StatusNotifierWatcher"
+	watcherPath    = dbus.ObjectPath("/StatusNotifierWatcher")
+	propsInterface = "org.freedesktop.DBus.Properties"
+)
+
+var (
+	notifier     *statusNotifier
+	notifierLock sync.Mutex
+	// running is closed by quitNative to release runNative.
+	running  chan struct{}
+	itemSeq  uint32
+	appTitle = filepath.Base(os.Args[0])
+)
+
+type iconPixmap struct {
+	Width  int32
+	Height int32
+	Data   []byte
+}
+
+type toolTip struct {
+	IconName    string
+	IconPixmap  []iconPixmap
+	Title       string
+	Description string
+}
+
+// statusNotifier holds a snapshot of the tray taken when it is set up and
+// patched by the setters, so D-Bus calls never read the Tray or its
+// MenuItems while the app is changing them.
+type statusNotifier struct {
+	conn *dbus.Conn
+	name string
+
+	mu         sync.Mutex
+	icon       []iconPixmap
+	title      string
+	tooltip    string
+	itemIsMenu bool
+	onLeft     func()
+	onRight    func()
+	menu       map[int32]*menuNode
+	revision   uint32
+}
+
+func newStatusNotifier(t *Tray) (*statusNotifier, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect session bus: %w", err)
+	}
+	s := &statusNotifier{
+		conn: conn,
+		name: fmt.Sprintf("org.kde.StatusNotifierItem-%d-%d", os.Getpid(), atomic.AddUint32(&itemSeq, 1)),
+	}
+	s.load(t)
+	if err := s.export(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply, err := conn.RequestName(s.name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("request name %s: %w", s.name, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, fmt.Errorf("bus name %s is taken", s.name)
+	}
+	s.watch()
+	if err := s.register(); err != nil {
+		warnf("no StatusNotifierWatcher yet, the icon will show once one starts: %v", err)
+	}
+	return s, nil
+}
+
+// load replaces the snapshot with the current state of t.
+func (s *statusNotifier) load(t *Tray) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.icon = iconPixmaps(t.Icon)
+	s.title = t.Title
+	s.tooltip = t.Tooltip
+	s.itemIsMenu = t.Menu != nil && t.OnLeftClick == nil
+	s.onLeft = t.OnLeftClick
+	s.onRight = t.OnRightClick
+	s.menu = buildMenuNodes(t.Menu)
+	s.revision++
+}
+
+func (s *statusNotifier) export() error {
+	item := &notifierItem{s}
+	menu := &dbusMenu{s}
+	exports := []struct {
+		path  dbus.ObjectPath
+		iface string
+		obj   interface{}
+		props func() map[string]dbus.Variant
+		sigs  []introspect.Signal
+	}{
+		{itemPath, itemInterface, item, s.itemProperties, []introspect.Signal{
+			{Name: "NewTitle"}, {Name: "NewIcon"}, {Name: "NewToolTip"},
+			{Name: "NewStatus", Args: []introspect.Arg{{Name: "status", Type: "s"}}},
+		}},
+		{menuPath, menuInterface, menu, menuProperties, []introspect.Signal{
+			{Name: "ItemsPropertiesUpdated", Args: []introspect.Arg{{Name: "updatedProps", Type: "a(ia{sv})"}, {Name: "removedProps", Type: "a(ias)"}}},
+			{Name: "LayoutUpdated", Args: []introspect.Arg{{Name: "revision", Type: "u"}, {Name: "parent", Type: "i"}}},
+			{Name: "ItemActivationRequested", Args: []introspect.Arg{{Name: "id", Type: "i"}, {Name: "timestamp", Type: "u"}}},
+		}},
+	}
+	for _, e := range exports {
+		if err := s.conn.Export(e.obj, e.path, e.iface); err != nil {
+			return fmt.Errorf("export %s: %w", e.iface, err)
+		}
+		props := &properties{iface: e.iface, get: e.props}
+		if err := s.conn.Export(props, e.path, propsInterface); err != nil {
+			return fmt.Errorf("export %s properties: %w", e.iface, err)
+		}
+		node := &introspect.Node{
+			Name: string(e.path),
+			Interfaces: []introspect.Interface{
+				introspect.IntrospectData,
+				prop.IntrospectData,
+				{Name: e.iface, Methods: introspect.Methods(e.obj), Signals: e.sigs, Properties: props.introspection()},
+			},
+		}
+		if err := s.conn.Export(introspect.NewIntrospectable(node), e.path, "org.freedesktop.DBus.Introspectable"); err != nil {
+			return fmt.Errorf("export %s introspection: %w", e.iface, err)
+		}
+	}
+	return nil
+}
+
+// watch re-registers the item whenever a StatusNotifierWatcher appears,
+// e.g. when the panel starts after the app or restarts.
+func (s *statusNotifier) watch() {
+	err := s.conn.AddMatchSignal(
+		dbus.WithMatchSender("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, watcherName),
+	)
+	if err != nil {
+		warnf("watch %s: %v", watcherName, err)
+		return
+	}
+	ch := make(chan *dbus.Signal, 4)
+	s.conn.Signal(ch)
+	go func() {
+		for sig := range ch {
+			if sig.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(sig.Body) != 3 {
+				continue
+			}
+			if owner, _ := sig.Body[2].(string); owner == "" {
+				continue
+			}
+			if err := s.register(); err != nil {
+				warnf("register with %s: %v", watcherName, err)
+			}
+		}
+	}()
+}
+
+func (s *statusNotifier) register() error {
+	return s.conn.Object(watcherName, watcherPath).Call(watcherName+".RegisterStatusNotifierItem", 0, s.name).Err
+}
+
+func (s *statusNotifier) emit(path dbus.ObjectPath, signal string, args ...interface{}) {
+	s.conn.Emit(path, signal, args...)
+}
+
+func (s *statusNotifier) close() {
+	s.conn.Close()
+}
+
+func (s *statusNotifier) itemProperties() map[string]dbus.Variant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	title := s.title
+	if title == "" {
+		title = appTitle
+	}
+	return map[string]dbus.Variant{
+		"Category":            dbus.MakeVariant("ApplicationStatus"),
+		"Id":                  dbus.MakeVariant(appTitle),
+		"Title":               dbus.MakeVariant(title),
+		"Status":              dbus.MakeVariant("Active"),
+		"WindowId":            dbus.MakeVariant(int32(0)),
+		"IconName":            dbus.MakeVariant(""),
+		"IconPixmap":          dbus.MakeVariant(s.icon),
+		"OverlayIconName":     dbus.MakeVariant(""),
+		"OverlayIconPixmap":   dbus.MakeVariant([]iconPixmap{}),
+		"AttentionIconName":   dbus.MakeVariant(""),
+		"AttentionIconPixmap": dbus.MakeVariant([]iconPixmap{}),
+		"AttentionMovieName":  dbus.MakeVariant(""),
+		"IconThemePath":       dbus.MakeVariant(""),
+		"ToolTip":             dbus.MakeVariant(toolTip{IconPixmap: []iconPixmap{}, Title: s.tooltip}),
+		"ItemIsMenu":          dbus.MakeVariant(s.itemIsMenu),
+		"Menu":                dbus.MakeVariant(menuPath),
+	}
+}
+
+// notifierItem implements org.kde.StatusNotifierItem.
+type notifierItem struct {
+	s *statusNotifier
+}
+
+// Activate is a primary (usually left) click on the icon.
+func (i *notifierItem) Activate(x, y int32) *dbus.Error {
+	i.s.mu.Lock()
+	fn := i.s.onLeft
+	i.s.mu.Unlock()
+	if fn != nil {
+		go fn()
+	}
+	return nil
+}
+
+// ContextMenu is sent by hosts that leave the menu to the item; most
+// show the dbusmenu themselves and never call it.
+func (i *notifierItem) ContextMenu(x, y int32) *dbus.Error {
+	i.s.mu.Lock()
+	fn := i.s.onRight
+	i.s.mu.Unlock()
+	if fn != nil {
+		go fn()
+	}
+	return nil
+}
+
+func (i *notifierItem) SecondaryActivate(x, y int32) *dbus.Error {
+	return nil
+}
+
+func (i *notifierItem) Scroll(delta int32, orientation string) *dbus.Error {
+	return nil
+}
+
+// properties implements org.freedesktop.DBus.Properties for one
+// read-only interface whose values are computed on each call.
+type properties struct {
+	iface string
+	get   func() map[string]dbus.Variant
+}
+
+func (p *properties) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	if iface != p.iface {
+		return dbus.Variant{}, prop.ErrIfaceNotFound
+	}
+	v, ok := p.get()[name]
+	if !ok {
+		return dbus.Variant{}, prop.ErrPropNotFound
+	}
+	return v, nil
+}
+
+func (p *properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != p.iface {
+		return nil, prop.ErrIfaceNotFound
+	}
+	return p.get(), nil
+}
+
+func (p *properties) Set(iface, name string, value dbus.Variant) *dbus.Error {
+	if iface != p.iface {
+		return prop.ErrIfaceNotFound
+	}
+	return prop.ErrReadOnly
+}
+
+func (p *properties) introspection() []introspect.Property {
+	var out []introspect.Property
+	for name, v := range p.get() {
+		out = append(out, introspect.Property{Name: name, Type: v.Signature().String(), Access: "read"})
+	}
+	return out
+}
+
+// iconPixmaps converts an encoded image to the ARGB32, network byte order
+// pixmap the protocol carries.
+func iconPixmaps(data []byte) []iconPixmap {
+	if len(data) == 0 {
+		return []iconPixmap{}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		warnf("decode icon: %v", err)
+		return []iconPixmap{}
+	}
+	b := img.Bounds()
+	pixels := make([]byte, 0, b.Dx()*b.Dy()*4)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			pixels = append(pixels, c.A, c.R, c.G, c.B)
+		}
+	}
+	return []iconPixmap{{Width: int32(b.Dx()), Height: int32(b.Dy()), Data: pixels}}
+}
+
+func withNotifier(fn func(s *statusNotifier)) {
+	notifierLock.Lock()
+	s := notifier
+	notifierLock.Unlock()
+	if s != nil {
+		fn(s)
+	}
+}
+
+// setupNative exports the tray, or refreshes it when it is already up so
+// a new Menu gets a new layout revision.
+func setupNative(t *Tray) {
+	notifierLock.Lock()
+	defer notifierLock.Unlock()
+	if notifier != nil {
+		notifier.load(t)
+		notifier.mu.Lock()
+		revision := notifier.revision
+		notifier.mu.Unlock()
+		notifier.emit(menuPath, menuInterface+".LayoutUpdated", revision, int32(0))
+		notifier.emit(itemPath, itemInterface+".NewIcon")
+		notifier.emit(itemPath, itemInterface+".NewTitle")
+		notifier.emit(itemPath, itemInterface+".NewToolTip")
+		return
+	}
+	s, err := newStatusNotifier(t)
+	if err != nil {
+		warnf("start: %v", err)
+		return
+	}
+	notifier = s
+}
 
 func runNative(t *Tray, onReady func(), onExit func()) {
-	// Not implemented
+	setupNative(t)
+
+	notifierLock.Lock()
+	if running == nil {
+		running = make(chan struct{})
+	}
+	done := running
+	notifierLock.Unlock()
+
 	if onReady != nil {
-		onReady()
+		go onReady()
+	}
+
+	<-done
+
+	if onExit != nil {
+		onExit()
 	}
-	// Block forever or until exit?
-	// Since it's a stub, maybe just block to simulate running.
-	select {}
 }
 
-func quitNative() {}
+func quitNative() {
+	notifierLock.Lock()
+	s, done := notifier, running
+	notifier, running = nil, nil
+	notifierLock.Unlock()
 
-func setIconNative(icon []byte) {}
+	if s != nil {
+		s.close()
+	}
+	if done != nil {
+		close(done)
+	}
+}
 
-func setTemplateIconNative(icon []byte) {}
+func setIconNative(icon []byte) {
+	withNotifier(func(s *statusNotifier) {
+		pixmaps := iconPixmaps(icon)
+		s.mu.Lock()
+		s.icon = pixmaps
+		s.mu.Unlock()
+		s.emit(itemPath, itemInterface+".NewIcon")
+	})
+}
 
-func setTitleNative(title string) {}
+func setTemplateIconNative(icon []byte) {
+	// StatusNotifierItem has no template icons; use the icon as is.
+	setIconNative(icon)
+}
 
-func setTooltipNative(tooltip string) {}
+func setTitleNative(title string) {
+	withNotifier(func(s *statusNotifier) {
+		s.mu.Lock()
+		s.title = title
+		s.mu.Unlock()
+		s.emit(itemPath, itemInterface+".NewTitle")
+	})
+}
 
-func setMenuItemLabelNative(id uint32, label string) {}
+func setTooltipNative(tooltip string) {
+	withNotifier(func(s *statusNotifier) {
+		s.mu.Lock()
+		s.tooltip = tooltip
+		s.mu.Unlock()
+		s.emit(itemPath, itemInterface+".NewToolTip")
+	})
+}
 
-func setMenuItemTooltipNative(id uint32, tooltip string) {}
+func setMenuItemLabelNative(id uint32, label string) {
+	withNotifier(func(s *statusNotifier) {
+		s.updateItem(int32(id), map[string]dbus.Variant{"label": dbus.MakeVariant(mnemonicLabel(label))})
+	})
+}
 
-func setMenuItemCheckedNative(id uint32, checked bool) {}
+func setMenuItemTooltipNative(id uint32, tooltip string) {
+	withNotifier(func(s *statusNotifier) {
+		s.updateItem(int32(id), map[string]dbus.Variant{"accessible-desc": dbus.MakeVariant(tooltip)})
+	})
+}
 
-func setMenuItemDisabledNative(id uint32, disabled bool) {}
+func setMenuItemCheckedNative(id uint32, checked bool) {
+	withNotifier(func(s *statusNotifier) {
+		s.updateItem(int32(id), map[string]dbus.Variant{
+			"toggle-type":  dbus.MakeVariant("checkmark"),
+			"toggle-state": dbus.MakeVariant(toggleState(checked)),
+		})
+	})
+}
+
+func setMenuItemDisabledNative(id uint32, disabled bool) {
+	withNotifier(func(s *statusNotifier) {
+		s.updateItem(int32(id), map[string]dbus.Variant{"enabled": dbus.MakeVariant(!disabled)})
+	})
+}
@@ -0,0 +1,240 @@
+package tray
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// startSessionBus runs a private dbus-daemon and points the session bus
+// at it for the rest of the test.
+func startSessionBus(t *testing.T) *dbus.Conn {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+type fakeWatcher struct {
+	registered chan string
+}
+
+func (w *fakeWatcher) RegisterStatusNotifierItem(service string) *dbus.Error {
+	w.registered <- service
+	return nil
+}
+
+func startWatcher(t *testing.T, conn *dbus.Conn) *fakeWatcher {
+	w := &fakeWatcher{registered: make(chan string, 4)}
+	if err := conn.Export(w, watcherPath, watcherName); err != nil {
+		t.Fatalf("export watcher: %v", err)
+	}
+	if reply, err := conn.RequestName(watcherName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("request watcher name: %v, %v", reply, err)
+	}
+	return w
+}
+
+func receive[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		panic("unreachable")
+	}
+}
+
+func waitSignal(t *testing.T, ch <-chan *dbus.Signal, name string) *dbus.Signal {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case sig := <-ch:
+			if sig.Name == name {
+				return sig
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for %s", name)
+		}
+	}
+}
+
+func decodeLayout(t *testing.T, v interface{}) menuLayout {
+	t.Helper()
+	var l menuLayout
+	if err := dbus.Store([]interface{}{v}, &l); err != nil {
+		t.Fatalf("decode layout %v: %v", v, err)
+	}
+	return l
+}
+
+func TestStatusNotifierOverPrivateBus(t *testing.T) {
+	conn := startSessionBus(t)
+	watcher := startWatcher(t, conn)
+	signals := make(chan *dbus.Signal, 32)
+	conn.Signal(signals)
+	if err := conn.AddMatchSignal(dbus.WithMatchInterface(menuInterface)); err != nil {
+		t.Fatalf("match: %v", err)
+	}
+	if err := conn.AddMatchSignal(dbus.WithMatchInterface(itemInterface)); err != nil {
+		t.Fatalf("match: %v", err)
+	}
+
+	var icon bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	img.Set(1, 0, color.NRGBA{B: 0xff, A: 0x80})
+	png.Encode(&icon, img)
+
+	clicked := make(chan uint32, 1)
+	leftClicked := make(chan struct{}, 1)
+	open := &MenuItem{Label: "Open_File", Shortcut: "Ctrl+O", Click: func(m *MenuItem) { clicked <- m.ID }}
+	dark := &MenuItem{Label: "Dark"}
+	tr := &Tray{
+		Icon:        icon.Bytes(),
+		Tooltip:     "velo",
+		OnLeftClick: func() { leftClicked <- struct{}{} },
+		Menu: &Menu{Items: []*MenuItem{
+			open,
+			{IsSeparator: true},
+			{Label: "Theme", SubMenu: &Menu{Items: []*MenuItem{dark}}},
+		}},
+	}
+	Setup(tr)
+	t.Cleanup(Quit)
+
+	service := receive(t, watcher.registered, "RegisterStatusNotifierItem")
+	item := conn.Object(service, itemPath)
+	menu := conn.Object(service, menuPath)
+
+	v, err := item.GetProperty(itemInterface + ".Menu")
+	if err != nil || v.Value() != menuPath {
+		t.Fatalf("Menu = %v, %v", v, err)
+	}
+	var pixmaps []iconPixmap
+	if v, err = item.GetProperty(itemInterface + ".IconPixmap"); err == nil {
+		err = v.Store(&pixmaps)
+	}
+	want := []byte{0xff, 0xff, 0, 0, 0x80, 0, 0, 0xff}
+	if err != nil || len(pixmaps) != 1 || pixmaps[0].Width != 2 || pixmaps[0].Height != 1 || !bytes.Equal(pixmaps[0].Data, want) {
+		t.Fatalf("IconPixmap = %+v, %v", pixmaps, err)
+	}
+
+	var revision uint32
+	var root []interface{}
+	if err := menu.Call(menuInterface+".GetLayout", 0, int32(0), int32(-1), []string{}).Store(&revision, &root); err != nil {
+		t.Fatalf("GetLayout: %v", err)
+	}
+	layout := decodeLayout(t, root)
+	if len(layout.Children) != 3 {
+		t.Fatalf("root has %d children, want 3", len(layout.Children))
+	}
+	first := decodeLayout(t, layout.Children[0].Value())
+	if first.ID != int32(open.ID) || first.Properties["label"].Value() != "Open__File" {
+		t.Fatalf("first item = %+v, want id %d", first, open.ID)
+	}
+	if keys := first.Properties["shortcut"].Value(); !strings.Contains(strings.Join(keys.([][]string)[0], " "), "Control O") {
+		t.Fatalf("shortcut = %v", keys)
+	}
+	theme := decodeLayout(t, layout.Children[2].Value())
+	if len(theme.Children) != 1 || decodeLayout(t, theme.Children[0].Value()).ID != int32(dark.ID) {
+		t.Fatalf("submenu = %+v, want item %d", theme, dark.ID)
+	}
+
+	if err := menu.Call(menuInterface+".Event", 0, int32(open.ID), "clicked", dbus.MakeVariant(""), uint32(0)).Err; err != nil {
+		t.Fatalf("Event: %v", err)
+	}
+	if id := receive(t, clicked, "Click"); id != open.ID {
+		t.Fatalf("clicked %d, want %d", id, open.ID)
+	}
+	if err := menu.Call(menuInterface+".Event", 0, int32(9999), "clicked", dbus.MakeVariant(""), uint32(0)).Err; err == nil {
+		t.Fatalf("Event on an unknown id succeeded")
+	}
+
+	if err := item.Call(itemInterface+".Activate", 0, int32(0), int32(0)).Err; err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	receive(t, leftClicked, "OnLeftClick")
+
+	dark.Check()
+	sig := waitSignal(t, signals, menuInterface+".ItemsPropertiesUpdated")
+	var updated []menuItemProperties
+	if err := dbus.Store(sig.Body[:1], &updated); err != nil || len(updated) != 1 || updated[0].ID != int32(dark.ID) || updated[0].Properties["toggle-state"].Value() != int32(1) {
+		t.Fatalf("ItemsPropertiesUpdated = %v, %v", sig.Body, err)
+	}
+	var state dbus.Variant
+	if err := menu.Call(menuInterface+".GetProperty", 0, int32(dark.ID), "toggle-state").Store(&state); err != nil || state.Value() != int32(1) {
+		t.Fatalf("toggle-state = %v, %v", state, err)
+	}
+
+	tr.SetTooltip("busy")
+	waitSignal(t, signals, itemInterface+".NewToolTip")
+	var tip toolTip
+	if v, err = item.GetProperty(itemInterface + ".ToolTip"); err == nil {
+		err = v.Store(&tip)
+	}
+	if err != nil || tip.Title != "busy" {
+		t.Fatalf("ToolTip = %+v, %v", tip, err)
+	}
+
+	// Setting up again swaps the menu; the new ids come from menuItems.
+	quit := &MenuItem{Label: "Quit"}
+	tr.Menu = &Menu{Items: []*MenuItem{quit}}
+	Setup(tr)
+	sig = waitSignal(t, signals, menuInterface+".LayoutUpdated")
+	if rev := sig.Body[0].(uint32); rev <= revision {
+		t.Fatalf("LayoutUpdated revision %d, want > %d", rev, revision)
+	}
+	if err := menu.Call(menuInterface+".GetLayout", 0, int32(0), int32(-1), []string{"label"}).Store(&revision, &root); err != nil {
+		t.Fatalf("GetLayout: %v", err)
+	}
+	layout = decodeLayout(t, root)
+	if len(layout.Children) != 1 {
+		t.Fatalf("root has %d children after Setup, want 1", len(layout.Children))
+	}
+	if got := decodeLayout(t, layout.Children[0].Value()); got.ID != int32(quit.ID) || getMenuItem(quit.ID) != quit {
+		t.Fatalf("new item = %+v, want id %d", got, quit.ID)
+	}
+}
+
+func TestRunReturnsAfterQuit(t *testing.T) {
+	startSessionBus(t)
+
+	exited := make(chan struct{})
+	go Run(&Tray{Tooltip: "velo"}, Quit, func() { close(exited) })
+	receive(t, exited, "onExit")
+}
@@ -0,0 +1,471 @@
+// Package tsgen generates TypeScript declarations and a typed client from
+// the routes registered on a velo Box and the Go types of their requests
+// and responses.
+package tsgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ltaoo/velo/internal/jsontype"
+)
+
+// Route describes a registered route.
+type Route struct {
+	// Method is "GET" or "POST".
+	Method string
+	// Path is the registered pattern, e.g. "/api/notes/:id".
+	Path string
+	// Request and Response are the Go types of the route; nil when the
+	// handler is not typed.
+	Request  reflect.Type
+	Response reflect.Type
+	// Service and Name are set for methods bound with Box.Bind.
+	Service string
+	Name    string
+}
+
+// Enum is implemented by named types whose values form a fixed set. They
+// are declared as a union of literal types.
+type Enum = jsontype.Enum
+
+// Generate returns the declaration file, which types window.invoke,
+// window.velo and window.__VELO__ (described by runtime when not nil), and
+// the client module, which exports one function per route and one object
+// per bound service. The client imports its types from "./velo", so the
+// declaration file is expected to be written as velo.d.ts next to it.
+func Generate(routes []Route, runtime reflect.Type) (dts, client []byte) {
+	routes = append([]Route(nil), routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	g := newGenerator()
+	runtimeRef := "Record<string, unknown>"
+	if runtime != nil {
+		runtimeRef = g.ref(runtime)
+	}
+	entries := make([]routeEntry, 0, len(routes))
+	for _, r := range routes {
+		entries = append(entries, routeEntry{
+			Route:    r,
+			request:  g.ref(r.Request),
+			response: g.responseRef(r.Response),
+		})
+	}
+	return g.declarations(entries, runtimeRef), g.client(entries)
+}
+
+type routeEntry struct {
+	Route
+	request  string
+	response string
+}
+
+type generator struct {
+	namer *jsontype.Namer
+	decls map[string]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		namer: jsontype.NewNamer(),
+		decls: make(map[string]string),
+	}
+}
+
+// responseRef is ref for a handler result, where a []byte is sent as binary
+// data rather than base64 JSON.
+func (g *generator) responseRef(t reflect.Type) string {
+	if t == jsontype.BytesType {
+		return "ArrayBuffer"
+	}
+	return g.ref(t)
+}
+
+// ref returns the TypeScript type of t, declaring the named types it uses.
+func (g *generator) ref(t reflect.Type) string {
+	if t == nil {
+		return "unknown"
+	}
+	if name, ok := g.namer.Lookup(t); ok {
+		return name
+	}
+	switch t {
+	case jsontype.TimeType, jsontype.BytesType:
+		return "string"
+	case jsontype.RawMessageType:
+		return "unknown"
+	}
+	if values, ok := jsontype.EnumValues(t); ok {
+		name := g.namer.Name(t)
+		g.decls[name] = fmt.Sprintf("export type %s = %s;\n", name, literalUnion(values))
+		return name
+	}
+	switch jsontype.Marshaler(t) {
+	case "json":
+		return "unknown"
+	case "text":
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Pointer:
+		return g.ref(t.Elem()) + " | null"
+	case reflect.Slice, reflect.Array:
+		return arrayOf(g.ref(t.Elem()))
+	case reflect.Map:
+		return "Record<string, " + g.ref(t.Elem()) + ">"
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, "")
+		}
+		name := g.namer.Name(t)
+		g.decls[name] = ""
+		g.decls[name] = fmt.Sprintf("export interface %s %s\n", name, g.object(t, ""))
+		return name
+	}
+	return "unknown"
+}
+
+func arrayOf(elem string) string {
+	if strings.Contains(elem, "|") {
+		elem = "(" + elem + ")"
+	}
+	return elem + "[]"
+}
+
+// object returns the inline object type of the struct t.
+func (g *generator) object(t reflect.Type, indent string) string {
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, f := range jsontype.Fields(t) {
+		optional := ""
+		if f.OmitEmpty {
+			optional = "?"
+		}
+		var typ string
+		switch {
+		case f.Enum != nil:
+			typ = enumOf(literalUnion(f.Enum), f.Type)
+		case f.String:
+			typ = "string"
+		case f.Type.Kind() == reflect.Struct && f.Type.Name() == "":
+			typ = g.object(f.Type, indent+"  ")
+		default:
+			typ = g.ref(f.Type)
+		}
+		fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, propertyName(f.Name), optional, typ)
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// enumOf applies the pointers and slices of t to the union of an enum tag.
+func enumOf(union string, t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return enumOf(union, t.Elem()) + " | null"
+	case reflect.Slice, reflect.Array:
+		return arrayOf(enumOf(union, t.Elem()))
+	}
+	return union
+}
+
+func literalUnion(values []interface{}) string {
+	if len(values) == 0 {
+		return "never"
+	}
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, " | ")
+}
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func propertyName(name string) string {
+	if identPattern.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+const header = "// Code generated by velo generate. DO NOT EDIT.\n\n"
+
+func (g *generator) declarations(entries []routeEntry, runtimeRef string) []byte {
+	var b bytes.Buffer
+	b.WriteString(header)
+	b.WriteString(`export interface BoxResult<T = unknown> {
+  code: number;
+  msg: string;
+  data: T;
+  reason?: string;
+  details?: unknown;
+}
+
+export interface InvokeOptions<A = unknown> {
+  args?: A;
+  body?: ArrayBuffer | ArrayBufferView | Blob;
+  headers?: Record<string, string>;
+  method?: "GET" | "POST";
+  signal?: AbortSignal;
+  timeout?: number;
+  onChunk?: (chunk: unknown) => void;
+}
+
+`)
+	names := make([]string, 0, len(g.decls))
+	for name := range g.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(g.decls[name])
+		b.WriteString("\n")
+	}
+
+	// Routes maps the static paths to their types; dynamic paths are typed
+	// by the client module instead.
+	b.WriteString("export interface Routes {\n")
+	byPath := make(map[string][]routeEntry)
+	var paths []string
+	for _, e := range entries {
+		if strings.ContainsAny(e.Path, ":*") {
+			continue
+		}
+		if _, ok := byPath[e.Path]; !ok {
+			paths = append(paths, e.Path)
+		}
+		byPath[e.Path] = append(byPath[e.Path], e)
+	}
+	for _, p := range paths {
+		var reqs, resps []string
+		for _, e := range byPath[p] {
+			reqs = appendUnique(reqs, e.request)
+			resps = appendUnique(resps, e.response)
+		}
+		fmt.Fprintf(&b, "  %s: { request: %s; response: %s };\n", strconv.Quote(p), strings.Join(reqs, " | "), strings.Join(resps, " | "))
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("export interface Services {\n")
+	for _, svc := range services(entries) {
+		fmt.Fprintf(&b, "  %s: {\n", propertyName(svc.name))
+		for _, e := range svc.methods {
+			fmt.Fprintf(&b, "    %s(args%s: %s, options?: Omit<InvokeOptions, \"args\">): Promise<%s>;\n", propertyName(e.Name), optionalArg(e), e.request, e.response)
+		}
+		b.WriteString("  };\n")
+	}
+	b.WriteString("}\n\n")
+
+	if runtimeRef != "VeloRuntimeInfo" {
+		fmt.Fprintf(&b, "export type VeloRuntimeInfo = %s;\n\n", runtimeRef)
+	}
+	b.WriteString(`export interface Velo {
+  invoke: Window["invoke"];
+  on(name: string, handler: (payload: unknown) => void): () => void;
+  off(name: string, handler?: (payload: unknown) => void): void;
+  once(name: string, handler: (payload: unknown) => void): () => void;
+  emit(name: string, payload?: unknown): Promise<BoxResult>;
+  expose(name: string, fn: (...args: any[]) => unknown): () => void;
+  onBeforeQuit(handler: () => boolean | Promise<boolean>): () => void;
+  services: Services;
+}
+
+declare global {
+  interface Window {
+    invoke<P extends keyof Routes>(url: P, options?: InvokeOptions<Routes[P]["request"]>): Promise<BoxResult<Routes[P]["response"]>>;
+    invoke<T = unknown>(url: string, options?: InvokeOptions): Promise<BoxResult<T>>;
+    velo: Velo;
+    __VELO__: VeloRuntimeInfo;
+  }
+}
+`)
+	return b.Bytes()
+}
+
+func (g *generator) client(entries []routeEntry) []byte {
+	var b bytes.Buffer
+	b.WriteString(`export type CallOptions = Omit<InvokeOptions, "args" | "method">;
+
+export class VeloError extends Error {
+  code: number;
+  reason?: string;
+  details?: unknown;
+
+  constructor(result: BoxResult | undefined) {
+    super((result && result.msg) || "velo call failed");
+    this.name = "VeloError";
+    this.code = result ? result.code : -1;
+    this.reason = result && result.reason;
+    this.details = result && result.details;
+  }
+}
+
+async function call<T>(method: "GET" | "POST", url: string, args: unknown, options?: CallOptions): Promise<T> {
+  const result = (await window.invoke(url, { ...options, method, args })) as BoxResult<T>;
+  if (!result || result.code !== 0) {
+    throw new VeloError(result);
+  }
+  return result.data;
+}
+`)
+	used := make(map[string]bool)
+	for _, e := range entries {
+		if e.Service != "" {
+			continue
+		}
+		name := functionName(e.Method, e.Path)
+		for base, i := name, 2; used[name]; i++ {
+			name = base + strconv.Itoa(i)
+		}
+		used[name] = true
+		params, url := pathParams(e.Path)
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "export function %s(%sargs%s: %s, options?: CallOptions): Promise<%s> {\n", name, params, optionalArg(e), e.request, e.response)
+		fmt.Fprintf(&b, "  return call(%q, %s, args, options);\n}\n", e.Method, url)
+	}
+	for _, svc := range services(entries) {
+		fmt.Fprintf(&b, "\nexport const %s = {\n", jsontype.Export(svc.name))
+		for _, e := range svc.methods {
+			fmt.Fprintf(&b, "  %s(args%s: %s, options?: CallOptions): Promise<%s> {\n", propertyName(e.Name), optionalArg(e), e.request, e.response)
+			fmt.Fprintf(&b, "    return call(%q, %q, args, options);\n  },\n", e.Method, e.Path)
+		}
+		b.WriteString("};\n")
+	}
+
+	imports := []string{"BoxResult", "InvokeOptions"}
+	var types []string
+	for name := range g.decls {
+		if regexp.MustCompile(`\b` + name + `\b`).Match(b.Bytes()) {
+			types = append(types, name)
+		}
+	}
+	sort.Strings(types)
+	imports = append(imports, types...)
+	var out bytes.Buffer
+	out.WriteString(header)
+	fmt.Fprintf(&out, "import type { %s } from \"./velo\";\n\n", strings.Join(imports, ", "))
+	out.Write(b.Bytes())
+	return out.Bytes()
+}
+
+type service struct {
+	name    string
+	methods []routeEntry
+}
+
+func services(entries []routeEntry) []service {
+	var out []service
+	index := make(map[string]int)
+	for _, e := range entries {
+		if e.Service == "" {
+			continue
+		}
+		i, ok := index[e.Service]
+		if !ok {
+			i = len(out)
+			index[e.Service] = i
+			out = append(out, service{name: e.Service})
+		}
+		out[i].methods = append(out[i].methods, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// optionalArg makes the args parameter optional when the route takes none.
+func optionalArg(e routeEntry) string {
+	if e.Request == nil {
+		return "?"
+	}
+	return ""
+}
+
+// functionName derives a client function name from the method and path,
+// e.g. "POST /api/notes/:id" becomes postApiNotesById.
+func functionName(method, path string) string {
+	name := strings.ToLower(method)
+	for _, seg := range strings.Split(path, "/") {
+		switch {
+		case seg == "":
+		case strings.HasPrefix(seg, ":"), strings.HasPrefix(seg, "*"):
+			name += "By" + camel(seg[1:])
+		default:
+			name += camel(seg)
+		}
+	}
+	return name
+}
+
+func camel(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pathParams returns the params argument for a dynamic path and the
+// expression that builds its URL.
+func pathParams(path string) (string, string) {
+	if !strings.ContainsAny(path, ":*") {
+		return "", strconv.Quote(path)
+	}
+	var fields []string
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			fields = append(fields, propertyName(seg[1:])+": string | number")
+			segs[i] = "${encodeURIComponent(String(params." + seg[1:] + "))}"
+		case strings.HasPrefix(seg, "*"):
+			fields = append(fields, propertyName(seg[1:])+": string")
+			segs[i] = "${encodeURI(params." + seg[1:] + ")}"
+		default:
+			segs[i] = strings.ReplaceAll(seg, "`", "\\`")
+		}
+	}
+	return "params: { " + strings.Join(fields, "; ") + " }, ", "`" + strings.Join(segs, "/") + "`"
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
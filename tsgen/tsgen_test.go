@@ -0,0 +1,104 @@
+package tsgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type status string
+
+func (status) EnumValues() []interface{} {
+	return []interface{}{"draft", "published"}
+}
+
+type audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type note struct {
+	audit
+	ID       int      `json:"id"`
+	Title    string   `json:"title"`
+	Tags     []string `json:"tags,omitempty"`
+	Status   status   `json:"status"`
+	Priority int      `json:"priority" enum:"1,2,3"`
+	Parent   *note    `json:"parent"`
+	Secret   string   `json:"-"`
+	Version  int64    `json:"version,string"`
+	Meta     struct {
+		Words int `json:"words"`
+	} `json:"meta"`
+	internal bool
+}
+
+type listReq struct {
+	Query string `json:"query,omitempty"`
+}
+
+func generate(t *testing.T) (string, string) {
+	t.Helper()
+	dts, client := Generate([]Route{
+		{Method: "POST", Path: "/api/notes/:id", Request: reflect.TypeOf(note{}), Response: reflect.TypeOf(note{})},
+		{Method: "GET", Path: "/api/notes", Request: reflect.TypeOf(listReq{}), Response: reflect.TypeOf([]note{})},
+		{Method: "GET", Path: "/api/export", Response: reflect.TypeOf([]byte{})},
+		{Method: "GET", Path: "/api/ping"},
+		{Method: "POST", Path: "/svc/Notes/Archive", Request: reflect.TypeOf(listReq{}), Response: reflect.TypeOf(0), Service: "Notes", Name: "Archive"},
+	}, nil)
+	return string(dts), string(client)
+}
+
+func TestGenerateMapsStructTags(t *testing.T) {
+	dts, _ := generate(t)
+	for _, want := range []string{
+		"export type Status = \"draft\" | \"published\";",
+		"export interface Note {\n  created_at: string;\n  id: number;\n  title: string;\n  tags?: string[];\n  status: Status;\n  priority: 1 | 2 | 3;\n  parent: Note | null;\n  version: string;\n  meta: {\n    words: number;\n  };\n}",
+		"export interface ListReq {\n  query?: string;\n}",
+	} {
+		if !strings.Contains(dts, want) {
+			t.Fatalf("declarations missing %q:\n%s", want, dts)
+		}
+	}
+	for _, unwanted := range []string{"Secret", "internal", "audit"} {
+		if strings.Contains(dts, unwanted) {
+			t.Fatalf("declarations contain %q:\n%s", unwanted, dts)
+		}
+	}
+}
+
+func TestGenerateTypesInvokeAndServices(t *testing.T) {
+	dts, _ := generate(t)
+	for _, want := range []string{
+		`"/api/notes": { request: ListReq; response: Note[] };`,
+		`"/api/export": { request: unknown; response: ArrayBuffer };`,
+		"Archive(args: ListReq, options?: Omit<InvokeOptions, \"args\">): Promise<number>;",
+		"invoke<P extends keyof Routes>(url: P",
+		"__VELO__: VeloRuntimeInfo;",
+	} {
+		if !strings.Contains(dts, want) {
+			t.Fatalf("declarations missing %q:\n%s", want, dts)
+		}
+	}
+	if strings.Contains(dts, `"/api/notes/:id"`) {
+		t.Fatalf("dynamic route typed by path:\n%s", dts)
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	_, client := generate(t)
+	for _, want := range []string{
+		`import type { BoxResult, InvokeOptions, ListReq, Note } from "./velo";`,
+		"export function postApiNotesById(params: { id: string | number }, args: Note, options?: CallOptions): Promise<Note> {\n  return call(\"POST\", `/api/notes/${encodeURIComponent(String(params.id))}`, args, options);",
+		"export function getApiNotes(args: ListReq, options?: CallOptions): Promise<Note[]> {",
+		"export function getApiPing(args?: unknown, options?: CallOptions): Promise<unknown> {",
+		"export const Notes = {\n  Archive(args: ListReq, options?: CallOptions): Promise<number> {\n    return call(\"POST\", \"/svc/Notes/Archive\", args, options);",
+	} {
+		if !strings.Contains(client, want) {
+			t.Fatalf("client missing %q:\n%s", want, client)
+		}
+	}
+	if strings.Contains(client, "svcNotesArchive") {
+		t.Fatalf("service method generated as a route function:\n%s", client)
+	}
+}
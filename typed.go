@@ -0,0 +1,222 @@
+package velo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Validator is implemented by request types that check their own fields
+// after binding. A non-nil error is reported to the caller instead of
+// invoking the handler.
+type Validator interface {
+	Validate() error
+}
+
+// TypedFunc is a handler with a decoded request and a typed response.
+type TypedFunc[Req, Resp any] func(c *BoxContext, req Req) (Resp, error)
+
+// routeTypes records the request and response types of a typed route so
+// that tooling can describe it.
+type routeTypes struct {
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// TypedHandler is a Handler together with the request and response types
+// it decodes and encodes. Typed builds one; register it with GetTyped or
+// PostTyped so that the types are recorded for velo generate and OpenAPI.
+type TypedHandler struct {
+	Handler  Handler
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// Typed adapts fn to a TypedHandler. The invocation args (the bridge
+// payload or the HTTP body) are decoded into Req once, with query and path
+// parameters filling fields that the args leave unset; Req is then
+// validated if it implements Validator. The returned value or error is
+// encoded into a BoxResult for every transport. Binding failures are
+// reported as ErrBadRequest, validation failures as ErrValidation unless
+// Validate returns an *Error itself, and handler errors as described by
+// Fail.
+func Typed[Req, Resp any](fn TypedFunc[Req, Resp]) *TypedHandler {
+	return &TypedHandler{
+		Handler: func(c *BoxContext) interface{} {
+			var req Req
+			if err := c.decodeRequest(&req); err != nil {
+				return c.Fail(err)
+			}
+			resp, err := fn(c, req)
+			if err != nil {
+				return c.Fail(err)
+			}
+			return c.respond(resp)
+		},
+		Request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		Response: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+}
+
+func (h *TypedHandler) types() *routeTypes {
+	return &routeTypes{Request: h.Request, Response: h.Response}
+}
+
+// decodeRequest binds the invocation args into req and validates it. The
+// error is ready for Fail: ErrBadRequest for binding failures, and
+// ErrValidation unless Validate returned an *Error itself.
+func (c *BoxContext) decodeRequest(req interface{}) error {
+	if err := c.bind(req); err != nil {
+		return ErrBadRequest.WithMessage("%v", err).Wrap(err)
+	}
+	if err := validate(req); err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			return err
+		}
+		return ErrValidation.WithMessage("%v", err).Wrap(err)
+	}
+	return nil
+}
+
+// respond encodes a typed handler result; a []byte is returned raw.
+func (c *BoxContext) respond(resp interface{}) interface{} {
+	if data, ok := resp.([]byte); ok {
+		return data
+	}
+	return c.Ok(resp)
+}
+
+func validate(req interface{}) error {
+	if v, ok := req.(Validator); ok {
+		return v.Validate()
+	}
+	if v, ok := reflect.ValueOf(req).Elem().Interface().(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// bind decodes query values, path parameters and the invocation args into
+// obj, in that order, so that explicit args take precedence.
+func (c *BoxContext) bind(obj interface{}) error {
+	target := reflect.ValueOf(obj).Elem()
+	if target.Kind() == reflect.Struct {
+		if err := bindValues(target, c.query); err != nil {
+			return err
+		}
+		if err := bindValues(target, c.params); err != nil {
+			return err
+		}
+	}
+	switch args := c.args.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if len(args) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(args, obj); err != nil {
+			return fmt.Errorf("invalid arguments: %w", err)
+		}
+		return nil
+	default:
+		data, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, obj); err != nil {
+			return fmt.Errorf("invalid arguments: %w", err)
+		}
+		return nil
+	}
+}
+
+// bindValues assigns string values to the scalar fields of a struct, using
+// the same names encoding/json would.
+func bindValues(target reflect.Value, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	t := target.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+		raw, ok := values[name]
+		if !ok {
+			continue
+		}
+		if err := setScalar(target.Field(i), raw); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func setScalar(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
+
+func (b *Box) recordTypes(method, pattern string, types *routeTypes) {
+	key := method + " " + pattern
+	if types != nil {
+		b.route_types[key] = types
+	} else {
+		delete(b.route_types, key)
+	}
+}
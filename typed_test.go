@@ -0,0 +1,125 @@
+package velo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type createNoteReq struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+func (r createNoteReq) Validate() error {
+	if r.Title == "" {
+		return errors.New("title is required")
+	}
+	return nil
+}
+
+type createNoteResp struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Draft bool   `json:"draft"`
+}
+
+func newTypedTestApp() *Box {
+	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
+	app.PostTyped("/api/notes/:id", Typed(func(c *BoxContext, req createNoteReq) (createNoteResp, error) {
+		if req.Title == "fail" {
+			return createNoteResp{}, errors.New("cannot save")
+		}
+		return createNoteResp{ID: req.ID, Title: req.Title, Draft: req.Draft}, nil
+	}))
+	return app
+}
+
+func decodeTypedResult(t *testing.T, raw string) (BoxResult, createNoteResp) {
+	t.Helper()
+	var res struct {
+		BoxResult
+		Data createNoteResp `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		t.Fatalf("unmarshal result: %v; raw=%s", err, raw)
+	}
+	return res.BoxResult, res.Data
+}
+
+func TestTypedHandlerBindsArgsAndParams(t *testing.T) {
+	app := newTypedTestApp()
+
+	_, result := app.handleMessage(`{"id":"1","method":"/api/notes/7?draft=true","httpMethod":"POST","args":{"title":"hello"}}`)
+	res, data := decodeTypedResult(t, result)
+	if res.Code != 0 {
+		t.Fatalf("code = %d, msg = %q", res.Code, res.Msg)
+	}
+	if data != (createNoteResp{ID: 7, Title: "hello", Draft: true}) {
+		t.Fatalf("data = %+v", data)
+	}
+
+	server := newTestServer(t, app)
+	resp, err := http.Post(server.URL+"/api/notes/8", "application/json", strings.NewReader(`{"title":"http"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	res, data = decodeTypedResult(t, string(body))
+	if res.Code != 0 || data.ID != 8 || data.Title != "http" {
+		t.Fatalf("HTTP result = %+v %+v", res, data)
+	}
+}
+
+func TestTypedHandlerReportsErrors(t *testing.T) {
+	app := newTypedTestApp()
+
+	for args, want := range map[string]string{
+		`{"title":""}`:     "title is required",
+		`{"title":"fail"}`: "cannot save",
+		`{"title":42}`:     "invalid arguments",
+	} {
+		_, result := app.handleMessage(`{"id":"1","method":"/api/notes/1","httpMethod":"POST","args":` + args + `}`)
+		res, _ := decodeTypedResult(t, result)
+		if res.Code == 0 || !strings.Contains(res.Msg, want) {
+			t.Errorf("args %s: result = %+v, want error containing %q", args, res, want)
+		}
+	}
+}
+
+func TestTypedHandlerRecordsTypes(t *testing.T) {
+	app := newTypedTestApp()
+	app.Get("/api/plain", func(c *BoxContext) interface{} { return c.Ok(nil) })
+
+	types := app.route_types["POST /api/notes/:id"]
+	if types == nil {
+		t.Fatal("typed route types were not recorded")
+	}
+	if types.Request != reflect.TypeOf(createNoteReq{}) || types.Response != reflect.TypeOf(createNoteResp{}) {
+		t.Fatalf("types = %v -> %v", types.Request, types.Response)
+	}
+	if app.route_types["GET /api/plain"] != nil {
+		t.Fatal("plain handler should not have recorded types")
+	}
+
+	// Instantiations with the same GC shape share code; their types must
+	// still be told apart.
+	app.PostTyped("/api/a", Typed(func(c *BoxContext, req *createNoteReq) (*createNoteResp, error) { return nil, nil }))
+	app.PostTyped("/api/b", Typed(func(c *BoxContext, req *FieldError) (*Error, error) { return nil, nil }))
+	if got := app.route_types["POST /api/a"]; got == nil || got.Request != reflect.TypeOf(&createNoteReq{}) {
+		t.Fatalf("/api/a types = %+v", got)
+	}
+	if got := app.route_types["POST /api/b"]; got == nil || got.Request != reflect.TypeOf(&FieldError{}) || got.Response != reflect.TypeOf(&Error{}) {
+		t.Fatalf("/api/b types = %+v", got)
+	}
+	app.Post("/api/b", func(c *BoxContext) interface{} { return nil })
+	if app.route_types["POST /api/b"] != nil {
+		t.Fatal("replacing a typed route with a plain one kept its types")
+	}
+}
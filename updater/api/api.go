@@ -202,8 +202,7 @@ func (u *AppUpdater) ApplyUpdate(ctx context.Context, updatePath string) error {
 	// Create backup path
 	backupPath := execPath + ".backup"
 	// Step 1: Create backup
-	u.logger.Info().Msg("Creating backup of current executable")
-	fmt.Println("ApplyUpdate - Creating backup of current executable", execPath, backupPath)
+	u.logger.Info().Str("exec_path", execPath).Str("backup_path", backupPath).Msg("Creating backup of current executable")
 	if err := u.applier.Backup(execPath, backupPath); err != nil {
 		// logpkg.LogUpdateError(uo.logger, err, "backup")
 		return &types.UpdateError{
@@ -219,7 +218,6 @@ func (u *AppUpdater) ApplyUpdate(ctx context.Context, updatePath string) error {
 	// logpkg.LogBackupOperation(uo.logger, "create", execPath, backupPath, true)
 	// Step 2: Apply update (extract and replace)
 	u.logger.Info().Msg("Applying update")
-	fmt.Println("ApplyUpdate - Applying update")
 	if err := u.applier.Apply(updatePath, execPath); err != nil {
 		// logpkg.LogUpdateError(uo.logger, err, "apply")
 		// Rollback on failure
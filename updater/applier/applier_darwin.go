@@ -111,14 +111,14 @@ func (du *DarwinUpdater) Apply(updatePath, execPath string) error {
 		Str("new_app", newAppPath).
 		Msg("Found new .app bundle in DMG")
 
-	fmt.Println("DarwinUpdater.Apply before validateAppBundle", newAppPath)
+	du.logger.Debug().Str("path", newAppPath).Msg("DarwinUpdater.Apply before validateAppBundle")
 	if err := du.validateAppBundle(newAppPath); err != nil {
 		du.logger.Warn().Err(err).Str("path", newAppPath).Msg("Invalid .app bundle in DMG, falling back to executable replacement")
 		du.triggerRollback(backupPath, appBundlePath)
 		return du.applyExecutableOnly(updatePath, execPath)
 	}
 
-	fmt.Println("DarwinUpdater.Apply before os.removeAll", appBundlePath)
+	du.logger.Debug().Str("path", appBundlePath).Msg("DarwinUpdater.Apply before os.removeAll")
 	if err := os.RemoveAll(appBundlePath); err != nil {
 		du.triggerRollback(backupPath, appBundlePath)
 		return &types.UpdateError{
@@ -131,7 +131,7 @@ func (du *DarwinUpdater) Apply(updatePath, execPath string) error {
 		}
 	}
 
-	fmt.Println("DarwinUpdater.Apply before du.moveAppBundle", newAppPath)
+	du.logger.Debug().Str("path", newAppPath).Msg("DarwinUpdater.Apply before du.moveAppBundle")
 	if err := du.moveAppBundle(newAppPath, appBundlePath); err != nil {
 		du.triggerRollback(backupPath, appBundlePath)
 		return err
@@ -209,7 +209,7 @@ func (du *DarwinUpdater) applyFromArchive(updatePath, execPath string) error {
 		Str("extract_to", tempDir).
 		Msg("Starting archive extraction")
 
-	fmt.Println("DarwinUpdater.Apply before ExtractArchive", tempDir)
+	du.logger.Debug().Str("path", tempDir).Msg("DarwinUpdater.Apply before ExtractArchive")
 	if err := du.ExtractArchive(updatePath, tempDir); err != nil {
 		du.triggerRollback(backupPath, appBundlePath)
 		return err
@@ -220,7 +220,7 @@ func (du *DarwinUpdater) applyFromArchive(updatePath, execPath string) error {
 		Msg("Archive extraction completed successfully")
 
 	newAppPath, err := du.findExtractedAppBundle(tempDir)
-	fmt.Println("DarwinUpdater.Apply after findExtractedAppBundle", newAppPath)
+	du.logger.Debug().Str("path", newAppPath).Msg("DarwinUpdater.Apply after findExtractedAppBundle")
 	if err != nil {
 		du.logger.Warn().Err(err).Msg("No .app bundle found in archive, trying executable replacement")
 		du.triggerRollback(backupPath, appBundlePath)
@@ -231,14 +231,14 @@ func (du *DarwinUpdater) applyFromArchive(updatePath, execPath string) error {
 		Str("new_app", newAppPath).
 		Msg("Found new .app bundle in archive")
 
-	fmt.Println("DarwinUpdater.Apply before validateAppBundle", newAppPath)
+	du.logger.Debug().Str("path", newAppPath).Msg("DarwinUpdater.Apply before validateAppBundle")
 	if err := du.validateAppBundle(newAppPath); err != nil {
 		du.logger.Warn().Err(err).Str("path", newAppPath).Msg("Invalid .app bundle in archive, falling back to executable replacement")
 		du.triggerRollback(backupPath, appBundlePath)
 		return du.applyExecutableOnly(updatePath, execPath)
 	}
 
-	fmt.Println("DarwinUpdater.Apply before os.removeAll", appBundlePath)
+	du.logger.Debug().Str("path", appBundlePath).Msg("DarwinUpdater.Apply before os.removeAll")
 	if err := os.RemoveAll(appBundlePath); err != nil {
 		du.triggerRollback(backupPath, appBundlePath)
 		return &types.UpdateError{
@@ -251,7 +251,7 @@ func (du *DarwinUpdater) applyFromArchive(updatePath, execPath string) error {
 		}
 	}
 
-	fmt.Println("DarwinUpdater.Apply before du.moveAppBundle", newAppPath)
+	du.logger.Debug().Str("path", newAppPath).Msg("DarwinUpdater.Apply before du.moveAppBundle")
 	if err := du.moveAppBundle(newAppPath, appBundlePath); err != nil {
 		du.triggerRollback(backupPath, appBundlePath)
 		return err
@@ -752,7 +752,7 @@ func (du *DarwinUpdater) Restart(execPath string, args []string) error {
 		// 		du.logger.Warn().Err(err).Msg("Ad-hoc re-sign failed")
 		// 	}
 		// }
-		fmt.Println("[]applier  before du.tryOpenLaunch", appBundlePath)
+		du.logger.Debug().Str("path", appBundlePath).Msg("Restart before du.tryOpenLaunch")
 		du.logger.Info().
 			Str("appBundlePath", appBundlePath).
 			Msg("try open app bundle path")
@@ -763,7 +763,7 @@ func (du *DarwinUpdater) Restart(execPath string, args []string) error {
 		du.logger.Warn().Msg("LaunchServices open strategies failed, falling back to direct exec")
 	}
 
-	fmt.Println("[]applier  before exec.Command(execPath)", execPath)
+	du.logger.Debug().Str("path", execPath).Msg("Restart before exec.Command(execPath)")
 	du.logger.Info().
 		Str("appBundlePath", execPath).
 		Msg("open exec with exec.Command")
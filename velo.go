@@ -12,11 +12,14 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ltaoo/velo/asset"
@@ -53,9 +56,17 @@ type BoxContext struct {
 	ctx     context.Context
 	id      string
 	method  string
+	route   string
 	args    interface{}
 	query   map[string]string
+	params  map[string]string
 	headers interface{}
+	// status is the HTTP status recorded by Fail.
+	status int
+	// body is the binary argument and bytes the binary result of the call.
+	body    []byte
+	bytes   []byte
+	logger  *slog.Logger
 	Writer  http.ResponseWriter
 	Request *http.Request
 }
@@ -63,30 +74,40 @@ type BoxContext struct {
 type H map[string]interface{}
 
 type BoxResult struct {
-	Code int         `json:"code"`
-	Msg  string      `json:"msg"`
-	Data interface{} `json:"data"`
+	Code    int         `json:"code"`
+	Msg     string      `json:"msg"`
+	Data    interface{} `json:"data"`
+	Reason  string      `json:"reason,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+	// Binary is the VeloTransferPath id of a []byte result returned over a
+	// native bridge.
+	Binary string `json:"binary,omitempty"`
 }
 
-func (c *BoxContext) Ok(data interface{}) string {
-	r, _ := json.Marshal(BoxResult{
-		Code: 0,
-		Msg:  "success",
-		Data: data,
-	})
+func marshalResult(result BoxResult) string {
+	r, _ := json.Marshal(result)
 	// if err != nil {
 	// 	return fmt.Sprintf(`{"error": %q}`, err.Error())
 	// }
 	return string(r)
 }
 
+func (c *BoxContext) Ok(data interface{}) string {
+	return marshalResult(BoxResult{
+		Code: CodeOK,
+		Msg:  "success",
+		Data: data,
+	})
+}
+
+// Error reports a generic failure with CodeError. Use Fail with an *Error to
+// send a specific code, reason and HTTP status.
 func (c *BoxContext) Error(message string) string {
-	r, _ := json.Marshal(BoxResult{
-		Code: 100,
+	return marshalResult(BoxResult{
+		Code: CodeError,
 		Msg:  message,
 		Data: nil,
 	})
-	return string(r)
 }
 
 func (c *BoxContext) Query(key string) string {
@@ -109,6 +130,19 @@ func (c *BoxContext) SetQuery(query map[string]string) {
 	c.query = query
 }
 
+// Param returns the value captured by a ":name" or "*name" segment of the
+// route pattern that matched this request.
+func (c *BoxContext) Param(key string) string {
+	if c.params == nil {
+		return ""
+	}
+	return c.params[key]
+}
+
+func (c *BoxContext) SetParams(params map[string]string) {
+	c.params = params
+}
+
 func (c *BoxContext) GetHeader(key string) string {
 	if c.headers == nil {
 		return ""
@@ -156,6 +190,12 @@ func (c *BoxContext) Method() string {
 	return c.method
 }
 
+// Route returns the pattern the request was matched against, such as
+// "/api/notes/:id". For literal routes it equals Method.
+func (c *BoxContext) Route() string {
+	return c.route
+}
+
 func (c *BoxContext) Args() interface{} {
 	return c.args
 }
@@ -175,6 +215,14 @@ func (c *BoxContext) Context() context.Context {
 	return c.ctx
 }
 
+// Logger returns the logger of the app handling the call.
+func (c *BoxContext) Logger() *slog.Logger {
+	if c.logger == nil {
+		return slog.Default()
+	}
+	return c.logger
+}
+
 type Handler func(c *BoxContext) interface{}
 
 type AppConfig struct {
@@ -187,8 +235,11 @@ type AppConfig struct {
 		Icon        string `json:"icon"`
 		TrayIcon    string `json:"tray_icon"`
 	} `json:"app"`
-	Desktop buildcfg.DesktopSection `json:"desktop"`
-	Update  buildcfg.UpdateSection  `json:"update"`
+	Desktop       buildcfg.DesktopSection        `json:"desktop"`
+	Protocols     []buildcfg.ProtocolSection     `json:"protocols"`
+	DocumentTypes []buildcfg.DocumentTypeSection `json:"document_types"`
+	Update        buildcfg.UpdateSection         `json:"update"`
+	CrashReports  buildcfg.CrashReportsSection   `json:"crash_reports"`
 }
 
 func LoadAppConfig(embedded ...[]byte) *AppConfig {
@@ -296,18 +347,50 @@ type veloRuntimeWindowInfo struct {
 type veloRuntimeInfo struct {
 	Version   string                 `json:"version"`
 	Mode      string                 `json:"mode"`
+	HTTPBase  string                 `json:"http_base,omitempty"`
+	Token     string                 `json:"token,omitempty"`
 	ModeValue int                    `json:"mode_value"`
 	Engine    string                 `json:"engine"`
 	AppName   string                 `json:"app_name"`
 	Title     string                 `json:"title"`
 	Config    veloRuntimeConfig      `json:"config"`
 	Window    *veloRuntimeWindowInfo `json:"window"`
+	// Services lists the methods registered with Bind by service name.
+	Services map[string][]string `json:"services,omitempty"`
+	// FrontendLogs is set when console output is forwarded to the Go log.
+	FrontendLogs *veloRuntimeFrontendLogs `json:"frontend_logs,omitempty"`
 }
 
 type Box struct {
 	get_handlers           map[string]Handler
 	post_handlers          map[string]Handler
+	get_routes             []*route
+	post_routes            []*route
+	middlewares            []Middleware
+	inflight               *inflightCalls
+	transfers              *transferStore
+	services               map[string][]string
+	lifecycle              *lifecycle
+	instance               *instanceLock
+	logger                 *slog.Logger
+	logFile                io.Closer
+	frontendLogs           *frontendLogs
+	crashes                *crashReporter
+	urls                   *launchQueue[string]
+	files                  *launchQueue[[]string]
+	events                 *eventBus
+	sendMu                 sync.Mutex
+	sendSeq                int
+	sendObservers          map[int]func(message interface{})
+	addr                   string
+	listenMu               sync.Mutex
+	listener               net.Listener
+	server                 *http.Server
+	token                  string
+	allowedOrigins         []string
+	route_types            map[string]*routeTypes
 	webviews               []*webview.BoxWebviewOptions
+	webviewInfos           []*veloRuntimeWindowInfo
 	Webview                *webview.Webview
 	Store                  *store.Store
 	DB                     *gorm.DB
@@ -326,14 +409,52 @@ type Box struct {
 type VeloAppOpt struct {
 	Mode          Mode
 	WebviewEngine webview.Engine
-	AppName       string
-	Title         string
-	IconData      []byte
-	AppConfig     *AppConfig
+	// Addr is the HTTP listen address for ModeHttp and ModeBridgeHttp,
+	// overriding velo.json's desktop.addr. Use port 0 (e.g. "127.0.0.1:0")
+	// to pick a random free port; Box.Addr reports the bound address.
+	Addr      string
+	AppName   string
+	Title     string
+	IconData  []byte
+	AppConfig *AppConfig
 	// EnableLocalStorage creates storage.json and enables the built-in storage
 	// and window state persistence APIs.
 	EnableLocalStorage     bool
 	QuitOnLastWindowClosed *bool
+	// DisableTokenAuth turns off the per-launch token that HTTP and WebSocket
+	// requests must carry in ModeHttp and ModeBridgeHttp.
+	DisableTokenAuth bool
+	// AllowedOrigins lists extra browser origins (e.g. a frontend dev server
+	// such as "http://localhost:5173") that may call the API.
+	AllowedOrigins []string
+	// HookTimeout bounds each lifecycle hook; defaults to DefaultHookTimeout.
+	HookTimeout time.Duration
+	// ShutdownTimeout bounds the shutdown started by a signal or by the last
+	// window closing; defaults to DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+	// SingleInstance allows one running instance per user. A later launch
+	// hands its arguments to the running instance (see OnSecondInstance)
+	// and exits before opening storage, the database or the HTTP port.
+	SingleInstance bool
+	// Logger receives the logs of the app. By default they are written to
+	// dir.Dir.LogFile(), rotated at logging.DefaultMaxSize, and to stderr
+	// with LogToConsole or SetDebug.
+	Logger *slog.Logger
+	// LogLevel is the minimum level of the default logger; SetDebug lowers
+	// it to slog.LevelDebug.
+	LogLevel slog.Level
+	// LogToConsole also writes the default logger to stderr, e.g. in
+	// development builds.
+	LogToConsole bool
+	// FrontendLogs forwards the console output and uncaught errors of the
+	// windows into the log, overriding velo.json's desktop.frontend_logs.
+	FrontendLogs *buildcfg.FrontendLogsSection
+	// CrashUploader sends the crash reports of earlier launches at launch,
+	// replacing velo.json's crash_reports.upload_url.
+	CrashUploader CrashUploader
+	// DisableCrashReports turns off crash reports, like velo.json's
+	// crash_reports.disabled.
+	DisableCrashReports bool
 }
 
 func NewApp(o *VeloAppOpt) *Box {
@@ -344,21 +465,36 @@ func NewApp(o *VeloAppOpt) *Box {
 	b := &Box{
 		get_handlers:           make(map[string]Handler),
 		post_handlers:          make(map[string]Handler),
+		route_types:            make(map[string]*routeTypes),
 		wsHub:                  newVeloWSHub(),
+		inflight:               newInflightCalls(),
+		transfers:              newTransferStore(),
+		services:               make(map[string][]string),
+		urls:                   newLaunchQueue[string](),
+		files:                  newLaunchQueue[[]string](),
+		lifecycle:              newLifecycle(o.HookTimeout, o.ShutdownTimeout),
+		events:                 newEventBus(),
+		sendObservers:          make(map[int]func(message interface{})),
 		frontendDir:            "frontend",
 		appName:                appConfig.displayName(),
 		appConfig:              appConfig,
 		quitOnLastWindowClosed: true,
 		webviewEngine:          resolveWebviewEngine(appConfig, o.WebviewEngine),
+		addr:                   resolveAddr(appConfig, o.Addr),
+		allowedOrigins:         o.AllowedOrigins,
 	}
-	b.mode = o.Mode
-	if b.webviewEngine == webview.EngineElectron && b.mode == ModeBridge {
-		fmt.Println("[velo] electron webview engine uses HTTP/WebSocket transport; switching ModeBridge to ModeBridgeHttp")
-		b.mode = ModeBridgeHttp
+	if !o.DisableTokenAuth {
+		b.token = generateID()
 	}
 	if o.AppName != "" {
 		b.appName = o.AppName
 	}
+	b.useLogger(o)
+	b.mode = o.Mode
+	if b.webviewEngine == webview.EngineElectron && b.mode == ModeBridge {
+		b.Log("webview").Info("electron webview engine uses HTTP/WebSocket transport; switching ModeBridge to ModeBridgeHttp")
+		b.mode = ModeBridgeHttp
+	}
 	if o.Title != "" {
 		b.title = o.Title
 	}
@@ -368,11 +504,17 @@ func NewApp(o *VeloAppOpt) *Box {
 	if o.QuitOnLastWindowClosed != nil {
 		b.quitOnLastWindowClosed = *o.QuitOnLastWindowClosed
 	}
+	if o.SingleInstance {
+		b.useSingleInstance()
+	}
+	b.useCrashReports(o)
+	b.useAssociations()
 	if o.EnableLocalStorage {
 		b.Store = store.New()
 		b.registerStoreRoutes()
 	}
 	b.registerVeloRoutes()
+	b.useFrontendLogs(o)
 	return b
 }
 
@@ -431,15 +573,24 @@ func (b *Box) runtimeInfo(window *veloRuntimeWindowInfo) veloRuntimeInfo {
 	if title == "" {
 		title = b.appName
 	}
+	var httpBase, token string
+	if usesHTTP(b.mode) {
+		httpBase = b.httpBase()
+		token = b.token
+	}
 	return veloRuntimeInfo{
-		Version:   Version,
-		Mode:      b.mode.String(),
-		ModeValue: int(b.mode),
-		Engine:    string(b.webviewEngine),
-		AppName:   b.appName,
-		Title:     title,
-		Config:    b.appConfig.runtimeConfig(),
-		Window:    window,
+		Version:      Version,
+		Mode:         b.mode.String(),
+		HTTPBase:     httpBase,
+		Token:        token,
+		ModeValue:    int(b.mode),
+		Engine:       string(b.webviewEngine),
+		AppName:      b.appName,
+		Title:        title,
+		Config:       b.appConfig.runtimeConfig(),
+		Window:       window,
+		Services:     b.services,
+		FrontendLogs: b.runtimeFrontendLogs(),
 	}
 }
 
@@ -473,7 +624,7 @@ func (b *Box) webviewURL(optURL, pathname string) string {
 		pathname = "/" + pathname
 	}
 	if b.mode == ModeBridgeHttp {
-		return "http://127.0.0.1:8080" + pathname
+		return b.tokenURL(b.httpBase() + pathname)
 	}
 	return "velo://localhost" + pathname
 }
@@ -519,14 +670,77 @@ func (b *Box) Migrate(opt *VeloDatabaseOpt) error {
 	}, opt.Migrations)
 }
 
-func (b *Box) Get(name string, handler Handler) {
+// Get registers a handler for name. Besides literal paths, name may contain
+// ":param" segments and a trailing "*wildcard" segment, e.g. "/api/notes/:id"
+// or "/api/files/*path"; captured values are read with BoxContext.Param. A
+// wildcard anywhere but the last segment panics.
+// Optional middlewares apply to this route only and run after the global
+// ones registered with Use.
+func (b *Box) Get(name string, handler Handler, middlewares ...Middleware) {
+	b.handle(http.MethodGet, name, handler, nil, middlewares)
+}
+
+// Post registers a POST handler for name. See Get for the pattern syntax and
+// middlewares.
+func (b *Box) Post(name string, handler Handler, middlewares ...Middleware) {
+	b.handle(http.MethodPost, name, handler, nil, middlewares)
+}
+
+// GetTyped registers a handler built by Typed like Get does, and records its
+// request and response types for tooling.
+func (b *Box) GetTyped(name string, h *TypedHandler, middlewares ...Middleware) {
+	b.handle(http.MethodGet, name, h.Handler, h.types(), middlewares)
+}
+
+// PostTyped registers a handler built by Typed like Post does, and records
+// its request and response types for tooling.
+func (b *Box) PostTyped(name string, h *TypedHandler, middlewares ...Middleware) {
+	b.handle(http.MethodPost, name, h.Handler, h.types(), middlewares)
+}
+
+func (b *Box) handle(method, name string, handler Handler, types *routeTypes, middlewares []Middleware) {
+	validatePattern(name)
+	b.recordTypes(method, name, types)
+	handler = chain(handler, middlewares)
+	if method == http.MethodPost {
+		b.post_handlers[name] = handler
+		if isDynamicPattern(name) {
+			b.post_routes = addRoute(b.post_routes, name, handler)
+		}
+		return
+	}
 	b.get_handlers[name] = handler
+	if isDynamicPattern(name) {
+		b.get_routes = addRoute(b.get_routes, name, handler)
+	}
 }
-func (b *Box) Post(name string, handler Handler) {
-	b.post_handlers[name] = handler
+
+// OnSendMessage registers fn to receive every message sent to the pages with
+// SendMessage, including the events of Emit and EmitTo. The returned
+// function removes it.
+func (b *Box) OnSendMessage(fn func(message interface{})) func() {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	id := b.sendSeq
+	b.sendSeq++
+	b.sendObservers[id] = fn
+	return func() {
+		b.sendMu.Lock()
+		delete(b.sendObservers, id)
+		b.sendMu.Unlock()
+	}
 }
 
 func (b *Box) SendMessage(message interface{}) bool {
+	b.sendMu.Lock()
+	observers := make([]func(interface{}), 0, len(b.sendObservers))
+	for _, fn := range b.sendObservers {
+		observers = append(observers, fn)
+	}
+	b.sendMu.Unlock()
+	for _, fn := range observers {
+		fn(message)
+	}
 	delivered := false
 	if b.mode != ModeHttp {
 		delivered = webview.SendMessage(message)
@@ -606,10 +820,13 @@ func (b *Box) OpenWindow(opt *VeloWebviewOpt) *webview.Webview {
 		HasPosition:            hasPosition,
 		Mux:                    mux,
 		FrontendFS:             opt.FrontendFS,
-		HandleMessage:          b.handleMessage,
+		HandleMessage:          b.windowMessageHandler(windowName),
+		HandleWindowMessage:    b.windowCallbackHandler(windowName),
 		HandleDragDrop:         opt.OnDragDrop,
 		HandleReopen:           opt.OnReopen,
+		HandleOpenURL:          b.openURLs,
 		HandleClose:            opt.OnClose,
+		HandleQuitRequest:      b.quitRequested,
 		QuitOnLastWindowClosed: b.quitOnLastWindowClosed,
 		Engine:                 b.webviewEngine,
 		ElectronCommand:        b.appConfig.Desktop.Electron.Command,
@@ -620,27 +837,95 @@ func (b *Box) OpenWindow(opt *VeloWebviewOpt) *webview.Webview {
 		PreserveStateOnFocus:   opt.PreserveStateOnFocus,
 		URL:                    windowURL,
 	}
-	return webview.OpenWindow(opts)
+	webview.OpenWindow(opts)
+	return b.windowHandle(windowName)
 }
 
 func (b *Box) handleMessage(message string) (string, string) {
+	return b.handleMessageContext(context.Background(), message)
+}
+
+// windowMessageHandler returns the bridge handler of window name. Its calls
+// are scoped to the window, so another page cannot cancel them. Backends
+// with a single window use it; stream chunks then go to that window through
+// webview.SendCallback.
+func (b *Box) windowMessageHandler(name string) webview.Handler {
+	handle := b.windowCallbackHandler(name)
+	return func(message string) (string, string) {
+		return handle(message, nil)
+	}
+}
+
+// windowCallbackHandler is windowMessageHandler for backends that pass the
+// window's own send function, which stream chunks are delivered through
+// like the WebSocket hub does for its clients.
+func (b *Box) windowCallbackHandler(name string) webview.WindowHandler {
+	return func(message string, send func(id, result string)) (string, string) {
+		ctx := withCaller(context.Background(), windowCaller+name)
+		if send != nil {
+			ctx = withCallbackSink(ctx, func(id, result string) error {
+				send(id, result)
+				return nil
+			})
+		}
+		return b.handleMessageContext(ctx, message)
+	}
+}
+
+// HandleMessage dispatches a bridge message, the JSON sent by the runtime's
+// invoke, and returns its callback id and result like the native webview
+// does. Custom transports and the velotest package use it.
+func (b *Box) HandleMessage(message string) (id, result string) {
+	return b.handleMessage(message)
+}
+
+// handleMessageContext dispatches a bridge message with a handler context
+// derived from parent, so that the WebSocket hub can cancel the calls of a
+// client that disconnects. Cancel packets sent by the runtime abort the
+// matching in-flight call and produce no callback. A []byte result is kept
+// in the transfer store and its id returned in BoxResult.Binary.
+func (b *Box) handleMessageContext(parent context.Context, message string) (string, string) {
+	id, result, data := b.handleBinaryMessage(parent, message, nil)
+	if data != nil {
+		result = marshalResult(BoxResult{Code: CodeOK, Msg: "success", Binary: b.transfers.put(data)})
+	}
+	return id, result
+}
+
+// handleBinaryMessage dispatches a bridge message whose binary argument, if
+// any, is body. A []byte result is returned as data for transports that can
+// carry it as is.
+func (b *Box) handleBinaryMessage(parent context.Context, message string, body []byte) (string, string, []byte) {
 	var msg struct {
-		ID         string      `json:"id"`
-		Method     string      `json:"method"`
-		Headers    interface{} `json:"headers"`
-		Args       interface{} `json:"args"`
-		HTTPMethod string      `json:"httpMethod"`
+		ID           string      `json:"id"`
+		Method       string      `json:"method"`
+		Headers      interface{} `json:"headers"`
+		Args         interface{} `json:"args"`
+		HTTPMethod   string      `json:"httpMethod"`
+		Timeout      int64       `json:"timeout"`
+		Binary       string      `json:"binary"`
+		BinaryBase64 string      `json:"binary_base64"`
 	}
 	if err := json.Unmarshal([]byte(message), &msg); err != nil {
-		fmt.Println("[box]handleMessage - unmarshal message failed", err)
-		return "", ""
+		b.Log("bridge").Warn("invalid message", "error", err)
+		return "", "", nil
+	}
+	if msg.Method == veloCancelMethod {
+		b.inflight.cancel(callerFrom(parent), cancelTarget(msg.Args))
+		return "", "", nil
+	}
+	if body == nil && msg.Binary != "" {
+		body, _ = b.transfers.take(msg.Binary)
+	}
+	if body == nil {
+		body = decodeBase64Body(msg.BinaryBase64)
 	}
 	// Separate path and query string so that handlers registered by path
 	// can be matched even when the frontend sends query parameters in the URL.
-	path := msg.Method
+	path, escapedPath := msg.Method, msg.Method
 	var queryParams map[string]string
 	if u, err := url.Parse(msg.Method); err == nil {
-		path = u.Path
+		path, escapedPath = u.Path, u.EscapedPath()
 		if len(u.Query()) > 0 {
 			queryParams = make(map[string]string, len(u.Query()))
 			for k, v := range u.Query() {
@@ -650,33 +935,27 @@ func (b *Box) handleMessage(message string) (string, string) {
 			}
 		}
 	}
-	fmt.Println("match methods in get handlers or post handlers", path)
-	var handler Handler
-	var exists bool
-	if msg.HTTPMethod == "POST" {
-		handler, exists = b.post_handlers[path]
-		if !exists {
-			handler, exists = b.get_handlers[path]
-		}
-	} else {
-		handler, exists = b.get_handlers[path]
-		if !exists {
-			handler, exists = b.post_handlers[path]
-		}
-	}
+	b.Log("bridge").Debug("call", "method", path)
+	handler, pattern, params, exists := b.lookupHandler(msg.HTTPMethod, escapedPath)
+	callCtx, done := b.inflight.begin(parent, msg.ID, msg.Timeout)
+	defer done()
 	ctx := &BoxContext{
-		ctx:     context.Background(),
+		ctx:     callCtx,
 		id:      msg.ID,
 		method:  path,
+		route:   pattern,
 		headers: msg.Headers,
 		args:    msg.Args,
 		query:   queryParams,
+		params:  params,
+		body:    body,
+		logger:  b.Logger(),
 	}
 	if !exists {
-		return msg.ID, fmt.Sprintf("%v", ctx.Error("unknown method"))
+		return msg.ID, ctx.Fail(ErrUnknownMethod), nil
 	}
-	result := handler(ctx)
-	return msg.ID, fmt.Sprintf("%v", result)
+	result := b.dispatch(handler, ctx)
+	return msg.ID, ctx.render(result), ctx.bytes
 }
 
 func (b *Box) registerStoreRoutes() {
@@ -741,6 +1020,9 @@ func (b *Box) registerVeloRoutes() {
 	b.Get("/api/velo/info", func(c *BoxContext) interface{} {
 		return c.Ok(b.runtimeInfo(nil))
 	})
+	b.registerLifecycleRoutes()
+	b.registerEventRoutes()
+	b.registerCallRoutes()
 }
 
 func generateID() string {
@@ -756,115 +1038,203 @@ func (box *Box) setupMux(frontendFS fs.FS, entryPage string) *http.ServeMux {
 		entryPage = "index.html"
 	}
 
+	var root http.Handler
 	if frontendFS != nil {
-		mux.Handle("/", frontendserver.New(frontendserver.Options{
+		root = frontendserver.New(frontendserver.Options{
 			Mode:      frontendserver.ModeProd,
 			Root:      "frontend",
 			Embedded:  frontendFS,
 			EntryPage: entryPage,
-		}))
+		})
 	} else if box.mode == ModeBridgeHttp || box.mode == ModeBridge || box.mode == ModeHttp {
-		mux.Handle("/", frontendserver.New(frontendserver.Options{
+		root = frontendserver.New(frontendserver.Options{
 			Root:      box.frontendDir,
 			EntryPage: entryPage,
-		}))
+		})
 	}
 
 	if box.wsHub != nil {
-		mux.HandleFunc(VeloWebSocketPath, func(w http.ResponseWriter, r *http.Request) {
-			box.wsHub.ServeHTTP(w, r, box.handleMessage)
-		})
+		mux.Handle(VeloWebSocketPath, box.requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			box.wsHub.ServeHTTP(w, r, box.handleBinaryMessage)
+		})))
 	}
-	mux.HandleFunc(VeloRuntimePath, func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle(VeloTransferPath, box.requireToken(http.HandlerFunc(box.serveTransfer)))
+	mux.Handle(VeloOpenAPIPath, box.requireToken(http.HandlerFunc(box.serveOpenAPI)))
+	mux.Handle(VeloRuntimePath, box.requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
 		w.Write([]byte(box.injectedRuntimeJS(nil)))
-	})
-
-	// Collect all unique paths (union of GET and POST handlers)
-	allPaths := make(map[string]struct{})
-	for path := range box.get_handlers {
-		allPaths[path] = struct{}{}
-	}
-	for path := range box.post_handlers {
-		allPaths[path] = struct{}{}
-	}
-
-	for path := range allPaths {
-		path := path
-		getHandler, hasGet := box.get_handlers[path]
-		postHandler, hasPost := box.post_handlers[path]
-		// Capture copies for the closure (Go 1.20 loop variable semantics).
-		gh, hg := getHandler, hasGet
-		ph, hp := postHandler, hasPost
-		fmt.Printf("[velo] registering %s (GET=%v, POST=%v)\n", path, hg, hp)
-		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-			fmt.Printf("[velo] handling %s %s\n", r.Method, r.URL.Path)
-
-			query_params := make(map[string]string)
-			for key, values := range r.URL.Query() {
-				if len(values) > 0 {
-					query_params[key] = values[0]
-				}
+	})))
+
+	// Every registered pattern is served by the same dispatcher so that HTTP
+	// requests resolve routes exactly like handleMessage does. Dynamic
+	// patterns are mounted on the ServeMux subtree of their literal prefix;
+	// paths under that subtree which match no route fall back to "/".
+	var fallback http.Handler = http.NotFoundHandler()
+	if root != nil {
+		root = box.withToken(root)
+		fallback = root
+	}
+	dispatch := box.routeHandler(fallback)
+	mounted := make(map[string]struct{})
+	mount := func(p string) {
+		if _, ok := mounted[p]; ok {
+			return
+		}
+		mounted[p] = struct{}{}
+		mux.Handle(p, dispatch)
+	}
+	for _, handlers := range []map[string]Handler{box.get_handlers, box.post_handlers} {
+		for path := range handlers {
+			if isDynamicPattern(path) {
+				continue
+			}
+			_, hasGet := box.get_handlers[path]
+			_, hasPost := box.post_handlers[path]
+			if _, ok := mounted[path]; !ok {
+				box.Log("http").Debug("registering route", "path", path, "get", hasGet, "post", hasPost)
 			}
+			mount(path)
+		}
+	}
+	for _, routes := range [][]*route{box.get_routes, box.post_routes} {
+		for _, r := range routes {
+			prefix := r.muxPrefix()
+			if _, ok := mounted[prefix]; !ok {
+				box.Log("http").Debug("registering route", "path", prefix, "pattern", r.pattern)
+			}
+			mount(prefix)
+		}
+	}
+	if _, ok := mounted["/"]; !ok && root != nil {
+		mux.Handle("/", root)
+	}
 
-			var handler Handler
-			var args interface{}
+	return mux
+}
 
-			if r.Method == "POST" && hp {
-				handler = ph
-				contentType := strings.ToLower(r.Header.Get("Content-Type"))
-				if strings.Contains(contentType, "application/json") || contentType == "" {
-					json.NewDecoder(r.Body).Decode(&args)
-				} else if body, err := io.ReadAll(r.Body); err == nil {
-					args = body
-				}
-			} else if hg {
-				handler = gh
-			} else {
-				w.WriteHeader(http.StatusMethodNotAllowed)
+// routeHandler serves registered handlers over HTTP. A POST request uses the
+// POST handler when one matches, any other request (and a POST without one)
+// uses the GET handler. Unmatched paths are passed on to fallback.
+func (box *Box) routeHandler(fallback http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Routes match the escaped path so that "%2F" inside a parameter
+		// does not split it.
+		path, escapedPath := r.URL.Path, r.URL.EscapedPath()
+		var (
+			handler Handler
+			pattern string
+			params  map[string]string
+			ok      bool
+		)
+		if r.Method == http.MethodPost {
+			handler, pattern, params, ok = box.lookupPost(escapedPath)
+		}
+		if !ok {
+			handler, pattern, params, ok = box.lookupGet(escapedPath)
+		}
+		if !ok {
+			if _, _, _, hasPost := box.lookupPost(escapedPath); !hasPost {
+				fallback.ServeHTTP(w, r)
 				return
 			}
+		}
+		// Authorize before answering 405 or logging, so that an
+		// unauthenticated client learns nothing about the routes.
+		if !box.authorize(w, r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte((&BoxContext{}).Fail(ErrUnauthorized.WithMessage("missing or invalid velo token"))))
+			return
+		}
+		box.Log("http").Debug("request", "method", r.Method, "path", r.URL.Path)
+		if !ok {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 
-			ctx := &BoxContext{
-				ctx:     r.Context(),
-				id:      "",
-				method:  path,
-				args:    args,
-				query:   query_params,
-				headers: r.Header,
-				Writer:  w,
-				Request: r,
+		query_params := make(map[string]string)
+		for key, values := range r.URL.Query() {
+			if len(values) > 0 {
+				query_params[key] = values[0]
 			}
-			result := handler(ctx)
-			if result != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.Write([]byte(fmt.Sprintf("%v", result)))
+		}
+
+		var args interface{}
+		var body []byte
+		if r.Method == http.MethodPost {
+			contentType := strings.ToLower(r.Header.Get("Content-Type"))
+			if strings.Contains(contentType, "application/json") || contentType == "" {
+				json.NewDecoder(r.Body).Decode(&args)
+			} else if data, err := io.ReadAll(r.Body); err == nil {
+				args = data
+				body = data
 			}
-		})
-	}
+		}
 
-	return mux
+		ctx := &BoxContext{
+			ctx:     r.Context(),
+			id:      "",
+			method:  path,
+			route:   pattern,
+			args:    args,
+			query:   query_params,
+			params:  params,
+			headers: r.Header,
+			body:    body,
+			logger:  box.Logger(),
+			Writer:  w,
+			Request: r,
+		}
+		result := box.dispatch(handler, ctx)
+		if result != nil {
+			body := ctx.render(result)
+			if ctx.bytes != nil {
+				ctx.writeBinary(w)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if ctx.status != 0 {
+				w.WriteHeader(ctx.status)
+			}
+			w.Write([]byte(body))
+		}
+	}
 }
 
+// Run starts the app and blocks until it quits, either through Box.Quit, a
+// SIGINT/SIGTERM or the last window closing. The shutdown sequence has run
+// by the time Run returns.
 func (box *Box) Run() {
-	fmt.Printf("[velo] version: %s\n", Version)
-	if box.mode == ModeHttp {
-		if len(box.webviews) > 0 {
-			first := box.webviews[0]
-			if mux, ok := first.Mux.(*http.ServeMux); ok {
-				box.mux = mux
-			} else {
-				box.mux = box.setupMux(first.FrontendFS, "")
-			}
-		} else {
-			box.mux = box.setupMux(nil, "")
+	if box.generateFromEnv() {
+		return
+	}
+	box.Logger().Info("starting", "velo", Version, "mode", box.mode.String())
+	box.reportCrashes()
+	if err := registerAssociations(box.appConfig); err != nil {
+		box.Log("associations").Error("registering associations failed", "error", err)
+	}
+	if err := box.startup(); err != nil {
+		box.Log("lifecycle").Error("startup failed", "error", err)
+		box.finish()
+		return
+	}
+	go func() {
+		box.urls.start()
+		box.files.start()
+		if box.instance != nil {
+			box.instance.launches.start()
 		}
-		server := &http.Server{Addr: "127.0.0.1:8080", Handler: box.mux}
-		server.ListenAndServe()
+	}()
+	stop := box.handleSignals()
+	defer stop()
+	defer box.finish()
+	if box.mode == ModeHttp {
+		box.mux = box.serveMux()
+		box.listenAndServe()
 		return
 	}
 
@@ -872,25 +1242,82 @@ func (box *Box) Run() {
 		first := box.webviews[0]
 		pathname := first.Pathname
 		if box.mode == ModeBridgeHttp {
-			if mux, ok := first.Mux.(*http.ServeMux); ok {
-				box.mux = mux
-			} else {
-				// Fallback if Mux is generic handler
-				box.mux = box.setupMux(first.FrontendFS, "")
+			box.mux = box.serveMux()
+			if !box.bindHTTP() {
+				return
 			}
-			go func() {
-				server := &http.Server{Addr: "127.0.0.1:8080", Handler: box.mux}
-				server.ListenAndServe()
-			}()
-			first.URL = "http://127.0.0.1:8080" + pathname
+			first.URL = box.tokenURL(box.httpBase() + pathname)
+			box.webviewInfos[0].URL = first.URL
+			box.refreshRuntime()
+			go box.listenAndServe()
 		} else {
 			first.URL = "velo://localhost" + pathname
 		}
 		webview.OpenWebview(first)
 	} else {
 		box.mux = box.setupMux(nil, "")
-		server := &http.Server{Addr: "127.0.0.1:8080", Handler: box.mux}
-		server.ListenAndServe()
+		box.listenAndServe()
+	}
+}
+
+// serveMux returns the mux of the HTTP server: the one of the first window,
+// which also serves its frontend, or one for the routes alone.
+func (box *Box) serveMux() *http.ServeMux {
+	if len(box.webviews) > 0 {
+		first := box.webviews[0]
+		if mux, ok := first.Mux.(*http.ServeMux); ok {
+			return mux
+		}
+		return box.setupMux(first.FrontendFS, "")
+	}
+	return box.setupMux(nil, "")
+}
+
+// Handler returns the HTTP handler that Run serves in ModeHttp and
+// ModeBridgeHttp, with the routes registered so far, the built-in endpoints
+// and the frontend of the first window.
+func (box *Box) Handler() http.Handler {
+	if box.mux != nil {
+		return box.mux
+	}
+	return box.serveMux()
+}
+
+// bindHTTP binds the HTTP listener, logging the error when it fails.
+func (box *Box) bindHTTP() bool {
+	if _, err := box.listen(); err != nil {
+		box.Log("http").Error("listen failed", "addr", box.addr, "error", err)
+		return false
+	}
+	return true
+}
+
+// refreshRuntime rebuilds the runtime config of the windows created before
+// Run bound the listener, whose http_base still names the configured
+// address rather than the port picked for ":0".
+func (box *Box) refreshRuntime() {
+	for i, opts := range box.webviews {
+		info := box.webviewInfos[i]
+		opts.InjectedJS = box.injectedRuntimeJS(info)
+		opts.RuntimeJSON = box.runtimeJSON(info)
+	}
+}
+
+func (box *Box) listenAndServe() {
+	if !box.bindHTTP() {
+		return
+	}
+	box.Log("http").Info("listening", "addr", box.httpBase())
+	if box.mode == ModeHttp && box.authRequired() {
+		// The token grants access to the API, so it stays out of the logs
+		// that supervisors and CI collect unless SetDebug asked for it.
+		box.Log("http").Info("open the app with its launch token", "url", box.httpBase()+"/", "param", VeloTokenParam)
+		if debugMode.Load() {
+			fmt.Fprintf(os.Stderr, "[velo] open %s\n", box.tokenURL(box.httpBase()+"/"))
+		}
+	}
+	if err := box.serve(box.mux); err != nil {
+		box.Log("http").Error("server stopped", "error", err)
 	}
 }
 
@@ -987,10 +1414,13 @@ func (b *Box) NewWebview(opt *VeloWebviewOpt) *webview.Webview {
 		HasPosition:            hasPosition,
 		Mux:                    mux,
 		FrontendFS:             opt.FrontendFS,
-		HandleMessage:          b.handleMessage,
+		HandleMessage:          b.windowMessageHandler(windowName),
+		HandleWindowMessage:    b.windowCallbackHandler(windowName),
 		HandleDragDrop:         opt.OnDragDrop,
 		HandleReopen:           opt.OnReopen,
+		HandleOpenURL:          b.openURLs,
 		HandleClose:            opt.OnClose,
+		HandleQuitRequest:      b.quitRequested,
 		QuitOnLastWindowClosed: b.quitOnLastWindowClosed,
 		Engine:                 b.webviewEngine,
 		ElectronCommand:        b.appConfig.Desktop.Electron.Command,
@@ -1002,7 +1432,8 @@ func (b *Box) NewWebview(opt *VeloWebviewOpt) *webview.Webview {
 		URL:                    windowURL,
 	}
 	b.webviews = append(b.webviews, opts)
-	wv := webview.NewHandle(windowName, b.webviewEngine)
+	b.webviewInfos = append(b.webviewInfos, windowInfo)
+	wv := b.windowHandle(windowName)
 	b.Webview = wv
 	return wv
 }
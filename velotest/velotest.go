@@ -0,0 +1,327 @@
+// Package velotest drives the handlers of a velo.Box in process, through the
+// same bridge and HTTP paths the pages use, without opening a window or
+// binding a port.
+//
+//	app := velo.NewApp(&velo.VeloAppOpt{Mode: velo.ModeHttp})
+//	app.Post("/api/greet", greet)
+//	c := velotest.New(app)
+//	c.Invoke("/api/greet", velo.H{"name": "velo"}).AssertOK(t).AssertData(t, velo.H{"message": "hi velo"})
+//	c.HTTP("POST", "/api/greet", velo.H{"name": "velo"}).AssertOK(t)
+package velotest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ltaoo/velo"
+)
+
+// eventMessageType is the type of the messages sent by Box.Emit.
+const eventMessageType = "__velo_event"
+
+// Client calls the handlers of a Box.
+type Client struct {
+	box *velo.Box
+	seq atomic.Int64
+}
+
+// New returns a client for box. Register the routes before the first call
+// to HTTP, which serves the routes registered at that time.
+func New(box *velo.Box) *Client {
+	return &Client{box: box}
+}
+
+// Box returns the box the client calls.
+func (c *Client) Box() *velo.Box {
+	return c.box
+}
+
+// Invoke calls method the way the runtime's invoke does over a native
+// bridge, with args as the JSON arguments of a POST call. A []byte args is
+// sent as the binary body of the call.
+func (c *Client) Invoke(method string, args interface{}) *Result {
+	return c.call(method, http.MethodPost, args)
+}
+
+// InvokeGet is Invoke for the handlers registered with Box.Get only.
+func (c *Client) InvokeGet(method string, args interface{}) *Result {
+	return c.call(method, http.MethodGet, args)
+}
+
+func (c *Client) call(method, httpMethod string, args interface{}) *Result {
+	msg := map[string]interface{}{
+		"id":         fmt.Sprintf("velotest-%d", c.seq.Add(1)),
+		"method":     method,
+		"httpMethod": httpMethod,
+	}
+	if data, ok := args.([]byte); ok {
+		msg["binary_base64"] = base64.StdEncoding.EncodeToString(data)
+	} else if args != nil {
+		msg["args"] = args
+	}
+	message, err := json.Marshal(msg)
+	if err != nil {
+		return &Result{err: fmt.Errorf("encode arguments of %s: %w", method, err)}
+	}
+	id, raw := c.box.HandleMessage(string(message))
+	r := &Result{ID: id, Raw: []byte(raw)}
+	if err := json.Unmarshal(r.Raw, &r.BoxResult); err != nil {
+		r.err = fmt.Errorf("decode result of %s: %w; raw=%s", method, err, raw)
+		return r
+	}
+	if r.Binary != "" {
+		resp := c.HTTP(http.MethodGet, velo.VeloTransferPath+r.Binary, nil)
+		if resp.Status != http.StatusOK {
+			r.err = fmt.Errorf("fetch binary result of %s: status %d", method, resp.Status)
+		}
+		r.Bytes = resp.Bytes
+	}
+	return r
+}
+
+// HTTP sends a request to the HTTP handler of the box with the velo token.
+// body is sent as is when it is a string, []byte or io.Reader and encoded
+// as JSON otherwise; path may hold a query string.
+func (c *Client) HTTP(method, path string, body interface{}) *Result {
+	req := c.NewRequest(method, path, body)
+	rec := httptest.NewRecorder()
+	c.box.Handler().ServeHTTP(rec, req)
+	r := &Result{Status: rec.Code, Raw: rec.Body.Bytes()}
+	if strings.Contains(rec.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(r.Raw, &r.BoxResult); err != nil {
+			r.err = fmt.Errorf("decode response of %s %s: %w; body=%s", method, path, err, r.Raw)
+		}
+	} else {
+		r.Bytes = r.Raw
+	}
+	return r
+}
+
+// NewRequest builds the request sent by HTTP, for tests that need to set
+// more headers and serve it with Box.Handler themselves.
+func (c *Client) NewRequest(method, path string, body interface{}) *http.Request {
+	var reader io.Reader
+	contentType := ""
+	switch v := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = v
+		contentType = "application/octet-stream"
+	case []byte:
+		reader = bytes.NewReader(v)
+		contentType = "application/octet-stream"
+	case string:
+		reader = strings.NewReader(v)
+		contentType = "text/plain"
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("velotest: encode body of %s %s: %v", method, path, err))
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set(velo.VeloTokenHeader, c.box.Token())
+	return req
+}
+
+// Result is the outcome of a call.
+type Result struct {
+	velo.BoxResult
+	// ID is the callback id of a bridge call.
+	ID string
+	// Status is the HTTP status of the response; it is 0 for bridge calls.
+	Status int
+	// Raw is the response body, or the JSON result of a bridge call.
+	Raw []byte
+	// Bytes is the binary result of the handler.
+	Bytes []byte
+
+	err error
+}
+
+// Err reports a call whose result could not be decoded.
+func (r *Result) Err() error {
+	return r.err
+}
+
+// Bind decodes the data of the result into v.
+func (r *Result) Bind(v interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return roundTrip(r.Data, v)
+}
+
+// AssertOK fails the test unless the call succeeded.
+func (r *Result) AssertOK(t testing.TB) *Result {
+	t.Helper()
+	r.assertDecoded(t)
+	if r.Code != velo.CodeOK {
+		t.Fatalf("result code = %d (%s), want %d; msg=%q", r.Code, r.Reason, velo.CodeOK, r.Msg)
+	}
+	return r
+}
+
+// AssertCode fails the test unless the result has code.
+func (r *Result) AssertCode(t testing.TB, code int) *Result {
+	t.Helper()
+	r.assertDecoded(t)
+	if r.Code != code {
+		t.Fatalf("result code = %d, want %d; msg=%q", r.Code, code, r.Msg)
+	}
+	return r
+}
+
+// AssertError fails the test unless the call failed with the code and
+// reason of err, e.g. velo.ErrNotFound.
+func (r *Result) AssertError(t testing.TB, err *velo.Error) *Result {
+	t.Helper()
+	r.assertDecoded(t)
+	if r.Code != err.Code || r.Reason != err.Reason {
+		t.Fatalf("result = %d/%q, want %d/%q; msg=%q", r.Code, r.Reason, err.Code, err.Reason, r.Msg)
+	}
+	return r
+}
+
+// AssertStatus fails the test unless the response has status.
+func (r *Result) AssertStatus(t testing.TB, status int) *Result {
+	t.Helper()
+	if r.Status != status {
+		t.Fatalf("status = %d, want %d; body=%s", r.Status, status, r.Raw)
+	}
+	return r
+}
+
+// AssertData fails the test unless the data of the result and want encode
+// to the same JSON.
+func (r *Result) AssertData(t testing.TB, want interface{}) *Result {
+	t.Helper()
+	r.assertDecoded(t)
+	var got, expected interface{}
+	if err := roundTrip(r.Data, &got); err != nil {
+		t.Fatalf("encode result data: %v", err)
+	}
+	if err := roundTrip(want, &expected); err != nil {
+		t.Fatalf("encode wanted data: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		g, _ := json.Marshal(got)
+		w, _ := json.Marshal(expected)
+		t.Fatalf("result data = %s, want %s", g, w)
+	}
+	return r
+}
+
+func (r *Result) assertDecoded(t testing.TB) {
+	t.Helper()
+	if r.err != nil {
+		t.Fatalf("%v", r.err)
+	}
+}
+
+func roundTrip(v interface{}, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Subscription records the messages sent to the pages with Box.SendMessage
+// and the events of Box.Emit and Box.EmitTo.
+type Subscription struct {
+	remove func()
+
+	mu       sync.Mutex
+	messages []json.RawMessage
+	changed  chan struct{}
+}
+
+// Subscribe starts recording the messages sent to the pages.
+func (c *Client) Subscribe() *Subscription {
+	s := &Subscription{changed: make(chan struct{})}
+	s.remove = c.box.OnSendMessage(func(message interface{}) {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, data)
+		close(s.changed)
+		s.changed = make(chan struct{})
+		s.mu.Unlock()
+	})
+	return s
+}
+
+// Close stops recording.
+func (s *Subscription) Close() {
+	s.remove()
+}
+
+// Messages returns the messages recorded so far, as JSON.
+func (s *Subscription) Messages() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.messages...)
+}
+
+// Events returns the events recorded so far.
+func (s *Subscription) Events() []velo.Event {
+	var events []velo.Event
+	for _, m := range s.Messages() {
+		if e, ok := decodeEvent(m); ok {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// WaitEvent waits up to timeout for an event called name and returns the
+// first one recorded.
+func (s *Subscription) WaitEvent(name string, timeout time.Duration) (velo.Event, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		messages, changed := s.messages, s.changed
+		s.mu.Unlock()
+		for _, m := range messages {
+			if e, ok := decodeEvent(m); ok && e.Name == name {
+				return e, true
+			}
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return velo.Event{}, false
+		}
+	}
+}
+
+func decodeEvent(message json.RawMessage) (velo.Event, bool) {
+	var m struct {
+		Type string `json:"type"`
+		velo.Event
+	}
+	if err := json.Unmarshal(message, &m); err != nil || m.Type != eventMessageType {
+		return velo.Event{}, false
+	}
+	return m.Event, true
+}
@@ -0,0 +1,109 @@
+package velotest
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ltaoo/velo"
+)
+
+func newTestApp() *velo.Box {
+	app := velo.NewApp(&velo.VeloAppOpt{Mode: velo.ModeHttp})
+	app.Post("/api/greet", func(c *velo.BoxContext) interface{} {
+		var args struct {
+			Name string `json:"name"`
+		}
+		if err := c.BindJSON(&args); err != nil {
+			return velo.ErrBadRequest.Wrap(err)
+		}
+		if args.Name == "" {
+			return velo.ErrBadRequest.WithMessage("missing name")
+		}
+		app.Emit("greeted", velo.H{"name": args.Name})
+		return c.Ok(velo.H{"message": "hi " + args.Name})
+	})
+	app.Get("/api/echo", func(c *velo.BoxContext) interface{} {
+		return c.Ok(velo.H{"q": c.Query("q")})
+	})
+	app.Post("/api/reverse", func(c *velo.BoxContext) interface{} {
+		body := c.Binary()
+		out := make([]byte, len(body))
+		for i, b := range body {
+			out[len(body)-1-i] = b
+		}
+		return out
+	})
+	return app
+}
+
+func TestInvokeAndHTTPReachTheSameHandler(t *testing.T) {
+	c := New(newTestApp())
+	want := velo.H{"message": "hi velo"}
+
+	r := c.Invoke("/api/greet", velo.H{"name": "velo"}).AssertOK(t).AssertData(t, want)
+	if r.ID == "" {
+		t.Fatalf("bridge call has no callback id")
+	}
+	c.HTTP(http.MethodPost, "/api/greet", velo.H{"name": "velo"}).AssertStatus(t, http.StatusOK).AssertOK(t).AssertData(t, want)
+
+	c.Invoke("/api/greet", velo.H{}).AssertError(t, velo.ErrBadRequest)
+	c.HTTP(http.MethodPost, "/api/greet", velo.H{}).AssertStatus(t, http.StatusBadRequest).AssertError(t, velo.ErrBadRequest)
+
+	c.InvokeGet("/api/echo?q=1", nil).AssertOK(t).AssertData(t, velo.H{"q": "1"})
+	c.HTTP(http.MethodGet, "/api/echo?q=1", nil).AssertOK(t).AssertData(t, velo.H{"q": "1"})
+	c.Invoke("/api/missing", nil).AssertError(t, velo.ErrUnknownMethod)
+
+	var out struct {
+		Message string `json:"message"`
+	}
+	if err := r.Bind(&out); err != nil || out.Message != "hi velo" {
+		t.Fatalf("Bind = %+v, %v", out, err)
+	}
+}
+
+func TestBinaryArgumentsAndResults(t *testing.T) {
+	c := New(newTestApp())
+
+	r := c.Invoke("/api/reverse", []byte("abc")).AssertOK(t)
+	if !bytes.Equal(r.Bytes, []byte("cba")) {
+		t.Fatalf("bridge bytes = %q, want cba", r.Bytes)
+	}
+	r = c.HTTP(http.MethodPost, "/api/reverse", []byte("abc")).AssertStatus(t, http.StatusOK)
+	if !bytes.Equal(r.Bytes, []byte("cba")) {
+		t.Fatalf("http bytes = %q, want cba", r.Bytes)
+	}
+}
+
+func TestSubscribeRecordsEvents(t *testing.T) {
+	c := New(newTestApp())
+	sub := c.Subscribe()
+	defer sub.Close()
+
+	c.Invoke("/api/greet", velo.H{"name": "velo"}).AssertOK(t)
+	c.Box().SendMessage(velo.H{"type": "custom"})
+
+	e, ok := sub.WaitEvent("greeted", time.Second)
+	if !ok {
+		t.Fatalf("no greeted event; messages=%s", sub.Messages())
+	}
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := e.Bind(&payload); err != nil || payload.Name != "velo" {
+		t.Fatalf("payload = %+v, %v", payload, err)
+	}
+	if n := len(sub.Messages()); n != 2 {
+		t.Fatalf("messages = %d, want 2", n)
+	}
+	if n := len(sub.Events()); n != 1 {
+		t.Fatalf("events = %d, want 1", n)
+	}
+
+	sub.Close()
+	c.Box().Emit("later", nil)
+	if n := len(sub.Messages()); n != 2 {
+		t.Fatalf("messages after Close = %d, want 2", n)
+	}
+}
@@ -0,0 +1,142 @@
+package webview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCallTimeout bounds a Call whose context has no deadline, so that a
+// page that never answers does not block the caller forever.
+const DefaultCallTimeout = 30 * time.Second
+
+// ErrNoPage is returned by Call when no page could receive the request.
+var ErrNoPage = errors.New("webview: no page to call")
+
+// CallError is a JavaScript error thrown or rejected by an exposed function.
+type CallError struct {
+	Fn      string
+	Message string
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("webview: %s: %s", e.Fn, e.Message)
+}
+
+type callRequest struct {
+	Type   string        `json:"type"`
+	ID     string        `json:"id"`
+	Fn     string        `json:"fn"`
+	Args   []interface{} `json:"args"`
+	Window string        `json:"window,omitempty"`
+}
+
+type callResult struct {
+	result json.RawMessage
+	err    string
+}
+
+// pendingCall is a Call waiting for the reply of the page in window.
+type pendingCall struct {
+	window string
+	done   chan callResult
+}
+
+var (
+	callSeq      uint64
+	callMu       sync.Mutex
+	pendingCalls = make(map[string]*pendingCall)
+)
+
+// WithMessageRelay returns a copy of w whose Call also delivers requests
+// through relay, to pages the native bridge does not reach such as
+// WebSocket clients.
+func (w *Webview) WithMessageRelay(relay func(payload []byte) bool) *Webview {
+	c := Webview{}
+	if w != nil {
+		c = *w
+	}
+	c.relay = relay
+	return &c
+}
+
+// Call invokes the function the page registered with velo.expose(fn, ...)
+// and waits for its result. Arguments are encoded as JSON. ctx bounds the
+// wait, or DefaultCallTimeout when it has no deadline; a JavaScript
+// exception is returned as a *CallError.
+func (w *Webview) Call(ctx context.Context, fn string, args ...interface{}) (json.RawMessage, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+		defer cancel()
+	}
+	id := "call-" + strconv.FormatUint(atomic.AddUint64(&callSeq, 1), 10)
+	window := w.windowName()
+	payload, err := json.Marshal(callRequest{
+		Type:   "__velo_call",
+		ID:     id,
+		Fn:     fn,
+		Args:   args,
+		Window: window,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan callResult, 1)
+	callMu.Lock()
+	pendingCalls[id] = &pendingCall{window: normalizeWindowName(window), done: done}
+	callMu.Unlock()
+	defer func() {
+		callMu.Lock()
+		delete(pendingCalls, id)
+		callMu.Unlock()
+	}()
+
+	delivered := w.webviewBackend().SendMessage(string(payload))
+	if w != nil && w.relay != nil && w.relay(payload) {
+		delivered = true
+	}
+	if !delivered {
+		return nil, ErrNoPage
+	}
+
+	select {
+	case r := <-done:
+		if r.err != "" {
+			return nil, &CallError{Fn: fn, Message: r.err}
+		}
+		return r.result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ResolveCall completes the pending Call with the given id, if it was made
+// to window. errMessage is the message of a JavaScript error, empty on
+// success. It reports whether a call was waiting for the result; a reply
+// from another window leaves the call pending.
+func ResolveCall(id, window string, result json.RawMessage, errMessage string) bool {
+	callMu.Lock()
+	call, ok := pendingCalls[id]
+	if ok && call.window != normalizeWindowName(window) {
+		ok = false
+	}
+	if ok {
+		delete(pendingCalls, id)
+	}
+	callMu.Unlock()
+	if !ok {
+		return false
+	}
+	call.done <- callResult{result: result, err: errMessage}
+	return true
+}
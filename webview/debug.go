@@ -1,9 +1,12 @@
 package webview
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 )
@@ -12,6 +15,7 @@ var (
 	debugMode     atomic.Bool
 	debugOutput   io.Writer = os.Stderr
 	debugOutputMu sync.Mutex
+	logger        atomic.Pointer[slog.Logger]
 )
 
 // SetDebug enables or disables diagnostic logging from the webview package.
@@ -20,11 +24,25 @@ func SetDebug(enabled bool) {
 	debugMode.Store(enabled)
 }
 
+// SetLogger routes the package's diagnostics through l: debug messages at
+// slog.LevelDebug, whatever SetDebug says, and warnings at slog.LevelWarn.
+// Without a logger, debug messages go to stderr when SetDebug is on and
+// warnings to stdout.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
 func debugEnabled() bool {
 	return debugMode.Load()
 }
 
 func debugln(args ...interface{}) {
+	if l := logger.Load(); l != nil {
+		if l.Enabled(context.Background(), slog.LevelDebug) {
+			l.Debug(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+		}
+		return
+	}
 	if !debugEnabled() {
 		return
 	}
@@ -34,6 +52,12 @@ func debugln(args ...interface{}) {
 }
 
 func debugf(format string, args ...interface{}) {
+	if l := logger.Load(); l != nil {
+		if l.Enabled(context.Background(), slog.LevelDebug) {
+			l.Debug(strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+		}
+		return
+	}
 	if !debugEnabled() {
 		return
 	}
@@ -41,3 +65,19 @@ func debugf(format string, args ...interface{}) {
 	defer debugOutputMu.Unlock()
 	fmt.Fprintf(debugOutput, format, args...)
 }
+
+func warnln(args ...interface{}) {
+	if l := logger.Load(); l != nil {
+		l.Warn(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+		return
+	}
+	fmt.Println(args...)
+}
+
+func errorln(args ...interface{}) {
+	if l := logger.Load(); l != nil {
+		l.Error(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+		return
+	}
+	fmt.Fprintln(os.Stderr, append([]interface{}{"ERROR:"}, args...)...)
+}
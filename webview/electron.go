@@ -199,6 +199,15 @@ func (b *electronBackend) Close(name string) {
 	b.windowControl(name, "__velo/window/close", nil)
 }
 
+func (b *electronBackend) Quit() {
+	if !b.running() {
+		return
+	}
+	if err := b.sendCommand(electronCommand{Type: "quit"}); err != nil {
+		fmt.Fprintf(os.Stderr, "[velo] electron quit: %v\n", err)
+	}
+}
+
 func (b *electronBackend) windowControl(name, method string, args interface{}) {
 	if !b.running() {
 		return
@@ -433,6 +442,10 @@ func (b *electronBackend) handleEvent(w http.ResponseWriter, r *http.Request) {
 		if opts != nil && opts.HandleClose != nil {
 			go opts.HandleClose(name)
 		}
+	case "quit_request":
+		if opts := b.windowOptions(name); opts != nil && opts.HandleQuitRequest != nil {
+			go opts.HandleQuitRequest(name)
+		}
 	case "drag_drop":
 		if opts := b.windowOptions(name); opts != nil && opts.HandleDragDrop != nil {
 			go opts.HandleDragDrop(event.Event, event.Payload)
@@ -583,6 +596,9 @@ const config = JSON.parse(fs.readFileSync(configPath, "utf8"));
 const preloadPath = path.join(configDir, "preload.js");
 const windowsByName = new Map();
 const namesByWebContents = new Map();
+// Set once the app is quitting, so that closing the last window no longer
+// asks Go first.
+let quitting = false;
 
 function safeName(name) {
   return String(name || "default").replace(/[^a-zA-Z0-9_.-]/g, "_");
@@ -603,6 +619,10 @@ function wsURLForWindow(windowConfig) {
     url.pathname = "/__velo/ws";
     url.search = "";
     url.hash = "";
+    const token = runtimeInfoForWindow(windowConfig).token;
+    if (token) {
+      url.searchParams.set("velo_token", token);
+    }
     return url.toString();
   } catch (_) {
     return "ws://127.0.0.1:8080/__velo/ws";
@@ -645,6 +665,64 @@ function postWindowState(name, win) {
   });
 }
 
+const consoleLevels = ["debug", "info", "warn", "error"];
+const consoleLevelRanks = { debug: 0, log: 1, info: 2, warn: 3, error: 4 };
+
+// forwardConsole sends the console messages of win, which include uncaught
+// errors, to the Go log when desktop.frontend_logs is enabled.
+function forwardConsole(name, win, runtimeInfo) {
+  const options = runtimeInfo.frontend_logs;
+  if (!options || !config.http_base || typeof fetch !== "function") {
+    return;
+  }
+  const min = options.level in consoleLevelRanks ? consoleLevelRanks[options.level] : consoleLevelRanks.warn;
+  let entries = [];
+  let timer = null;
+  let url = "";
+  const flush = () => {
+    if (timer) {
+      clearTimeout(timer);
+      timer = null;
+    }
+    if (!entries.length) {
+      return;
+    }
+    const headers = { "content-type": "application/json" };
+    if (runtimeInfo.token) {
+      headers["X-Velo-Token"] = runtimeInfo.token;
+    }
+    fetch(config.http_base + "/__velo/log", {
+      method: "POST",
+      headers,
+      body: JSON.stringify({ window: name, url, entries })
+    }).catch(() => {});
+    entries = [];
+  };
+  win.webContents.on("console-message", (event, level, message, line, sourceId) => {
+    // Electron 35 passes the message on the event instead of as arguments.
+    const details = typeof level === "number" ? { level, message, lineNumber: line, sourceId } : event;
+    const levelName = typeof details.level === "number" ? consoleLevels[details.level] : details.level === "warning" ? "warn" : details.level;
+    if (!(levelName in consoleLevelRanks) || consoleLevelRanks[levelName] < min) {
+      return;
+    }
+    url = win.webContents.getURL();
+    entries.push({
+      level: levelName,
+      kind: "console",
+      message: String(details.message || ""),
+      source: details.sourceId || "",
+      line: details.lineNumber || 0,
+      time: Date.now()
+    });
+    if (entries.length >= 50) {
+      flush();
+    } else if (!timer) {
+      timer = setTimeout(flush, 1000);
+    }
+  });
+  win.on("closed", flush);
+}
+
 function createWindow(windowConfig) {
   const name = windowConfig.name || "default";
   const existing = windowsByName.get(name);
@@ -690,6 +768,7 @@ function createWindow(windowConfig) {
   const win = new BrowserWindow(options);
   windowsByName.set(name, win);
   namesByWebContents.set(win.webContents.id, name);
+  forwardConsole(name, win, runtimeInfoForWindow(windowConfig));
 
   let stateTimer = null;
   const scheduleState = () => {
@@ -700,7 +779,14 @@ function createWindow(windowConfig) {
   };
   win.on("resize", scheduleState);
   win.on("move", scheduleState);
-  win.on("close", () => postWindowState(name, win));
+  win.on("close", (event) => {
+    postWindowState(name, win);
+    // The last window stays open until Go has asked whether to quit.
+    if (!quitting && config.quit_on_last_window_closed !== false && BrowserWindow.getAllWindows().length === 1) {
+      event.preventDefault();
+      postEvent({ type: "quit_request", name });
+    }
+  });
   win.on("closed", () => {
     postEvent({ type: "window_closed", name });
     windowsByName.delete(name);
@@ -819,6 +905,10 @@ ipcMain.on("velo-drag-drop", (event, payload) => {
   });
 });
 
+app.on("before-quit", () => {
+  quitting = true;
+});
+
 app.on("window-all-closed", () => {
   if (config.quit_on_last_window_closed !== false) {
     app.quit();
@@ -877,6 +967,10 @@ app.whenReady().then(() => {
     }
     if (command.type === "window_control") {
       handleWindowControl(windowForName(command.name || "default"), command.method, command.args);
+      return;
+    }
+    if (command.type === "quit") {
+      app.quit();
     }
   });
 });
@@ -905,10 +999,49 @@ const messageHandlers = [];
 let socket = null;
 let connecting = null;
 
+function isBinary(value) {
+  return value instanceof ArrayBuffer || ArrayBuffer.isView(value) ||
+    (typeof Blob !== "undefined" && value instanceof Blob);
+}
+
+function toArrayBuffer(value) {
+  if (value instanceof ArrayBuffer) {
+    return Promise.resolve(value);
+  }
+  if (ArrayBuffer.isView(value)) {
+    return Promise.resolve(value.buffer.slice(value.byteOffset, value.byteOffset + value.byteLength));
+  }
+  return value.arrayBuffer();
+}
+
+// Binary frames carry a big-endian uint32 header length, the JSON header
+// and the raw bytes, matching encodeBinaryMessage in Go.
+function encodeBinary(payload, buffer) {
+  const header = new TextEncoder().encode(JSON.stringify(payload));
+  const out = new Uint8Array(4 + header.length + buffer.byteLength);
+  new DataView(out.buffer).setUint32(0, header.length);
+  out.set(header, 4);
+  out.set(new Uint8Array(buffer), 4 + header.length);
+  return out.buffer;
+}
+
+function decodeBinary(buffer) {
+  const size = new DataView(buffer).getUint32(0);
+  const packet = JSON.parse(new TextDecoder().decode(new Uint8Array(buffer, 4, size)));
+  if (packet && packet.result && typeof packet.result === "object") {
+    packet.result.data = buffer.slice(4 + size);
+  }
+  return packet;
+}
+
 function handlePacket(data) {
   let packet = null;
   try {
-    packet = typeof data === "string" ? JSON.parse(data) : data;
+    if (data instanceof ArrayBuffer) {
+      packet = decodeBinary(data);
+    } else {
+      packet = typeof data === "string" ? JSON.parse(data) : data;
+    }
   } catch (_) {
     return;
   }
@@ -924,6 +1057,18 @@ function handlePacket(data) {
     return;
   }
   if (packet.type === "__velo_message") {
+    if (packet.payload && packet.payload.type === "__velo_before_quit") {
+      handleBeforeQuit(packet.payload.id);
+      return;
+    }
+    if (packet.payload && packet.payload.type === "__velo_event") {
+      handleEvent(packet.payload);
+      return;
+    }
+    if (packet.payload && packet.payload.type === "__velo_call") {
+      handleCall(packet.payload);
+      return;
+    }
     for (const handler of messageHandlers.slice()) {
       try {
         handler(packet.payload);
@@ -962,6 +1107,7 @@ function ensureSocket() {
     let nextSocket = null;
     try {
       nextSocket = new WebSocket(wsURL);
+      nextSocket.binaryType = "arraybuffer";
     } catch (error) {
       finish(reject, error);
       return;
@@ -1007,14 +1153,15 @@ function invoke(method, options) {
       }
       resolve(result);
     };
+    const binary = isBinary(options.body) ? options.body : isBinary(options.args) ? options.args : null;
     const payload = {
       id,
       method,
       headers: options.headers,
-      args: options.args
+      args: binary === options.args ? undefined : options.args
     };
-    ensureSocket().then((ws) => {
-      ws.send(JSON.stringify(payload));
+    Promise.all([ensureSocket(), binary ? toArrayBuffer(binary) : null]).then(([ws, buffer]) => {
+      ws.send(buffer ? encodeBinary(payload, buffer) : JSON.stringify(payload));
     }).catch((error) => {
       delete callbacks[id];
       reject(error);
@@ -1030,10 +1177,186 @@ function onGoMessage(handler) {
   ensureSocket().catch(() => {});
 }
 
+const pageID = String(Date.now()) + Math.random().toString(16).slice(2);
+const beforeQuitHandlers = [];
+
+function setQuitGuard(active) {
+  invoke("/__velo/lifecycle/guard", { args: { page: pageID, active } }).catch(() => {});
+}
+
+function handleBeforeQuit(id) {
+  const answers = beforeQuitHandlers.slice().map((handler) => {
+    try {
+      return Promise.resolve(handler()).catch(() => true);
+    } catch (_) {
+      return Promise.resolve(true);
+    }
+  });
+  Promise.all(answers).then((values) => {
+    const allow = values.every((v) => v !== false);
+    invoke("/__velo/lifecycle/reply", { args: { id, page: pageID, allow } }).catch(() => {});
+  });
+}
+
+function onBeforeQuit(handler) {
+  if (typeof handler !== "function") {
+    return () => {};
+  }
+  beforeQuitHandlers.push(handler);
+  if (beforeQuitHandlers.length === 1) {
+    setQuitGuard(true);
+  }
+  return () => {
+    const i = beforeQuitHandlers.indexOf(handler);
+    if (i === -1) {
+      return;
+    }
+    beforeQuitHandlers.splice(i, 1);
+    if (beforeQuitHandlers.length === 0) {
+      setQuitGuard(false);
+    }
+  };
+}
+
+const eventListeners = new Map();
+
+function windowName() {
+  return (runtimeInfo.window && runtimeInfo.window.name) || "default";
+}
+
+function handleEvent(packet) {
+  if (packet.source && packet.source === pageID) {
+    return;
+  }
+  if (packet.window && packet.window !== windowName()) {
+    return;
+  }
+  dispatchVeloEvent(packet.name, packet.payload);
+}
+
+function dispatchVeloEvent(name, payload) {
+  for (const handler of (eventListeners.get(name) || []).slice()) {
+    try {
+      handler(payload);
+    } catch (_) {}
+  }
+}
+
+function on(name, handler) {
+  if (typeof handler !== "function") {
+    return () => {};
+  }
+  if (!eventListeners.has(name)) {
+    eventListeners.set(name, []);
+  }
+  eventListeners.get(name).push(handler);
+  ensureSocket().catch(() => {});
+  return () => off(name, handler);
+}
+
+function off(name, handler) {
+  const list = eventListeners.get(name);
+  if (!list) {
+    return;
+  }
+  if (!handler) {
+    eventListeners.delete(name);
+    return;
+  }
+  const i = list.indexOf(handler);
+  if (i !== -1) {
+    list.splice(i, 1);
+  }
+  if (list.length === 0) {
+    eventListeners.delete(name);
+  }
+}
+
+function once(name, handler) {
+  const remove = on(name, (payload) => {
+    remove();
+    handler(payload);
+  });
+  return remove;
+}
+
+function emit(name, payload) {
+  dispatchVeloEvent(name, payload);
+  return invoke("/__velo/events/emit", {
+    args: { name, payload, window: windowName(), source: pageID }
+  });
+}
+
+const exposedFunctions = new Map();
+
+function expose(name, fn) {
+  if (typeof fn !== "function") {
+    return () => {};
+  }
+  exposedFunctions.set(name, fn);
+  ensureSocket().catch(() => {});
+  return () => {
+    if (exposedFunctions.get(name) === fn) {
+      exposedFunctions.delete(name);
+    }
+  };
+}
+
+function replyCall(id, body) {
+  invoke("/__velo/call/reply", { args: Object.assign({ id, window: windowName() }, body) }).catch(() => {});
+}
+
+function handleCall(packet) {
+  if (packet.window && packet.window !== windowName()) {
+    return;
+  }
+  const fn = exposedFunctions.get(packet.fn);
+  if (!fn) {
+    replyCall(packet.id, { error: "no function exposed as " + JSON.stringify(packet.fn) });
+    return;
+  }
+  new Promise((resolve) => resolve(fn(...(packet.args || [])))).then(
+    (result) => replyCall(packet.id, { result: result === undefined ? null : result }),
+    (err) => replyCall(packet.id, { error: String((err && err.message) || err || "error") })
+  );
+}
+
+window.addEventListener("pagehide", () => {
+  if (beforeQuitHandlers.length) {
+    setQuitGuard(false);
+  }
+});
+
+// Methods bound with Box.Bind resolve with the result data and reject with
+// an Error carrying the result code, reason and details.
+function buildServices() {
+  const services = {};
+  for (const name of Object.keys(runtimeInfo.services || {})) {
+    services[name] = {};
+    for (const method of runtimeInfo.services[name]) {
+      const url = "/svc/" + name + "/" + method;
+      services[name][method] = (args, options) =>
+        invoke(url, Object.assign({ method: "POST" }, options, { args })).then((result) => {
+          if (!result || result.code !== 0) {
+            const error = new Error((result && result.msg) || "service call failed");
+            error.code = result && result.code;
+            error.reason = result && result.reason;
+            error.details = result && result.details;
+            throw error;
+          }
+          return result.data;
+        });
+    }
+  }
+  return services;
+}
+
 contextBridge.exposeInMainWorld("__VELO__", runtimeInfo);
 contextBridge.exposeInMainWorld("invoke", invoke);
 contextBridge.exposeInMainWorld("goCall", invoke);
 contextBridge.exposeInMainWorld("onGoMessage", onGoMessage);
+contextBridge.exposeInMainWorld("onBeforeQuit", onBeforeQuit);
+contextBridge.exposeInMainWorld("velo", { invoke, on, off, once, emit, expose, onBeforeQuit, services: buildServices() });
 
 window.addEventListener("drop", (event) => {
   const files = [];
@@ -0,0 +1,413 @@
+package webview
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// HeadlessWindow is the state of a window of EngineHeadless.
+type HeadlessWindow struct {
+	Name        string
+	URL         string
+	Title       string
+	Width       int
+	Height      int
+	MinWidth    int
+	MinHeight   int
+	MaxWidth    int
+	MaxHeight   int
+	X           int
+	Y           int
+	Visible     bool
+	Minimized   bool
+	Maximized   bool
+	Fullscreen  bool
+	AlwaysOnTop bool
+}
+
+// HeadlessCall is a window method called on EngineHeadless, e.g.
+// {Window: "main", Method: "SetSize", Args: []interface{}{800, 600}}.
+type HeadlessCall struct {
+	Window string
+	Method string
+	Args   []interface{}
+}
+
+// HeadlessCallback is a result sent to a page before the final reply, such
+// as a chunk of a streamed response. Window is the window it was sent to,
+// empty for SendCallback, which has no target window.
+type HeadlessCallback struct {
+	Window string
+	ID     string
+	Result string
+}
+
+// HeadlessBackend inspects and drives the in-memory windows of
+// EngineHeadless, which runs an app without a display, e.g. in tests and CI.
+type HeadlessBackend struct {
+	b *headlessBackend
+}
+
+// Headless returns the backend of EngineHeadless.
+func Headless() *HeadlessBackend {
+	return &HeadlessBackend{b: headlessWebview}
+}
+
+// Windows returns the open windows in the order they were opened.
+func (h *HeadlessBackend) Windows() []HeadlessWindow {
+	b := h.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	windows := make([]HeadlessWindow, 0, len(b.order))
+	for _, name := range b.order {
+		windows = append(windows, b.windows[name].state)
+	}
+	return windows
+}
+
+// Window returns the state of the open window name.
+func (h *HeadlessBackend) Window(name string) (HeadlessWindow, bool) {
+	b := h.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, ok := b.windows[normalizeWindowName(name)]
+	if !ok {
+		return HeadlessWindow{}, false
+	}
+	return w.state, true
+}
+
+// WaitWindow waits until the window name is open, e.g. after starting
+// Box.Run in a goroutine.
+func (h *HeadlessBackend) WaitWindow(ctx context.Context, name string) (HeadlessWindow, error) {
+	for {
+		h.b.mu.Lock()
+		w, ok := h.b.windows[normalizeWindowName(name)]
+		changed := h.b.changed
+		h.b.mu.Unlock()
+		if ok {
+			return w.state, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return HeadlessWindow{}, ctx.Err()
+		}
+	}
+}
+
+// Calls returns the window methods called so far, oldest first.
+func (h *HeadlessBackend) Calls() []HeadlessCall {
+	h.b.mu.Lock()
+	defer h.b.mu.Unlock()
+	return append([]HeadlessCall(nil), h.b.calls...)
+}
+
+// Messages returns the JSON messages pushed to the pages with SendMessage
+// that have not been taken yet. Messages are queued whether or not a window
+// is open.
+func (h *HeadlessBackend) Messages() []string {
+	h.b.mu.Lock()
+	defer h.b.mu.Unlock()
+	return append([]string(nil), h.b.messages...)
+}
+
+// TakeMessages returns the queued messages and empties the queue.
+func (h *HeadlessBackend) TakeMessages() []string {
+	h.b.mu.Lock()
+	defer h.b.mu.Unlock()
+	messages := h.b.messages
+	h.b.messages = nil
+	return messages
+}
+
+// Callbacks returns the callbacks sent so far, oldest first.
+func (h *HeadlessBackend) Callbacks() []HeadlessCallback {
+	h.b.mu.Lock()
+	defer h.b.mu.Unlock()
+	return append([]HeadlessCallback(nil), h.b.callbacks...)
+}
+
+// PostMessage delivers a bridge message, as sent by the runtime of the page
+// in window, to the window's HandleMessage and returns its reply.
+func (h *HeadlessBackend) PostMessage(window, message string) (id, result string, err error) {
+	h.b.mu.Lock()
+	w, ok := h.b.windows[normalizeWindowName(window)]
+	h.b.mu.Unlock()
+	if !ok {
+		return "", "", fmt.Errorf("webview: no headless window %q", normalizeWindowName(window))
+	}
+	if !w.opts.hasMessageHandler() {
+		return "", "", fmt.Errorf("webview: window %q has no message handler", w.state.Name)
+	}
+	name := w.state.Name
+	id, result = w.opts.handleMessage(message, func(id, result string) {
+		h.b.mu.Lock()
+		h.b.callbacks = append(h.b.callbacks, HeadlessCallback{Window: name, ID: id, Result: result})
+		h.b.mu.Unlock()
+	})
+	return id, result, nil
+}
+
+// Reset closes the windows without calling their close handlers and clears
+// the recorded calls, messages and callbacks.
+func (h *HeadlessBackend) Reset() {
+	b := h.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.windows = make(map[string]*headlessWindow)
+	b.order = nil
+	b.calls = nil
+	b.messages = nil
+	b.callbacks = nil
+	b.notifyLocked()
+}
+
+type headlessWindow struct {
+	opts  *BoxWebviewOptions
+	state HeadlessWindow
+}
+
+type headlessBackend struct {
+	mu        sync.Mutex
+	windows   map[string]*headlessWindow
+	order     []string
+	calls     []HeadlessCall
+	messages  []string
+	callbacks []HeadlessCallback
+	// changed is closed and replaced whenever a window opens or closes.
+	changed chan struct{}
+	// done is closed by Quit to end OpenWebview.
+	done                   chan struct{}
+	quitOnLastWindowClosed bool
+}
+
+func newHeadlessBackend() *headlessBackend {
+	return &headlessBackend{
+		windows: make(map[string]*headlessWindow),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (b *headlessBackend) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// OpenWebview opens the first window and blocks like a native run loop
+// until Quit, or until the last window closes when QuitOnLastWindowClosed
+// is set.
+func (b *headlessBackend) OpenWebview(opts *BoxWebviewOptions) *Webview {
+	b.mu.Lock()
+	b.done = make(chan struct{})
+	b.quitOnLastWindowClosed = opts.QuitOnLastWindowClosed
+	done := b.done
+	b.mu.Unlock()
+	b.OpenWindow(opts)
+	<-done
+	return nil
+}
+
+func (b *headlessBackend) OpenWindow(opts *BoxWebviewOptions) *Webview {
+	name := normalizeWindowName(opts.Name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.windows[name]; !ok {
+		b.order = append(b.order, name)
+	}
+	b.windows[name] = &headlessWindow{
+		opts: opts,
+		state: HeadlessWindow{
+			Name:    name,
+			URL:     opts.URL,
+			Title:   opts.Title,
+			Width:   opts.Width,
+			Height:  opts.Height,
+			X:       opts.X,
+			Y:       opts.Y,
+			Visible: !opts.Hidden,
+		},
+	}
+	b.calls = append(b.calls, HeadlessCall{Window: name, Method: "OpenWindow", Args: []interface{}{opts.URL}})
+	b.notifyLocked()
+	return NewHandle(name, EngineHeadless)
+}
+
+func (b *headlessBackend) FocusWindow(opts *BoxWebviewOptions) bool {
+	name := normalizeWindowName(opts.Name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, ok := b.windows[name]
+	if !ok {
+		return false
+	}
+	b.calls = append(b.calls, HeadlessCall{Window: name, Method: "FocusWindow"})
+	w.state.Visible = true
+	w.state.Minimized = false
+	if opts.URL != "" && !opts.PreserveStateOnFocus {
+		w.state.URL = opts.URL
+	}
+	return true
+}
+
+func (b *headlessBackend) SendCallback(id, result string) {
+	b.mu.Lock()
+	b.callbacks = append(b.callbacks, HeadlessCallback{ID: id, Result: result})
+	b.mu.Unlock()
+}
+
+func (b *headlessBackend) SendMessage(payload string) bool {
+	b.mu.Lock()
+	b.messages = append(b.messages, payload)
+	b.mu.Unlock()
+	return true
+}
+
+// update records the call and applies fn to the state of the open window
+// name. It reports whether the window is open.
+func (b *headlessBackend) update(name, method string, args []interface{}, fn func(*HeadlessWindow)) bool {
+	name = normalizeWindowName(name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, HeadlessCall{Window: name, Method: method, Args: args})
+	w, ok := b.windows[name]
+	if ok && fn != nil {
+		fn(&w.state)
+	}
+	return ok
+}
+
+func (b *headlessBackend) SetTitle(name, title string) {
+	b.update(name, "SetTitle", []interface{}{title}, func(s *HeadlessWindow) { s.Title = title })
+}
+
+func (b *headlessBackend) SetSize(name string, width, height int) {
+	b.update(name, "SetSize", []interface{}{width, height}, func(s *HeadlessWindow) {
+		s.Width, s.Height = width, height
+	})
+}
+
+func (b *headlessBackend) SetMinSize(name string, width, height int) {
+	b.update(name, "SetMinSize", []interface{}{width, height}, func(s *HeadlessWindow) {
+		s.MinWidth, s.MinHeight = width, height
+	})
+}
+
+func (b *headlessBackend) SetMaxSize(name string, width, height int) {
+	b.update(name, "SetMaxSize", []interface{}{width, height}, func(s *HeadlessWindow) {
+		s.MaxWidth, s.MaxHeight = width, height
+	})
+}
+
+func (b *headlessBackend) SetPosition(name string, x, y int) {
+	b.update(name, "SetPosition", []interface{}{x, y}, func(s *HeadlessWindow) { s.X, s.Y = x, y })
+}
+
+func (b *headlessBackend) GetPosition(name string) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w, ok := b.windows[normalizeWindowName(name)]; ok {
+		return w.state.X, w.state.Y
+	}
+	return 0, 0
+}
+
+func (b *headlessBackend) GetSize(name string) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w, ok := b.windows[normalizeWindowName(name)]; ok {
+		return w.state.Width, w.state.Height
+	}
+	return 0, 0
+}
+
+func (b *headlessBackend) Show(name string) {
+	b.update(name, "Show", nil, func(s *HeadlessWindow) { s.Visible = true })
+}
+
+func (b *headlessBackend) Hide(name string) {
+	b.update(name, "Hide", nil, func(s *HeadlessWindow) { s.Visible = false })
+}
+
+func (b *headlessBackend) Minimize(name string) {
+	b.update(name, "Minimize", nil, func(s *HeadlessWindow) { s.Minimized = true })
+}
+
+func (b *headlessBackend) Maximize(name string) {
+	b.update(name, "Maximize", nil, func(s *HeadlessWindow) { s.Maximized = true })
+}
+
+func (b *headlessBackend) Fullscreen(name string) {
+	b.update(name, "Fullscreen", nil, func(s *HeadlessWindow) { s.Fullscreen = true })
+}
+
+func (b *headlessBackend) UnFullscreen(name string) {
+	b.update(name, "UnFullscreen", nil, func(s *HeadlessWindow) { s.Fullscreen = false })
+}
+
+func (b *headlessBackend) Restore(name string) {
+	b.update(name, "Restore", nil, func(s *HeadlessWindow) {
+		s.Minimized, s.Maximized = false, false
+	})
+}
+
+func (b *headlessBackend) SetAlwaysOnTop(name string, onTop bool) {
+	b.update(name, "SetAlwaysOnTop", []interface{}{onTop}, func(s *HeadlessWindow) { s.AlwaysOnTop = onTop })
+}
+
+func (b *headlessBackend) SetURL(name, url string) {
+	b.update(name, "SetURL", []interface{}{url}, func(s *HeadlessWindow) { s.URL = url })
+}
+
+// Close closes the window, calls its HandleClose and, when it was the last
+// window and QuitOnLastWindowClosed is set, ends OpenWebview. Like a native
+// close request, closing the last window calls HandleQuitRequest instead
+// when it is set.
+func (b *headlessBackend) Close(name string) {
+	name = normalizeWindowName(name)
+	b.mu.Lock()
+	b.calls = append(b.calls, HeadlessCall{Window: name, Method: "Close"})
+	w, ok := b.windows[name]
+	if !ok {
+		b.mu.Unlock()
+		return
+	}
+	if len(b.windows) == 1 && b.quitOnLastWindowClosed && w.opts.HandleQuitRequest != nil {
+		b.mu.Unlock()
+		go w.opts.HandleQuitRequest(name)
+		return
+	}
+	delete(b.windows, name)
+	for i, n := range b.order {
+		if n == name {
+			b.order = append(b.order[:i:i], b.order[i+1:]...)
+			break
+		}
+	}
+	last := len(b.windows) == 0
+	b.notifyLocked()
+	b.mu.Unlock()
+	if w.opts.HandleClose != nil {
+		w.opts.HandleClose(name)
+	}
+	if last && b.quitOnLastWindowClosed {
+		b.Quit()
+	}
+}
+
+// Quit closes every window and ends OpenWebview.
+func (b *headlessBackend) Quit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, HeadlessCall{Method: "Quit"})
+	b.windows = make(map[string]*headlessWindow)
+	b.order = nil
+	b.notifyLocked()
+	select {
+	case <-b.done:
+	default:
+		close(b.done)
+	}
+}
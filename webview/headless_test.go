@@ -0,0 +1,84 @@
+package webview
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeadlessRecordsWindowCalls(t *testing.T) {
+	h := Headless()
+	h.Reset()
+	t.Cleanup(h.Reset)
+
+	var closed []string
+	done := make(chan struct{})
+	go func() {
+		OpenWebview(&BoxWebviewOptions{
+			Name:                   "main",
+			URL:                    "velo://localhost/",
+			Title:                  "Notes",
+			Width:                  800,
+			Height:                 600,
+			Engine:                 EngineHeadless,
+			QuitOnLastWindowClosed: true,
+			HandleMessage: func(message string) (string, string) {
+				return "1", `{"code":0,"echo":` + message + `}`
+			},
+			HandleClose: func(name string) { closed = append(closed, name) },
+		})
+		close(done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := h.WaitWindow(ctx, "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewHandle("main", EngineHeadless)
+	w.SetTitle("Notes - draft")
+	w.SetSize(1024, 768)
+	w.Hide()
+	if got, _ := h.Window("main"); got.Title != "Notes - draft" || got.Width != 1024 || got.Visible {
+		t.Fatalf("window = %+v", got)
+	}
+	if width, height := w.GetSize(); width != 1024 || height != 768 {
+		t.Fatalf("GetSize = %d, %d", width, height)
+	}
+
+	if !SendMessage(map[string]string{"type": "ping"}) {
+		t.Fatal("SendMessage returned false")
+	}
+	if msgs := h.TakeMessages(); len(msgs) != 1 || msgs[0] != `{"type":"ping"}` {
+		t.Fatalf("messages = %q", msgs)
+	}
+	if _, result, err := h.PostMessage("main", `"hi"`); err != nil || result != `{"code":0,"echo":"hi"}` {
+		t.Fatalf("PostMessage = %q, %v", result, err)
+	}
+	if _, _, err := h.PostMessage("settings", `{}`); err == nil {
+		t.Fatal("PostMessage to a closed window succeeded")
+	}
+
+	w.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OpenWebview did not return after the last window closed")
+	}
+	if len(closed) != 1 || closed[0] != "main" {
+		t.Fatalf("closed = %q", closed)
+	}
+	var methods []string
+	for _, c := range h.Calls() {
+		methods = append(methods, c.Method)
+	}
+	want := []string{"OpenWindow", "SetTitle", "SetSize", "Hide", "Close", "Quit"}
+	if len(methods) != len(want) {
+		t.Fatalf("calls = %q, want %q", methods, want)
+	}
+	for i := range want {
+		if methods[i] != want[i] {
+			t.Fatalf("calls = %q, want %q", methods, want)
+		}
+	}
+}
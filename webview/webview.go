@@ -12,22 +12,39 @@ type Engine string
 const (
 	EngineNative   Engine = "native"
 	EngineElectron Engine = "electron"
+	// EngineHeadless keeps windows in memory without a display, for tests
+	// and CI; see Headless.
+	EngineHeadless Engine = "headless"
 )
 
 func NormalizeEngine(engine Engine) Engine {
 	switch engine {
 	case EngineElectron:
 		return EngineElectron
+	case EngineHeadless:
+		return EngineHeadless
 	default:
 		return EngineNative
 	}
 }
 
 type Handler func(message string) (id string, result string)
+
+// WindowHandler handles a bridge message like Handler. send delivers an
+// intermediate callback for an invoke id, such as a stream chunk, to the
+// window the message came from. As BoxWebviewOptions.HandleWindowMessage
+// it takes precedence over HandleMessage.
+type WindowHandler func(message string, send func(id, result string)) (id string, result string)
 type DragDropHandler func(event string, payload string)
 type ReopenHandler func()
+type OpenURLHandler func(urls []string)
 type CloseHandler func(name string)
 
+// QuitRequestHandler is called instead of closing the last window when the
+// user asks to close it and QuitOnLastWindowClosed is set. The window stays
+// open; the handler quits the app, or leaves it running to veto the close.
+type QuitRequestHandler func(name string)
+
 type BoxWebviewOptions struct {
 	ID                     string
 	Name                   string
@@ -45,9 +62,12 @@ type BoxWebviewOptions struct {
 	Mux                    http.Handler
 	FrontendFS             fs.FS
 	HandleMessage          Handler
+	HandleWindowMessage    WindowHandler
 	HandleDragDrop         DragDropHandler
 	HandleReopen           ReopenHandler
+	HandleOpenURL          OpenURLHandler
 	HandleClose            CloseHandler
+	HandleQuitRequest      QuitRequestHandler
 	QuitOnLastWindowClosed bool
 	Engine                 Engine
 	ElectronCommand        string
@@ -59,6 +79,20 @@ type BoxWebviewOptions struct {
 	PreserveStateOnFocus   bool
 }
 
+func (o *BoxWebviewOptions) hasMessageHandler() bool {
+	return o != nil && (o.HandleWindowMessage != nil || o.HandleMessage != nil)
+}
+
+// handleMessage dispatches message to HandleWindowMessage when it is set
+// and to HandleMessage otherwise. send must reach the window the message
+// came from.
+func (o *BoxWebviewOptions) handleMessage(message string, send func(id, result string)) (string, string) {
+	if o.HandleWindowMessage != nil {
+		return o.HandleWindowMessage(message, send)
+	}
+	return o.HandleMessage(message)
+}
+
 type backend interface {
 	OpenWebview(opts *BoxWebviewOptions) *Webview
 	OpenWindow(opts *BoxWebviewOptions) *Webview
@@ -82,12 +116,14 @@ type backend interface {
 	SetAlwaysOnTop(name string, onTop bool)
 	SetURL(name, url string)
 	Close(name string)
+	Quit()
 }
 
 type Webview struct {
 	name    string
 	engine  Engine
 	backend backend
+	relay   func(payload []byte) bool
 }
 
 func NewHandle(name string, engine Engine) *Webview {
@@ -130,11 +166,13 @@ func (nativeBackend) Restore(name string)                       { restore() }
 func (nativeBackend) SetAlwaysOnTop(name string, onTop bool)    { setAlwaysOnTop(onTop) }
 func (nativeBackend) SetURL(name, url string)                   { setURL(url) }
 func (nativeBackend) Close(name string)                         { close_webview() }
+func (nativeBackend) Quit()                                     { Terminate() }
 
 var (
 	backendMu       sync.Mutex
 	nativeWebview   backend = nativeBackend{}
 	electronWebview backend = newElectronBackend()
+	headlessWebview         = newHeadlessBackend()
 	activeWebview   backend = nativeWebview
 )
 
@@ -142,6 +180,8 @@ func backendForEngine(engine Engine) backend {
 	switch NormalizeEngine(engine) {
 	case EngineElectron:
 		return electronWebview
+	case EngineHeadless:
+		return headlessWebview
 	default:
 		return nativeWebview
 	}
@@ -225,6 +265,12 @@ func (w *Webview) SetAlwaysOnTop(onTop bool) {
 func (w *Webview) SetURL(url string) { w.webviewBackend().SetURL(w.windowName(), url) }
 func (w *Webview) Close()            { w.webviewBackend().Close(w.windowName()) }
 
+// Quit closes every window of the active backend and ends its run loop, so
+// that OpenWebview returns.
+func Quit() {
+	currentBackend().Quit()
+}
+
 func SendCallback(id, result string) {
 	currentBackend().SendCallback(id, result)
 }
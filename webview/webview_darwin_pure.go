@@ -68,11 +68,13 @@ func registerNativeClasses() {
 		appDelegateClass := cocoa.AllocateClassPair(cocoa.GetClass("NSObject"), "VeloAppDelegate", 0)
 		cocoa.AddMethod(appDelegateClass, cocoa.RegisterName("applicationShouldTerminateAfterLastWindowClosed:"), applicationShouldTerminateAfterLastWindowClosed, "B@:@")
 		cocoa.AddMethod(appDelegateClass, cocoa.RegisterName("applicationShouldHandleReopen:hasVisibleWindows:"), applicationShouldHandleReopen, "B@:@B")
+		cocoa.AddMethod(appDelegateClass, cocoa.RegisterName("application:openURLs:"), applicationOpenURLs, "v@:@@")
 		cocoa.RegisterClassPair(appDelegateClass)
 		debugln("DEBUG: VeloAppDelegate registered")
 
 		// Register VeloWindowDelegate class for named-window cleanup on close and focus/blur events.
 		windowDelegateClass := cocoa.AllocateClassPair(cocoa.GetClass("NSObject"), "VeloWindowDelegate", 0)
+		cocoa.AddMethod(windowDelegateClass, cocoa.RegisterName("windowShouldClose:"), windowShouldClose, "B@:@")
 		cocoa.AddMethod(windowDelegateClass, cocoa.RegisterName("windowWillClose:"), windowWillClose, "v@:@")
 		cocoa.AddMethod(windowDelegateClass, cocoa.RegisterName("windowDidBecomeKey:"), windowDidBecomeKey, "v@:@")
 		cocoa.AddMethod(windowDelegateClass, cocoa.RegisterName("windowDidResignKey:"), windowDidResignKey, "v@:@")
@@ -122,6 +124,44 @@ func applicationShouldHandleReopen(self, _cmd, app, hasVisibleWindows uintptr) u
 	return 1
 }
 
+// Callback for application:openURLs:, which delivers the links of the app's
+// registered schemes and the files opened with it (as file:// URLs), both
+// when they launch the app and while it runs.
+func applicationOpenURLs(self, _cmd, app, urls uintptr) {
+	if webview_opts == nil || webview_opts.HandleOpenURL == nil || urls == 0 {
+		return
+	}
+	list := cocoa.ID(urls)
+	count := uintptr(list.Send(cocoa.RegisterName("count")))
+	var opened []string
+	for i := uintptr(0); i < count; i++ {
+		urlObj := list.Send(cocoa.RegisterName("objectAtIndex:"), i)
+		if u := cocoa.NSStringToString(urlObj.Send(cocoa.RegisterName("absoluteString"))); u != "" {
+			opened = append(opened, u)
+		}
+	}
+	if len(opened) > 0 {
+		go webview_opts.HandleOpenURL(opened)
+	}
+}
+
+// windowShouldClose: runs for the close button and performClose:. The last
+// window is kept open and handed to HandleQuitRequest, which may veto
+// quitting.
+func windowShouldClose(self, _cmd, sender uintptr) uintptr {
+	mapLock.RLock()
+	wkWebView := windowWebViewMap[sender]
+	last := len(windowWebViewMap) == 1
+	opts := webviewMap[uintptr(wkWebView)]
+	name := webviewNameMap[uintptr(wkWebView)]
+	mapLock.RUnlock()
+	if wkWebView == 0 || !last || !quitOnLastWindowClosed || opts == nil || opts.HandleQuitRequest == nil {
+		return 1
+	}
+	go opts.HandleQuitRequest(name)
+	return 0
+}
+
 func windowWillClose(self, _cmd, notification uintptr) {
 	nsWindow := cocoa.ID(notification).Send(cocoa.RegisterName("object"))
 	cleanupWindow(nsWindow)
@@ -257,20 +297,24 @@ func didReceiveScriptMessage(self, _cmd, userContentController, message uintptr)
 		opts = webview_opts
 	}
 
-	if opts == nil || opts.HandleMessage == nil {
+	if !opts.hasMessageHandler() {
 		return
 	}
 
 	// Handle message in a goroutine to avoid blocking the main thread.
 	// This prevents deadlocks when handlers need to run UI code on the main thread
 	// (e.g. showing a native file dialog via performSelectorOnMainThread).
+	// Callbacks, stream chunks included, go back to this webview.
 	wv := cocoa.ID(webView)
+	send := func(id, result string) {
+		cocoa.DispatchMain(func() {
+			sendCallbackTo(wv, id, result)
+		})
+	}
 	go func() {
-		id, result := opts.HandleMessage(str)
+		id, result := opts.handleMessage(str, send)
 		if id != "" {
-			cocoa.DispatchMain(func() {
-				sendCallbackTo(wv, id, result)
-			})
+			send(id, result)
 		}
 	}()
 }
@@ -565,7 +609,7 @@ func open_webview(opts *BoxWebviewOptions) {
 		nsData := cocoa.BytesToNSData(opts.IconData)
 		nsImage := cocoa.GetClass("NSImage").Send(cocoa.RegisterName("alloc")).Send(cocoa.RegisterName("initWithData:"), nsData)
 		if nsImage == 0 {
-			fmt.Println("ERROR: Failed to create NSImage from IconData")
+			errorln("Failed to create NSImage from IconData")
 		} else {
 			nsApp.Send(cocoa.RegisterName("setApplicationIconImage:"), nsImage)
 		}
@@ -971,7 +1015,7 @@ func createWindow(opts *BoxWebviewOptions, isMain bool) {
 	userContentController := config.Send(cocoa.RegisterName("userContentController"))
 	scriptHandler := cocoa.GetClass("VeloScriptMessageHandler").Send(cocoa.RegisterName("alloc")).Send(cocoa.RegisterName("init"))
 	if scriptHandler == 0 {
-		fmt.Fprintln(os.Stderr, "ERROR: Failed to allocate VeloScriptMessageHandler")
+		errorln("Failed to allocate VeloScriptMessageHandler")
 	} else {
 		debugf("DEBUG: VeloScriptMessageHandler allocated: %d\n", scriptHandler)
 	}
@@ -1089,16 +1133,21 @@ func loadURLInWebView(wkWebView cocoa.ID, rawURL string) {
 	nsURL := cocoa.GetClass("NSURL").Send(cocoa.RegisterName("URLWithString:"), cocoa.StringToNSString(rawURL))
 	debugf("DEBUG: nsURL: %d\n", nsURL)
 	if nsURL == 0 {
-		fmt.Fprintln(os.Stderr, "ERROR: nsURL is nil")
+		errorln("nsURL is nil")
 	}
 	req := cocoa.GetClass("NSURLRequest").Send(cocoa.RegisterName("requestWithURL:"), nsURL)
 	debugf("DEBUG: req: %d\n", req)
 	if req == 0 {
-		fmt.Fprintln(os.Stderr, "ERROR: req is nil")
+		errorln("req is nil")
 	}
 	wkWebView.Send(cocoa.RegisterName("loadRequest:"), req)
 }
 
+// Terminate ends the application run loop.
+func Terminate() {
+	close_webview()
+}
+
 func close_webview() {
 	cocoa.DispatchMain(func() {
 		nsApp := cocoa.GetClass("NSApplication").Send(cocoa.RegisterName("sharedApplication"))
@@ -133,7 +133,7 @@ func scriptMessageReceived(self, _cmd, controller, message uintptr) uintptr {
 			}
 		}()
 	} else {
-		fmt.Println("ERROR: webview_opts or HandleMessage is nil, cannot handle message")
+		errorln("webview_opts or HandleMessage is nil, cannot handle message")
 	}
 	return 0
 }
@@ -209,7 +209,7 @@ func pushWindow(opts *BoxWebviewOptions) {
 
 	// Check if navigation controller exists
 	if navController == 0 {
-		fmt.Println("ERROR: navController is 0, cannot push")
+		errorln("navController is 0, cannot push")
 		return
 	}
 
@@ -285,6 +285,7 @@ func setURL(url string) {
 	}
 }
 func close_webview() {}
+func Terminate()     {}
 func sendCallback(id, result string) {
 	if wkWebView == 0 {
 		return
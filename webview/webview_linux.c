@@ -0,0 +1,396 @@
+//go:build linux && cgo && webkit2gtk
+
+#include "webview_linux.h"
+#include "_cgo_export.h"
+
+// The id of the Go window is kept on its GtkWindow and WebKitWebView.
+#define VELO_ID_KEY "velo-id"
+// Set on a WebKitWebView between a file drop and the arrival of its data.
+#define VELO_DROP_KEY "velo-drop"
+
+static uintptr_t objectID(gpointer object) {
+	return (uintptr_t)g_object_get_data(G_OBJECT(object), VELO_ID_KEY);
+}
+
+// requestHeaders returns the headers of request as "Name: value" lines.
+static char* requestHeaders(WebKitURISchemeRequest* request) {
+	GString* out = g_string_new(NULL);
+#if WEBKIT_CHECK_VERSION(2, 36, 0)
+	SoupMessageHeaders* headers = webkit_uri_scheme_request_get_http_headers(request);
+	if (headers != NULL) {
+		SoupMessageHeadersIter iter;
+		const char* name;
+		const char* value;
+		soup_message_headers_iter_init(&iter, headers);
+		while (soup_message_headers_iter_next(&iter, &name, &value)) {
+			g_string_append_printf(out, "%s: %s\r\n", name, value);
+		}
+	}
+#endif
+	return g_string_free(out, FALSE);
+}
+
+static void schemeRequest(WebKitURISchemeRequest* request, gpointer data) {
+	WebKitWebView* view = webkit_uri_scheme_request_get_web_view(request);
+	uintptr_t id = view != NULL ? objectID(view) : 0;
+	const char* method = NULL;
+	GInputStream* body = NULL;
+#if WEBKIT_CHECK_VERSION(2, 36, 0)
+	method = webkit_uri_scheme_request_get_http_method(request);
+#endif
+#if WEBKIT_CHECK_VERSION(2, 40, 0)
+	// Released by webviewSchemeBodyClose.
+	body = webkit_uri_scheme_request_get_http_body(request);
+#endif
+	char* headers = requestHeaders(request);
+	// Released by webviewSchemeFinish or webviewSchemeFail.
+	g_object_ref(request);
+	GoHandleSchemeTask(id, request, (char*)webkit_uri_scheme_request_get_uri(request), (char*)method, headers, body);
+	g_free(headers);
+}
+
+int webviewInit(const char* appName) {
+	if (appName != NULL && appName[0] != '\0') {
+		g_set_prgname(appName);
+		g_set_application_name(appName);
+	}
+	if (!gtk_init_check(NULL, NULL)) {
+		return 0;
+	}
+	WebKitWebContext* context = webkit_web_context_get_default();
+	webkit_web_context_register_uri_scheme(context, "velo", schemeRequest, NULL, NULL);
+	WebKitSecurityManager* security = webkit_web_context_get_security_manager(context);
+	webkit_security_manager_register_uri_scheme_as_secure(security, "velo");
+	webkit_security_manager_register_uri_scheme_as_cors_enabled(security, "velo");
+	return 1;
+}
+
+void webviewRun(void) {
+	gtk_main();
+}
+
+void webviewQuit(void) {
+	if (gtk_main_level() > 0) {
+		gtk_main_quit();
+	}
+}
+
+static gboolean dispatchCallback(gpointer data) {
+	GoDispatch((uintptr_t)data);
+	return G_SOURCE_REMOVE;
+}
+
+void webviewDispatch(uintptr_t id) {
+	g_idle_add_full(G_PRIORITY_HIGH_IDLE, dispatchCallback, (gpointer)id, NULL);
+}
+
+int webviewIsMainThread(void) {
+	return g_main_context_is_owner(g_main_context_default());
+}
+
+static void windowDestroyed(GtkWidget* widget, gpointer data) {
+	GoWindowDestroyed((uintptr_t)data);
+}
+
+// windowDeleted runs for the close button and gtk_window_close; returning
+// TRUE keeps the window open.
+static gboolean windowDeleted(GtkWidget* widget, GdkEvent* event, gpointer data) {
+	return GoWindowCloseRequest((uintptr_t)data) ? TRUE : FALSE;
+}
+
+static gboolean windowFocusIn(GtkWidget* widget, GdkEvent* event, gpointer data) {
+	GoWindowFocus((uintptr_t)data, 1);
+	return FALSE;
+}
+
+static gboolean windowFocusOut(GtkWidget* widget, GdkEvent* event, gpointer data) {
+	GoWindowFocus((uintptr_t)data, 0);
+	return FALSE;
+}
+
+GtkWidget* webviewCreateWindow(uintptr_t id, const char* title, int width, int height, int frameless, int nonActivating) {
+	GtkWidget* window = gtk_window_new(GTK_WINDOW_TOPLEVEL);
+	g_object_set_data(G_OBJECT(window), VELO_ID_KEY, (gpointer)id);
+	gtk_window_set_title(GTK_WINDOW(window), title);
+	if (width > 0 && height > 0) {
+		gtk_window_set_default_size(GTK_WINDOW(window), width, height);
+	}
+	if (frameless) {
+		gtk_window_set_decorated(GTK_WINDOW(window), FALSE);
+	}
+	if (nonActivating) {
+		gtk_window_set_type_hint(GTK_WINDOW(window), GDK_WINDOW_TYPE_HINT_UTILITY);
+		gtk_window_set_focus_on_map(GTK_WINDOW(window), FALSE);
+		gtk_window_set_keep_above(GTK_WINDOW(window), TRUE);
+	}
+	g_signal_connect(window, "delete-event", G_CALLBACK(windowDeleted), (gpointer)id);
+	g_signal_connect(window, "destroy", G_CALLBACK(windowDestroyed), (gpointer)id);
+	g_signal_connect(window, "focus-in-event", G_CALLBACK(windowFocusIn), (gpointer)id);
+	g_signal_connect(window, "focus-out-event", G_CALLBACK(windowFocusOut), (gpointer)id);
+	return window;
+}
+
+static void scriptMessage(WebKitUserContentManager* manager, WebKitJavascriptResult* result, gpointer data) {
+	char* message = jsc_value_to_string(webkit_javascript_result_get_js_value(result));
+	GoHandleMessage((uintptr_t)data, message);
+	g_free(message);
+}
+
+// isFileDrag reports a drag of files from another app; drags inside the page
+// are left to WebKit.
+static int isFileDrag(GdkDragContext* context) {
+	if (gtk_drag_get_source_widget(context) != NULL) {
+		return 0;
+	}
+	GdkAtom uriList = gdk_atom_intern_static_string("text/uri-list");
+	for (GList* t = gdk_drag_context_list_targets(context); t != NULL; t = t->next) {
+		if (GDK_POINTER_TO_ATOM(t->data) == uriList) {
+			return 1;
+		}
+	}
+	return 0;
+}
+
+static gboolean dragMotion(GtkWidget* widget, GdkDragContext* context, gint x, gint y, guint time, gpointer data) {
+	if (!isFileDrag(context)) {
+		return FALSE;
+	}
+	gdk_drag_status(context, GDK_ACTION_COPY, time);
+	GoDragOver((uintptr_t)data, x, y);
+	return TRUE;
+}
+
+static void dragLeave(GtkWidget* widget, GdkDragContext* context, guint time, gpointer data) {
+	GoDragLeave((uintptr_t)data);
+}
+
+static gboolean dragDrop(GtkWidget* widget, GdkDragContext* context, gint x, gint y, guint time, gpointer data) {
+	if (!isFileDrag(context)) {
+		return FALSE;
+	}
+	g_object_set_data(G_OBJECT(widget), VELO_DROP_KEY, GINT_TO_POINTER(1));
+	gtk_drag_get_data(widget, context, gdk_atom_intern_static_string("text/uri-list"), time);
+	return TRUE;
+}
+
+static void dragDataReceived(GtkWidget* widget, GdkDragContext* context, gint x, gint y, GtkSelectionData* selection, guint info, guint time, gpointer data) {
+	if (g_object_get_data(G_OBJECT(widget), VELO_DROP_KEY) == NULL) {
+		return;
+	}
+	g_object_set_data(G_OBJECT(widget), VELO_DROP_KEY, NULL);
+	// Keep WebKit from navigating to the dropped file.
+	g_signal_stop_emission_by_name(widget, "drag-data-received");
+
+	GString* paths = g_string_new(NULL);
+	gchar** uris = gtk_selection_data_get_uris(selection);
+	for (gchar** uri = uris; uri != NULL && *uri != NULL; uri++) {
+		gchar* path = g_filename_from_uri(*uri, NULL, NULL);
+		if (path == NULL) {
+			continue;
+		}
+		if (paths->len > 0) {
+			g_string_append_c(paths, '\n');
+		}
+		g_string_append(paths, path);
+		g_free(path);
+	}
+	g_strfreev(uris);
+	if (paths->len > 0) {
+		GoDrop((uintptr_t)data, paths->str, x, y);
+	}
+	gtk_drag_finish(context, paths->len > 0, FALSE, time);
+	g_string_free(paths, TRUE);
+}
+
+GtkWidget* webviewCreateWebView(uintptr_t id, GtkWidget* window, const char* script, int fileDrop) {
+	WebKitUserContentManager* manager = webkit_user_content_manager_new();
+	g_signal_connect(manager, "script-message-received::go", G_CALLBACK(scriptMessage), (gpointer)id);
+	webkit_user_content_manager_register_script_message_handler(manager, "go");
+	WebKitUserScript* userScript = webkit_user_script_new(
+		script,
+		WEBKIT_USER_CONTENT_INJECT_ALL_FRAMES,
+		WEBKIT_USER_SCRIPT_INJECT_AT_DOCUMENT_START,
+		NULL,
+		NULL);
+	webkit_user_content_manager_add_script(manager, userScript);
+	webkit_user_script_unref(userScript);
+
+	GtkWidget* view = webkit_web_view_new_with_user_content_manager(manager);
+	g_object_unref(manager);
+	g_object_set_data(G_OBJECT(view), VELO_ID_KEY, (gpointer)id);
+	webkit_settings_set_enable_developer_extras(webkit_web_view_get_settings(WEBKIT_WEB_VIEW(view)), TRUE);
+
+	if (fileDrop) {
+		g_signal_connect(view, "drag-motion", G_CALLBACK(dragMotion), (gpointer)id);
+		g_signal_connect(view, "drag-leave", G_CALLBACK(dragLeave), (gpointer)id);
+		g_signal_connect(view, "drag-drop", G_CALLBACK(dragDrop), (gpointer)id);
+		g_signal_connect(view, "drag-data-received", G_CALLBACK(dragDataReceived), (gpointer)id);
+	}
+
+	gtk_container_add(GTK_CONTAINER(window), view);
+	return view;
+}
+
+void webviewSetIcon(GtkWidget* window, const void* data, int length) {
+	GdkPixbufLoader* loader = gdk_pixbuf_loader_new();
+	gboolean ok = gdk_pixbuf_loader_write(loader, data, length, NULL);
+	ok = gdk_pixbuf_loader_close(loader, NULL) && ok;
+	GdkPixbuf* icon = ok ? gdk_pixbuf_loader_get_pixbuf(loader) : NULL;
+	if (icon != NULL) {
+		gtk_window_set_icon(GTK_WINDOW(window), icon);
+	}
+	g_object_unref(loader);
+}
+
+void webviewLoadURL(GtkWidget* webview, const char* url) {
+	webkit_web_view_load_uri(WEBKIT_WEB_VIEW(webview), url);
+}
+
+void webviewEval(GtkWidget* webview, const char* js) {
+#if WEBKIT_CHECK_VERSION(2, 40, 0)
+	webkit_web_view_evaluate_javascript(WEBKIT_WEB_VIEW(webview), js, -1, NULL, NULL, NULL, NULL, NULL);
+#else
+	webkit_web_view_run_javascript(WEBKIT_WEB_VIEW(webview), js, NULL, NULL, NULL);
+#endif
+}
+
+SoupMessageHeaders* webviewSchemeHeaders(void) {
+	return soup_message_headers_new(SOUP_MESSAGE_HEADERS_RESPONSE);
+}
+
+void webviewSchemeAddHeader(SoupMessageHeaders* headers, const char* name, const char* value) {
+	soup_message_headers_append(headers, name, value);
+}
+
+void webviewSchemeFinish(void* task, int status, const char* contentType, SoupMessageHeaders* headers, const void* data, int length) {
+	WebKitURISchemeRequest* request = task;
+	GBytes* bytes = g_bytes_new(data, length);
+	GInputStream* stream = g_memory_input_stream_new_from_bytes(bytes);
+	WebKitURISchemeResponse* response = webkit_uri_scheme_response_new(stream, length);
+	webkit_uri_scheme_response_set_status(response, status, NULL);
+	webkit_uri_scheme_response_set_content_type(response, contentType);
+	// The response takes ownership of headers.
+	webkit_uri_scheme_response_set_http_headers(response, headers);
+	webkit_uri_scheme_request_finish_with_response(request, response);
+	g_object_unref(response);
+	g_object_unref(stream);
+	g_bytes_unref(bytes);
+	g_object_unref(request);
+}
+
+gssize webviewSchemeBodyRead(GInputStream* body, void* buffer, gsize size) {
+	return g_input_stream_read(body, buffer, size, NULL, NULL);
+}
+
+void webviewSchemeBodyClose(GInputStream* body) {
+	g_input_stream_close(body, NULL, NULL);
+	g_object_unref(body);
+}
+
+void webviewSchemeFail(void* task, const char* message) {
+	WebKitURISchemeRequest* request = task;
+	GError* error = g_error_new_literal(G_IO_ERROR, G_IO_ERROR_FAILED, message);
+	webkit_uri_scheme_request_finish_error(request, error);
+	g_error_free(error);
+	g_object_unref(request);
+}
+
+void webviewSetTitle(GtkWidget* window, const char* title) {
+	gtk_window_set_title(GTK_WINDOW(window), title);
+}
+
+void webviewSetSize(GtkWidget* window, int width, int height) {
+	gtk_window_resize(GTK_WINDOW(window), width, height);
+}
+
+void webviewSetSizeHints(GtkWidget* window, int minWidth, int minHeight, int maxWidth, int maxHeight) {
+	GdkGeometry geometry = {0};
+	GdkWindowHints hints = 0;
+	if (minWidth > 0 || minHeight > 0) {
+		geometry.min_width = minWidth;
+		geometry.min_height = minHeight;
+		hints |= GDK_HINT_MIN_SIZE;
+	}
+	if (maxWidth > 0 || maxHeight > 0) {
+		geometry.max_width = maxWidth > 0 ? maxWidth : G_MAXINT16;
+		geometry.max_height = maxHeight > 0 ? maxHeight : G_MAXINT16;
+		hints |= GDK_HINT_MAX_SIZE;
+	}
+	gtk_window_set_geometry_hints(GTK_WINDOW(window), NULL, &geometry, hints);
+}
+
+// Positions are only honoured on X11; Wayland leaves placement to the
+// compositor and reports 0, 0.
+void webviewSetPosition(GtkWidget* window, int x, int y) {
+	gtk_window_move(GTK_WINDOW(window), x, y);
+}
+
+void webviewCenter(GtkWidget* window) {
+	gtk_window_set_position(GTK_WINDOW(window), GTK_WIN_POS_CENTER);
+}
+
+void webviewGetPosition(GtkWidget* window, int* x, int* y) {
+	gtk_window_get_position(GTK_WINDOW(window), x, y);
+}
+
+void webviewGetSize(GtkWidget* window, int* width, int* height) {
+	gtk_window_get_size(GTK_WINDOW(window), width, height);
+}
+
+void webviewShow(GtkWidget* window) {
+	gtk_widget_show_all(window);
+	gtk_window_present(GTK_WINDOW(window));
+}
+
+void webviewHide(GtkWidget* window) {
+	gtk_widget_hide(window);
+}
+
+void webviewMinimize(GtkWidget* window) {
+	gtk_window_iconify(GTK_WINDOW(window));
+}
+
+void webviewMaximize(GtkWidget* window) {
+	gtk_window_maximize(GTK_WINDOW(window));
+}
+
+void webviewToggleMaximize(GtkWidget* window) {
+	if (gtk_window_is_maximized(GTK_WINDOW(window))) {
+		gtk_window_unmaximize(GTK_WINDOW(window));
+	} else {
+		gtk_window_maximize(GTK_WINDOW(window));
+	}
+}
+
+void webviewFullscreen(GtkWidget* window) {
+	gtk_window_fullscreen(GTK_WINDOW(window));
+}
+
+void webviewUnFullscreen(GtkWidget* window) {
+	gtk_window_unfullscreen(GTK_WINDOW(window));
+}
+
+void webviewRestore(GtkWidget* window) {
+	gtk_window_deiconify(GTK_WINDOW(window));
+	if (gtk_window_is_maximized(GTK_WINDOW(window))) {
+		gtk_window_unmaximize(GTK_WINDOW(window));
+	}
+}
+
+void webviewSetAlwaysOnTop(GtkWidget* window, int onTop) {
+	gtk_window_set_keep_above(GTK_WINDOW(window), onTop);
+}
+
+void webviewClose(GtkWidget* window) {
+	gtk_window_close(GTK_WINDOW(window));
+}
+
+// webviewStartWindowDrag moves the window with the pointer. The page asks for
+// it after a mousedown on a drag region, so the press event is gone and the
+// pointer position stands in for it.
+void webviewStartWindowDrag(GtkWidget* window) {
+	GdkSeat* seat = gdk_display_get_default_seat(gtk_widget_get_display(window));
+	gint x = 0, y = 0;
+	gdk_device_get_position(gdk_seat_get_pointer(seat), NULL, &x, &y);
+	gtk_window_begin_move_drag(GTK_WINDOW(window), 1, x, y, GDK_CURRENT_TIME);
+}
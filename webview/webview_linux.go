@@ -0,0 +1,822 @@
+//go:build linux && cgo && webkit2gtk
+
+package webview
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*
+#cgo pkg-config: gtk+-3.0 webkit2gtk-4.1
+#include <stdlib.h>
+#include "webview_linux.h"
+*/
+import "C"
+
+// gtkWindow is a GtkWindow holding a WebKitWebView. Its widgets are only
+// used on the GTK main thread.
+type gtkWindow struct {
+	id      uintptr
+	name    string
+	opts    *BoxWebviewOptions
+	window  *C.GtkWidget
+	webview *C.GtkWidget
+
+	minWidth, minHeight int
+	maxWidth, maxHeight int
+}
+
+var (
+	webview_opts *BoxWebviewOptions
+	// mainWindow is the window the package-level functions act on; it is
+	// only used on the main thread.
+	mainWindow *gtkWindow
+
+	windows      = make(map[uintptr]*gtkWindow)
+	namedWindows = make(map[string]*gtkWindow)
+	windowSeq    uintptr
+	mapLock      sync.RWMutex
+
+	quitOnLastWindowClosed = true
+	// running is set while the GTK main loop runs.
+	running atomic.Bool
+
+	dispatchMu    sync.Mutex
+	dispatchSeq   uintptr
+	dispatchQueue = make(map[uintptr]func())
+)
+
+func init() {
+	// GTK must be initialised and run on one thread for the lifetime of the
+	// app; keep the main goroutine on the main OS thread like the Darwin
+	// backend does.
+	runtime.LockOSThread()
+}
+
+// dispatch runs fn on the GTK main thread.
+func dispatch(fn func()) {
+	dispatchMu.Lock()
+	dispatchSeq++
+	id := dispatchSeq
+	dispatchQueue[id] = fn
+	dispatchMu.Unlock()
+	C.webviewDispatch(C.uintptr_t(id))
+}
+
+// dispatchSync runs fn on the GTK main thread and waits for it. It returns
+// false without running fn when the main loop is not running.
+func dispatchSync(fn func()) bool {
+	if C.webviewIsMainThread() != 0 {
+		fn()
+		return true
+	}
+	if !running.Load() {
+		return false
+	}
+	done := make(chan struct{})
+	dispatch(func() {
+		defer close(done)
+		fn()
+	})
+	<-done
+	return true
+}
+
+//export GoDispatch
+func GoDispatch(id C.uintptr_t) {
+	dispatchMu.Lock()
+	fn := dispatchQueue[uintptr(id)]
+	delete(dispatchQueue, uintptr(id))
+	dispatchMu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+func lookupWindow(id uintptr) *gtkWindow {
+	mapLock.RLock()
+	defer mapLock.RUnlock()
+	return windows[id]
+}
+
+func (w *gtkWindow) options() *BoxWebviewOptions {
+	if w != nil && w.opts != nil {
+		return w.opts
+	}
+	return webview_opts
+}
+
+// schemeResponseWriter buffers the response of the mux and hands it to
+// WebKit once the handler returns.
+type schemeResponseWriter struct {
+	task   unsafe.Pointer
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *schemeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *schemeResponseWriter) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(data)
+}
+
+func (w *schemeResponseWriter) WriteHeader(statusCode int) {
+	if w.code == 0 {
+		w.code = statusCode
+	}
+}
+
+func (w *schemeResponseWriter) Finish() {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	contentType := w.header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.body.Bytes())
+	}
+	header := w.header.Clone()
+	body := w.body.Bytes()
+	dispatch(func() {
+		headers := C.webviewSchemeHeaders()
+		for name, values := range header {
+			if strings.EqualFold(name, "Content-Type") {
+				continue
+			}
+			cName := C.CString(name)
+			for _, v := range values {
+				cValue := C.CString(v)
+				C.webviewSchemeAddHeader(headers, cName, cValue)
+				C.free(unsafe.Pointer(cValue))
+			}
+			C.free(unsafe.Pointer(cName))
+		}
+		cContentType := C.CString(contentType)
+		defer C.free(unsafe.Pointer(cContentType))
+		var data unsafe.Pointer
+		if len(body) > 0 {
+			data = C.CBytes(body)
+			defer C.free(data)
+		}
+		C.webviewSchemeFinish(w.task, C.int(w.code), cContentType, headers, data, C.int(len(body)))
+	})
+}
+
+// schemeBody reads the body of a velo:// request.
+type schemeBody struct {
+	stream *C.GInputStream
+}
+
+func (b *schemeBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := C.webviewSchemeBodyRead(b.stream, unsafe.Pointer(&p[0]), C.gsize(len(p)))
+	if n < 0 {
+		return 0, errors.New("read velo:// request body")
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return int(n), nil
+}
+
+func (b *schemeBody) Close() error {
+	if b.stream != nil {
+		C.webviewSchemeBodyClose(b.stream)
+		b.stream = nil
+	}
+	return nil
+}
+
+// newSchemeRequest builds the request the mux serves for a velo://
+// request. header holds "Name: value" lines.
+func newSchemeRequest(method, rawURL, header string, body io.ReadCloser) (*http.Request, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	// Map the custom scheme to http for the mux.
+	req.URL.Scheme = "http"
+	if header != "" {
+		mime, err := textproto.NewReader(bufio.NewReader(strings.NewReader(header + "\r\n"))).ReadMIMEHeader()
+		if err != nil {
+			return nil, err
+		}
+		req.Header = http.Header(mime)
+	}
+	if body != http.NoBody {
+		req.ContentLength = -1
+		if n, err := strconv.ParseInt(req.Header.Get("Content-Length"), 10, 64); err == nil {
+			req.ContentLength = n
+		}
+	}
+	return req, nil
+}
+
+//export GoHandleSchemeTask
+func GoHandleSchemeTask(id C.uintptr_t, task unsafe.Pointer, uri, method, header *C.char, stream *C.GInputStream) {
+	opts := lookupWindow(uintptr(id)).options()
+	rawURL := C.GoString(uri)
+	var body io.ReadCloser
+	if stream != nil {
+		body = &schemeBody{stream: stream}
+	}
+	if opts == nil || opts.Mux == nil {
+		debugf("DEBUG: no mux for %s\n", rawURL)
+		if body != nil {
+			body.Close()
+		}
+		msg := C.CString("no handler for velo:// requests")
+		defer C.free(unsafe.Pointer(msg))
+		C.webviewSchemeFail(task, msg)
+		return
+	}
+	debugf("DEBUG: URL scheme request: %s\n", rawURL)
+	// The method and headers are owned by the request; copy them before
+	// returning to GTK.
+	m, h := C.GoString(method), C.GoString(header)
+	go func() {
+		if body != nil {
+			defer body.Close()
+		}
+		req, err := newSchemeRequest(m, rawURL, h, body)
+		if err != nil {
+			debugf("DEBUG: Failed to create request: %v\n", err)
+			dispatch(func() {
+				msg := C.CString(err.Error())
+				defer C.free(unsafe.Pointer(msg))
+				C.webviewSchemeFail(task, msg)
+			})
+			return
+		}
+		rw := &schemeResponseWriter{task: task, header: make(http.Header)}
+		opts.Mux.ServeHTTP(rw, req)
+		rw.Finish()
+	}()
+}
+
+//export GoHandleMessage
+func GoHandleMessage(id C.uintptr_t, msg *C.char) {
+	w := lookupWindow(uintptr(id))
+	if w == nil {
+		return
+	}
+	notifyReady()
+	str := C.GoString(msg)
+
+	// Handle the built-in window messages here, on the main thread, before
+	// routing to the Go handler.
+	var parsed struct {
+		ID     string      `json:"id"`
+		Method string      `json:"method"`
+		Args   interface{} `json:"args"`
+	}
+	if json.Unmarshal([]byte(str), &parsed) == nil && handleWindowControlMessage(w, parsed.ID, parsed.Method, parsed.Args) {
+		return
+	}
+
+	opts := w.options()
+	if !opts.hasMessageHandler() {
+		return
+	}
+	// Handle the message off the main thread so that handlers can wait for
+	// UI work dispatched to it. Callbacks, stream chunks included, go back
+	// to this window.
+	send := func(id, result string) {
+		dispatch(func() {
+			sendCallbackTo(w, id, result)
+		})
+	}
+	go func() {
+		id, result := opts.handleMessage(str, send)
+		if id != "" {
+			send(id, result)
+		}
+	}()
+}
+
+func handleWindowControlMessage(w *gtkWindow, id string, method string, args interface{}) bool {
+	if !strings.HasPrefix(method, "__velo/window/") {
+		return false
+	}
+	if w.window == nil {
+		if id != "" {
+			sendCallbackTo(w, id, `{"success":false}`)
+		}
+		return true
+	}
+
+	switch method {
+	case "__velo/window/start_drag":
+		C.webviewStartWindowDrag(w.window)
+	case "__velo/window/close":
+		if id != "" {
+			sendCallbackTo(w, id, `{"success":true}`)
+		}
+		C.webviewClose(w.window)
+		return true
+	case "__velo/window/minimize":
+		C.webviewMinimize(w.window)
+	case "__velo/window/hide":
+		C.webviewHide(w.window)
+	case "__velo/window/set_size":
+		width := intArg(args, "width")
+		height := intArg(args, "height")
+		if width > 0 && height > 0 {
+			C.webviewSetSize(w.window, C.int(width), C.int(height))
+		}
+	case "__velo/window/state":
+		if id != "" {
+			sendCallbackTo(w, id, windowStateResult(w))
+		}
+		return true
+	case "__velo/window/toggle_maximize":
+		C.webviewToggleMaximize(w.window)
+	case "__velo/window/maximize":
+		C.webviewMaximize(w.window)
+	case "__velo/window/restore":
+		C.webviewRestore(w.window)
+	case "__velo/window/set_always_on_top":
+		C.webviewSetAlwaysOnTop(w.window, cBool(boolArg(args, "onTop")))
+	default:
+		return false
+	}
+
+	if id != "" {
+		sendCallbackTo(w, id, `{"success":true}`)
+	}
+	return true
+}
+
+func windowStateResult(w *gtkWindow) string {
+	if w == nil || w.window == nil {
+		return `{"success":false}`
+	}
+	var x, y, width, height C.int
+	C.webviewGetPosition(w.window, &x, &y)
+	C.webviewGetSize(w.window, &width, &height)
+	return fmt.Sprintf(`{"success":true,"x":%d,"y":%d,"width":%d,"height":%d}`, x, y, width, height)
+}
+
+func intArg(args interface{}, key string) int {
+	values, ok := args.(map[string]interface{})
+	if !ok || values == nil {
+		return 0
+	}
+	v, ok := values[key]
+	if !ok {
+		return 0
+	}
+	switch value := v.(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+func boolArg(args interface{}, key string) bool {
+	values, ok := args.(map[string]interface{})
+	if !ok || values == nil {
+		return false
+	}
+	v, ok := values[key]
+	if !ok {
+		return false
+	}
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true") || value == "1"
+	case float64:
+		return value != 0
+	default:
+		return false
+	}
+}
+
+func cBool(v bool) C.int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// GoWindowCloseRequest reports whether closing the window must be stopped:
+// the last window is kept open and handed to HandleQuitRequest, which may
+// veto quitting.
+//
+//export GoWindowCloseRequest
+func GoWindowCloseRequest(id C.uintptr_t) C.int {
+	mapLock.Lock()
+	w := windows[uintptr(id)]
+	last := len(windows) == 1
+	mapLock.Unlock()
+	if w == nil || !last || !quitOnLastWindowClosed {
+		return 0
+	}
+	opts := w.options()
+	if opts == nil || opts.HandleQuitRequest == nil {
+		return 0
+	}
+	go opts.HandleQuitRequest(w.name)
+	return 1
+}
+
+//export GoWindowDestroyed
+func GoWindowDestroyed(id C.uintptr_t) {
+	mapLock.Lock()
+	w := windows[uintptr(id)]
+	if w == nil {
+		mapLock.Unlock()
+		return
+	}
+	delete(windows, w.id)
+	if w.name != "" && namedWindows[w.name] == w {
+		delete(namedWindows, w.name)
+	}
+	remaining := len(windows)
+	mapLock.Unlock()
+
+	w.window = nil
+	w.webview = nil
+	if mainWindow == w {
+		mainWindow = nil
+	}
+	if opts := w.options(); opts != nil && opts.HandleClose != nil {
+		go opts.HandleClose(w.name)
+	}
+	if remaining == 0 && quitOnLastWindowClosed {
+		C.webviewQuit()
+	}
+}
+
+//export GoWindowFocus
+func GoWindowFocus(id C.uintptr_t, focused C.int) {
+	eventType := "__velo_window_blur"
+	if focused != 0 {
+		eventType = "__velo_window_focus"
+	}
+	sendWebViewMessage(lookupWindow(uintptr(id)), map[string]interface{}{"type": eventType})
+}
+
+//export GoDragOver
+func GoDragOver(id C.uintptr_t, x, y C.int) {
+	sendWebViewMessage(lookupWindow(uintptr(id)), map[string]interface{}{
+		"type": "__velo_file_drag_over",
+		"point": map[string]float64{
+			"x": float64(x),
+			"y": float64(y),
+		},
+	})
+}
+
+//export GoDragLeave
+func GoDragLeave(id C.uintptr_t) {
+	sendWebViewMessage(lookupWindow(uintptr(id)), map[string]interface{}{"type": "__velo_drag_leave"})
+}
+
+//export GoDrop
+func GoDrop(id C.uintptr_t, paths *C.char, x, y C.int) {
+	w := lookupWindow(uintptr(id))
+	sendWebViewMessage(w, map[string]interface{}{"type": "__velo_drag_leave"})
+	opts := w.options()
+	if opts == nil || opts.HandleDragDrop == nil {
+		return
+	}
+	payload := map[string]interface{}{
+		"paths": strings.Split(C.GoString(paths), "\n"),
+		"x":     float64(x),
+		"y":     float64(y),
+	}
+	payloadJSON, _ := json.Marshal(payload)
+	go opts.HandleDragDrop("drop", string(payloadJSON))
+}
+
+func sendWebViewMessage(w *gtkWindow, payload map[string]interface{}) {
+	if w == nil {
+		return
+	}
+	payloadJSON, _ := json.Marshal(payload)
+	evalJS(w, fmt.Sprintf(
+		`window.__receiveGoMessage && window.__receiveGoMessage(%s);`,
+		string(payloadJSON),
+	))
+}
+
+// evalJS runs js in the page of w; it must be called on the main thread.
+func evalJS(w *gtkWindow, js string) {
+	if w == nil || w.webview == nil {
+		return
+	}
+	cjs := C.CString(js)
+	defer C.free(unsafe.Pointer(cjs))
+	C.webviewEval(w.webview, cjs)
+}
+
+func open_webview(opts *BoxWebviewOptions) {
+	debugln("DEBUG: open_webview started")
+	webview_opts = opts
+	quitOnLastWindowClosed = opts.QuitOnLastWindowClosed
+
+	cName := C.CString(opts.AppName)
+	defer C.free(unsafe.Pointer(cName))
+	if C.webviewInit(cName) == 0 {
+		warnln("Webview could not open a display; set DISPLAY or WAYLAND_DISPLAY, or use EngineHeadless to run without one.")
+		return
+	}
+
+	createWindow(opts, true)
+
+	debugln("DEBUG: Starting main loop...")
+	running.Store(true)
+	C.webviewRun()
+	running.Store(false)
+	debugln("DEBUG: Main loop ended")
+}
+
+func open_window(opts *BoxWebviewOptions) {
+	// The first window starts the main loop, like on macOS.
+	if !running.Load() {
+		open_webview(opts)
+		return
+	}
+	isMain := webview_opts != nil && strings.TrimSpace(opts.Name) != "" && opts.Name == webview_opts.Name
+	dispatch(func() {
+		createWindow(opts, isMain)
+	})
+}
+
+func focus_window(opts *BoxWebviewOptions) bool {
+	if opts == nil || strings.TrimSpace(opts.Name) == "" {
+		return false
+	}
+	name := strings.TrimSpace(opts.Name)
+	mapLock.Lock()
+	w := namedWindows[name]
+	if w != nil {
+		w.opts = opts
+	}
+	mapLock.Unlock()
+	if w == nil {
+		return false
+	}
+
+	dispatch(func() {
+		if w.window == nil {
+			return
+		}
+		if opts.Title != "" {
+			setWindowTitle(w, opts.Title)
+		}
+		if opts.URL != "" && !opts.PreserveStateOnFocus {
+			loadURL(w, opts.URL)
+		}
+		C.webviewRestore(w.window)
+		C.webviewShow(w.window)
+	})
+	return true
+}
+
+// createWindow opens a window for opts; it must be called on the main
+// thread.
+func createWindow(opts *BoxWebviewOptions, isMain bool) {
+	mapLock.Lock()
+	windowSeq++
+	w := &gtkWindow{id: windowSeq, name: strings.TrimSpace(opts.Name), opts: opts}
+	windows[w.id] = w
+	if w.name != "" {
+		namedWindows[w.name] = w
+	}
+	mapLock.Unlock()
+
+	cTitle := C.CString(opts.Title)
+	defer C.free(unsafe.Pointer(cTitle))
+	w.window = C.webviewCreateWindow(C.uintptr_t(w.id), cTitle, C.int(opts.Width), C.int(opts.Height), cBool(opts.Frameless), cBool(opts.NonActivating))
+	if len(opts.IconData) > 0 {
+		C.webviewSetIcon(w.window, unsafe.Pointer(&opts.IconData[0]), C.int(len(opts.IconData)))
+	}
+	if opts.HasPosition {
+		C.webviewSetPosition(w.window, C.int(opts.X), C.int(opts.Y))
+	} else {
+		C.webviewCenter(w.window)
+	}
+
+	setupScript := `
+		window.external = {
+			invoke: function(msg) {
+				window.webkit.messageHandlers.go.postMessage(msg);
+			}
+		};
+	`
+	if opts.InjectedJS != "" {
+		setupScript += "\n" + opts.InjectedJS
+	}
+	cScript := C.CString(setupScript)
+	defer C.free(unsafe.Pointer(cScript))
+	w.webview = C.webviewCreateWebView(C.uintptr_t(w.id), w.window, cScript, cBool(opts.HandleDragDrop != nil))
+
+	if isMain {
+		mainWindow = w
+	}
+
+	debugf("DEBUG: Loading URL: %s\n", opts.URL)
+	loadURL(w, opts.URL)
+	if !opts.Hidden {
+		C.webviewShow(w.window)
+	}
+}
+
+func loadURL(w *gtkWindow, rawURL string) {
+	if w.webview == nil || strings.TrimSpace(rawURL) == "" {
+		return
+	}
+	cURL := C.CString(rawURL)
+	defer C.free(unsafe.Pointer(cURL))
+	C.webviewLoadURL(w.webview, cURL)
+}
+
+func setWindowTitle(w *gtkWindow, title string) {
+	cTitle := C.CString(title)
+	defer C.free(unsafe.Pointer(cTitle))
+	C.webviewSetTitle(w.window, cTitle)
+}
+
+// withMainWindow runs fn with the main window on the main thread.
+func withMainWindow(fn func(w *gtkWindow)) {
+	dispatch(func() {
+		if mainWindow != nil && mainWindow.window != nil {
+			fn(mainWindow)
+		}
+	})
+}
+
+// Terminate ends the GTK main loop.
+func Terminate() {
+	dispatch(func() {
+		C.webviewQuit()
+	})
+}
+
+func close_webview() {
+	withMainWindow(func(w *gtkWindow) {
+		C.webviewClose(w.window)
+	})
+}
+
+func setTitle(title string) {
+	withMainWindow(func(w *gtkWindow) {
+		setWindowTitle(w, title)
+	})
+}
+
+func setSize(width, height int) {
+	withMainWindow(func(w *gtkWindow) {
+		C.webviewSetSize(w.window, C.int(width), C.int(height))
+	})
+}
+
+func setMinSize(width, height int) {
+	withMainWindow(func(w *gtkWindow) {
+		w.minWidth, w.minHeight = width, height
+		C.webviewSetSizeHints(w.window, C.int(w.minWidth), C.int(w.minHeight), C.int(w.maxWidth), C.int(w.maxHeight))
+	})
+}
+
+func setMaxSize(width, height int) {
+	withMainWindow(func(w *gtkWindow) {
+		w.maxWidth, w.maxHeight = width, height
+		C.webviewSetSizeHints(w.window, C.int(w.minWidth), C.int(w.minHeight), C.int(w.maxWidth), C.int(w.maxHeight))
+	})
+}
+
+func setPosition(x, y int) {
+	withMainWindow(func(w *gtkWindow) {
+		C.webviewSetPosition(w.window, C.int(x), C.int(y))
+	})
+}
+
+func getPosition() (int, int) {
+	var x, y C.int
+	dispatchSync(func() {
+		if mainWindow != nil && mainWindow.window != nil {
+			C.webviewGetPosition(mainWindow.window, &x, &y)
+		}
+	})
+	return int(x), int(y)
+}
+
+func getSize() (int, int) {
+	var width, height C.int
+	dispatchSync(func() {
+		if mainWindow != nil && mainWindow.window != nil {
+			C.webviewGetSize(mainWindow.window, &width, &height)
+		}
+	})
+	return int(width), int(height)
+}
+
+func show() {
+	withMainWindow(func(w *gtkWindow) { C.webviewShow(w.window) })
+}
+
+func hide() {
+	withMainWindow(func(w *gtkWindow) { C.webviewHide(w.window) })
+}
+
+func minimize() {
+	withMainWindow(func(w *gtkWindow) { C.webviewMinimize(w.window) })
+}
+
+func maximize() {
+	withMainWindow(func(w *gtkWindow) { C.webviewMaximize(w.window) })
+}
+
+func fullscreen() {
+	withMainWindow(func(w *gtkWindow) { C.webviewFullscreen(w.window) })
+}
+
+func unFullscreen() {
+	withMainWindow(func(w *gtkWindow) { C.webviewUnFullscreen(w.window) })
+}
+
+func restore() {
+	withMainWindow(func(w *gtkWindow) { C.webviewRestore(w.window) })
+}
+
+func setAlwaysOnTop(onTop bool) {
+	withMainWindow(func(w *gtkWindow) { C.webviewSetAlwaysOnTop(w.window, cBool(onTop)) })
+}
+
+func setURL(u string) {
+	withMainWindow(func(w *gtkWindow) { loadURL(w, u) })
+}
+
+func sendCallback(id, result string) {
+	withMainWindow(func(w *gtkWindow) { sendCallbackTo(w, id, result) })
+}
+
+func sendCallbackTo(w *gtkWindow, id, result string) {
+	jsonResult, _ := json.Marshal(result)
+	evalJS(w, fmt.Sprintf(
+		"window._goCallbacks && window._goCallbacks[%q] && window._goCallbacks[%q](%s);",
+		id, id, string(jsonResult),
+	))
+}
+
+func sendMessage(payload string) bool {
+	mapLock.RLock()
+	count := len(windows)
+	mapLock.RUnlock()
+	if count == 0 {
+		return false
+	}
+
+	script := fmt.Sprintf("window.__receiveGoMessage && window.__receiveGoMessage(%s);", payload)
+	dispatch(func() {
+		mapLock.RLock()
+		targets := make([]*gtkWindow, 0, len(windows))
+		for _, w := range windows {
+			targets = append(targets, w)
+		}
+		mapLock.RUnlock()
+		for _, w := range targets {
+			evalJS(w, script)
+		}
+	})
+	return true
+}
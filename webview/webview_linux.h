@@ -0,0 +1,44 @@
+#ifndef WEBVIEW_LINUX_H
+#define WEBVIEW_LINUX_H
+#include <stdint.h>
+#include <gtk/gtk.h>
+#include <webkit2/webkit2.h>
+
+int webviewInit(const char* appName);
+void webviewRun(void);
+void webviewQuit(void);
+void webviewDispatch(uintptr_t id);
+int webviewIsMainThread(void);
+
+GtkWidget* webviewCreateWindow(uintptr_t id, const char* title, int width, int height, int frameless, int nonActivating);
+GtkWidget* webviewCreateWebView(uintptr_t id, GtkWidget* window, const char* script, int fileDrop);
+void webviewSetIcon(GtkWidget* window, const void* data, int length);
+void webviewLoadURL(GtkWidget* webview, const char* url);
+void webviewEval(GtkWidget* webview, const char* js);
+
+SoupMessageHeaders* webviewSchemeHeaders(void);
+void webviewSchemeAddHeader(SoupMessageHeaders* headers, const char* name, const char* value);
+void webviewSchemeFinish(void* task, int status, const char* contentType, SoupMessageHeaders* headers, const void* data, int length);
+gssize webviewSchemeBodyRead(GInputStream* body, void* buffer, gsize size);
+void webviewSchemeBodyClose(GInputStream* body);
+void webviewSchemeFail(void* task, const char* message);
+
+void webviewSetTitle(GtkWidget* window, const char* title);
+void webviewSetSize(GtkWidget* window, int width, int height);
+void webviewSetSizeHints(GtkWidget* window, int minWidth, int minHeight, int maxWidth, int maxHeight);
+void webviewSetPosition(GtkWidget* window, int x, int y);
+void webviewCenter(GtkWidget* window);
+void webviewGetPosition(GtkWidget* window, int* x, int* y);
+void webviewGetSize(GtkWidget* window, int* width, int* height);
+void webviewShow(GtkWidget* window);
+void webviewHide(GtkWidget* window);
+void webviewMinimize(GtkWidget* window);
+void webviewMaximize(GtkWidget* window);
+void webviewToggleMaximize(GtkWidget* window);
+void webviewFullscreen(GtkWidget* window);
+void webviewUnFullscreen(GtkWidget* window);
+void webviewRestore(GtkWidget* window);
+void webviewSetAlwaysOnTop(GtkWidget* window, int onTop);
+void webviewClose(GtkWidget* window);
+void webviewStartWindowDrag(GtkWidget* window);
+#endif
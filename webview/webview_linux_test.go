@@ -0,0 +1,120 @@
+//go:build linux && cgo && webkit2gtk
+
+package webview
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWebKitGTKServesSchemeAndDeliversMessages needs a display; run it
+// with xvfb-run go test -tags webkit2gtk ./webview.
+func TestWebKitGTKServesSchemeAndDeliversMessages(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		t.Skip("no display; run under xvfb-run")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<!doctype html><script>
+			fetch("/upload", {method: "POST", headers: {"X-Velo-Test": "1"}, body: "hello"})
+				.then(function (res) { return res.text(); })
+				.catch(function (err) { return String(err); })
+				.then(function (upload) {
+					window.external.invoke(JSON.stringify({id: "1", method: "/ping", args: {path: location.pathname, upload: upload}}));
+				});
+		</script>`)
+	})
+	// POST requests keep their method, headers and body over velo://.
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("X-Velo-Test"), body)
+	})
+	messages := make(chan string, 1)
+	closed := make(chan string, 1)
+	opts := &BoxWebviewOptions{
+		Name:                   "main",
+		Title:                  "velo test",
+		URL:                    "velo://localhost/index.html",
+		Width:                  400,
+		Height:                 300,
+		Mux:                    mux,
+		QuitOnLastWindowClosed: true,
+		HandleMessage: func(message string) (string, string) {
+			messages <- message
+			return "", ""
+		},
+		HandleClose: func(name string) { closed <- name },
+	}
+
+	var got string
+	go func() {
+		select {
+		case got = <-messages:
+		case <-time.After(20 * time.Second):
+		}
+		close_webview()
+	}()
+	OpenWebview(opts)
+
+	if got == "" {
+		t.Fatalf("page sent no message")
+	}
+	var msg struct {
+		Method string `json:"method"`
+		Args   struct {
+			Path   string `json:"path"`
+			Upload string `json:"upload"`
+		} `json:"args"`
+	}
+	if err := json.Unmarshal([]byte(got), &msg); err != nil {
+		t.Fatalf("decode message %q: %v", got, err)
+	}
+	if msg.Method != "/ping" || msg.Args.Path != "/index.html" || msg.Args.Upload != "POST 1 hello" {
+		t.Fatalf("message = %s", got)
+	}
+	select {
+	case name := <-closed:
+		if name != "main" {
+			t.Fatalf("closed window = %q, want main", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("HandleClose was not called")
+	}
+}
+
+func TestSchemeRequestKeepsMethodHeadersAndBody(t *testing.T) {
+	var got string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/__velo/transfer/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = fmt.Sprintf("%s %s %s %d %s", r.Method, r.URL.Path, r.Header.Get("Content-Type"), r.ContentLength, body)
+	})
+	req, err := newSchemeRequest("POST", "velo://localhost/__velo/transfer/", "Content-Type: application/octet-stream\r\nContent-Length: 3\r\n", io.NopCloser(strings.NewReader("abc")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	if want := "POST /__velo/transfer/ application/octet-stream 3 abc"; got != want {
+		t.Fatalf("mux got %q, want %q", got, want)
+	}
+
+	req, err = newSchemeRequest("", "velo://localhost/index.html", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodGet || req.Body != http.NoBody || req.URL.Scheme != "http" {
+		t.Fatalf("request = %s %v %s", req.Method, req.Body, req.URL)
+	}
+}
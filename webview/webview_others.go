@@ -1,15 +1,13 @@
-//go:build !darwin && !windows
+//go:build !darwin && !windows && !(linux && cgo && webkit2gtk)
 
 package webview
 
-import "fmt"
-
 func open_webview(opts *BoxWebviewOptions) {
-	fmt.Println("Webview is not supported on this platform yet.")
+	warnln("Webview is not supported in this build; on Linux build with cgo and -tags webkit2gtk, or use EngineHeadless to run without a display.")
 }
 
 func open_window(opts *BoxWebviewOptions) {
-	fmt.Println("Additional webview windows are not supported on this platform yet.")
+	warnln("Additional webview windows are not supported on this platform yet.")
 }
 
 func focus_window(opts *BoxWebviewOptions) bool { return false }
@@ -31,6 +31,10 @@ var traceLogMu sync.Mutex
 var traceLogFile *os.File
 
 func traceLog(format string, args ...interface{}) {
+	if logger.Load() != nil {
+		debugf(format, args...)
+		return
+	}
 	traceLogMu.Lock()
 	defer traceLogMu.Unlock()
 	if traceLogFile == nil {
@@ -291,7 +295,7 @@ func open_webview(opts *BoxWebviewOptions) {
 }
 
 func open_window(opts *BoxWebviewOptions) {
-	fmt.Println("Additional webview windows are not supported on Windows yet.")
+	warnln("Additional webview windows are not supported on Windows yet.")
 }
 
 func focus_window(opts *BoxWebviewOptions) bool { return false }
@@ -2,14 +2,12 @@
 
 package webview
 
-import "fmt"
-
 func open_webview(opts *BoxWebviewOptions) {
-	fmt.Println("Webview (WebView2) requires CGO; building without UI on Windows.")
+	warnln("Webview (WebView2) requires CGO; building without UI on Windows.")
 }
 
 func open_window(opts *BoxWebviewOptions) {
-	fmt.Println("Additional webview windows are not supported on Windows without CGO.")
+	warnln("Additional webview windows are not supported on Windows without CGO.")
 }
 
 func focus_window(opts *BoxWebviewOptions) bool { return false }
@@ -2,6 +2,7 @@ package velo
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
@@ -40,6 +41,9 @@ type veloWSHub struct {
 }
 
 type veloWSConn struct {
+	// ctx is cancelled when the connection closes, aborting the handlers of
+	// requests still in flight for this client.
+	ctx     context.Context
 	conn    net.Conn
 	reader  *bufio.Reader
 	writeMu sync.Mutex
@@ -52,7 +56,13 @@ func newVeloWSHub() *veloWSHub {
 	}
 }
 
-func (h *veloWSHub) ServeHTTP(w http.ResponseWriter, r *http.Request, handleMessage func(string) (string, string)) {
+// wsMessageHandler handles one message from a WebSocket client. body is the
+// payload of a binary frame, nil for text frames; a non-nil data result is
+// sent back in a binary frame. The context is cancelled when the client
+// disconnects.
+type wsMessageHandler func(ctx context.Context, message string, body []byte) (id, result string, data []byte)
+
+func (h *veloWSHub) ServeHTTP(w http.ResponseWriter, r *http.Request, handleMessage wsMessageHandler) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -89,9 +99,12 @@ func (h *veloWSHub) ServeHTTP(w http.ResponseWriter, r *http.Request, handleMess
 		return
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	client := &veloWSConn{conn: netConn, reader: rw.Reader}
+	client.ctx = withCallbackSink(withCaller(ctx, fmt.Sprintf("ws:%p", client)), client.writeCallback)
 	h.add(client)
 	defer func() {
+		cancel()
 		h.remove(client)
 		client.close()
 	}()
@@ -116,7 +129,7 @@ func (h *veloWSHub) ServeHTTP(w http.ResponseWriter, r *http.Request, handleMess
 				fragmentedPayload = append(fragmentedPayload[:0], payload...)
 				continue
 			}
-			go h.handleClientMessage(client, string(payload), handleMessage)
+			go h.handleClientMessage(client, opcode, payload, handleMessage)
 		case wsOpcodeContinuation:
 			if fragmentedOpcode == 0 {
 				return
@@ -126,10 +139,10 @@ func (h *veloWSHub) ServeHTTP(w http.ResponseWriter, r *http.Request, handleMess
 				return
 			}
 			if fin {
-				msg := string(fragmentedPayload)
+				msg, msgOpcode := fragmentedPayload, fragmentedOpcode
 				fragmentedOpcode = 0
 				fragmentedPayload = nil
-				go h.handleClientMessage(client, msg, handleMessage)
+				go h.handleClientMessage(client, msgOpcode, msg, handleMessage)
 			}
 		case wsOpcodePing:
 			_ = client.writeFrame(wsOpcodePong, payload)
@@ -159,8 +172,19 @@ func (h *veloWSHub) BroadcastMessage(message interface{}) bool {
 	return h.broadcastText(frame)
 }
 
-func (h *veloWSHub) handleClientMessage(client *veloWSConn, message string, handleMessage func(string) (string, string)) {
-	id, result := handleMessage(message)
+// handleClientMessage runs one client message. A binary frame carries the
+// invoke message as its header and the binary argument as its body; see
+// encodeBinaryMessage.
+func (h *veloWSHub) handleClientMessage(client *veloWSConn, opcode byte, payload []byte, handleMessage wsMessageHandler) {
+	message := payload
+	var body []byte
+	if opcode == wsOpcodeBinary {
+		var err error
+		if message, body, err = decodeBinaryMessage(payload); err != nil {
+			return
+		}
+	}
+	id, result, data := handleMessage(client.ctx, string(message), body)
 	if id == "" {
 		return
 	}
@@ -168,7 +192,12 @@ func (h *veloWSHub) handleClientMessage(client *veloWSConn, message string, hand
 	if err != nil {
 		return
 	}
-	if err := client.writeText(frame); err != nil {
+	if data != nil {
+		err = client.writeFrame(wsOpcodeBinary, encodeBinaryMessage(frame, data))
+	} else {
+		err = client.writeText(frame)
+	}
+	if err != nil {
 		client.close()
 		h.remove(client)
 	}
@@ -194,6 +223,18 @@ func (h *veloWSHub) broadcastText(payload []byte) bool {
 	return delivered
 }
 
+// closeAll sends a close frame to every client and drops the connections.
+func (h *veloWSHub) closeAll() {
+	h.mu.Lock()
+	clients := h.clients
+	h.clients = make(map[*veloWSConn]struct{})
+	h.mu.Unlock()
+	for client := range clients {
+		_ = client.writeFrame(wsOpcodeClose, nil)
+		client.close()
+	}
+}
+
 func (h *veloWSHub) add(client *veloWSConn) {
 	h.mu.Lock()
 	h.clients[client] = struct{}{}
@@ -206,6 +247,16 @@ func (h *veloWSHub) remove(client *veloWSConn) {
 	h.mu.Unlock()
 }
 
+// writeCallback sends an intermediate callback frame, used for streamed
+// chunks that precede the final result.
+func (c *veloWSConn) writeCallback(id, result string) error {
+	frame, err := makeWSCallbackFrame(id, result)
+	if err != nil {
+		return err
+	}
+	return c.writeText(frame)
+}
+
 func (c *veloWSConn) writeText(payload []byte) error {
 	return c.writeFrame(wsOpcodeText, payload)
 }
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
@@ -36,8 +37,7 @@ func TestWebSocketInvokesRegisteredRoute(t *testing.T) {
 		})
 	})
 
-	server := httptest.NewServer(app.setupMux(nil, ""))
-	defer server.Close()
+	server := newTestServer(t, app)
 
 	client := dialTestWS(t, server.URL)
 	defer client.close()
@@ -82,8 +82,7 @@ func TestWebSocketInvokesRegisteredRoute(t *testing.T) {
 func TestSendMessageBroadcastsToWebSocketClients(t *testing.T) {
 	app := NewApp(&VeloAppOpt{Mode: ModeHttp})
 
-	server := httptest.NewServer(app.setupMux(nil, ""))
-	defer server.Close()
+	server := newTestServer(t, app)
 
 	client := dialTestWS(t, server.URL)
 	defer client.close()
@@ -115,6 +114,22 @@ func TestSendMessageBroadcastsToWebSocketClients(t *testing.T) {
 	}
 }
 
+// newTestServer serves app's mux and adds the session token to every
+// request, so that tests not concerned with authentication can call the API
+// like a page loaded from the app would.
+func newTestServer(t *testing.T, app *Box) *httptest.Server {
+	t.Helper()
+	mux := app.setupMux(nil, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(VeloTokenHeader) == "" {
+			r.Header.Set(VeloTokenHeader, app.Token())
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
 func dialTestWS(t *testing.T, serverURL string) *testWSClient {
 	t.Helper()
 